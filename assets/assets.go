@@ -0,0 +1,9 @@
+// Package assets embeds the piece images, so the binaries don't depend on the directory they're run from
+package assets
+
+import "embed"
+
+// Images holds the piece SVGs as images/<color><piece>.svg, like images/wn.svg for the white knight
+//
+//go:embed images/*.svg
+var Images embed.FS
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jgerontis/go-chess/internal/endgame"
+)
+
+// the endings generated when none are asked for
+var defaultMaterials = []string{"KQK", "KRK", "KPK", "KBNK", "KQKR"}
+
+func main() {
+	dir := flag.String("dir", "bitbases", "the directory to write the bitbase files to")
+	flag.Usage = func() {
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/bitbase [flags] [material...]")
+		fmt.Println()
+		fmt.Println("Generates exact distance to mate bitbases for endings with up to 4 pieces, like KQK or KQKR.")
+		fmt.Println("The tables the captures and promotions lead to are made as well, unless the directory has them already.")
+		fmt.Println("Without materials it makes", defaultMaterials)
+		fmt.Println()
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	materials := flag.Args()
+	if len(materials) == 0 {
+		materials = defaultMaterials
+	}
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	bitbases, err := endgame.Open(*dir)
+	if errors.Is(err, endgame.ErrNoTables) {
+		bitbases, err = endgame.NewBitbases(), nil
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	existing := make(map[string]bool)
+	for _, material := range bitbases.Materials() {
+		existing[material] = true
+	}
+
+	for _, material := range materials {
+		start := time.Now()
+		if _, err := bitbases.Generate(material); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "%s generated in %v\n", material, time.Since(start).Round(time.Millisecond))
+	}
+
+	for _, material := range bitbases.Materials() {
+		if existing[material] {
+			continue
+		}
+		table := bitbases.Table(material)
+		path, err := table.Save(*dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		stats := table.Stats()
+		fmt.Fprintf(os.Stderr, "%s: white to move %d won, %d drawn, %d lost; black to move %d won, %d drawn, %d lost; longest mate %d plies\n",
+			path, stats.Wins[0], stats.Draws[0], stats.Losses[0], stats.Wins[1], stats.Draws[1], stats.Losses[1], stats.Longest)
+	}
+}
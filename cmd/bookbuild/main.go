@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+	"github.com/jgerontis/go-chess/internal/polyglot"
+)
+
+func main() {
+	output := flag.String("o", "book.bin", "the Polyglot book to write")
+	dump := flag.String("dump", "", "also write the book as text to this file, - for stdout")
+	depth := flag.Int("depth", 20, "how many plies of each game to count, 0 for all of them")
+	minGames := flag.Int("min-games", 1, "leave out moves played in fewer games than this")
+	minRating := flag.Int("min-rating", 0, "skip moves by players rated below this")
+	results := flag.String("results", "1-0,0-1,1/2-1/2", "the game results to count, comma separated")
+	flag.Usage = func() {
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/bookbuild [flags] games.pgn...")
+		fmt.Println()
+		fmt.Println("Counts the moves played in the games and writes them as an opening book.")
+		fmt.Println("Moves are weighted 2 for each win and 1 for each draw by the side that played them.")
+		fmt.Println()
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	builder := polyglot.NewBuilder()
+	builder.MaxPly = *depth
+	builder.MinGames = *minGames
+	builder.MinRating = *minRating
+	builder.Results = strings.Split(*results, ",")
+
+	for _, path := range flag.Args() {
+		bad, err := readGames(builder, path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if bad > 0 {
+			fmt.Fprintf(os.Stderr, "%s: skipped %d games that couldn't be read\n", path, bad)
+		}
+	}
+
+	entries := builder.Entries()
+	if err := writeFile(*output, func(w io.Writer) error { return polyglot.Write(w, entries) }); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *dump != "" {
+		if err := writeFile(*dump, builder.Dump); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d games counted, %d filtered out, %d positions, %d book entries\n",
+		builder.Games, builder.Skipped, builder.Positions(), len(entries))
+}
+
+// adds every game in a PGN file to the builder, returns how many games had errors
+func readGames(builder *polyglot.Builder, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	reader := chess.NewPGNReader(f)
+	bad := 0
+	for {
+		game, err := reader.Read()
+		if err == io.EOF {
+			return bad, nil
+		}
+		if err != nil {
+			// the reader skips to the next game
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			bad++
+			continue
+		}
+		builder.AddGame(game)
+	}
+}
+
+// writes a file, or stdout for -
+func writeFile(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
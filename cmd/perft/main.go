@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+func main() {
+	depth := flag.Int("depth", 4, "how many plies deep to count")
+	fen := flag.String("fen", "", "count a single position instead of the reference suite")
+	divide := flag.Bool("divide", false, "print the count below each root move (needs -fen)")
+	parallel := flag.Bool("parallel", false, "split the root moves between goroutines")
+	flag.Usage = func() {
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/perft [-depth n] [-parallel]")
+		fmt.Println("  go run ./cmd/perft -fen <fen> [-depth n] [-divide] [-parallel]")
+		fmt.Println()
+		fmt.Println("Without -fen the reference positions are counted and compared against the published results.")
+		fmt.Println()
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	workers := 1
+	if *parallel {
+		workers = runtime.NumCPU()
+	}
+
+	if *fen != "" {
+		runPosition(*fen, *depth, workers, *divide)
+		return
+	}
+	if !runSuite(*depth, workers) {
+		os.Exit(1)
+	}
+}
+
+// counts a single position, optionally split up by root move
+func runPosition(fen string, depth, workers int, divide bool) {
+	board := chess.NewBoard()
+	if err := board.LoadFEN(fen); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	start := time.Now()
+	if divide {
+		counts := board.Divide(depth)
+		moves := make([]chess.Move, 0, len(counts))
+		for move := range counts {
+			moves = append(moves, move)
+		}
+		sort.Slice(moves, func(i, j int) bool {
+			return moves[i].String() < moves[j].String()
+		})
+		var total uint64
+		for _, move := range moves {
+			fmt.Printf("%s: %d\n", move.String(), counts[move])
+			total += counts[move]
+		}
+		fmt.Println()
+		fmt.Printf("Nodes searched: %d\n", total)
+		return
+	}
+	nodes := board.PerftParallel(depth, workers)
+	elapsed := time.Since(start)
+	fmt.Printf("depth %d: %d nodes in %v (%s)\n", depth, nodes, elapsed.Round(time.Millisecond), nps(nodes, elapsed))
+}
+
+// counts every reference position up to depth, returns false if any count is wrong
+func runSuite(depth, workers int) bool {
+	passed := true
+	for _, position := range chess.PerftSuite {
+		fmt.Printf("%s\n  %s\n", position.Name, position.FEN)
+		board := chess.NewBoard()
+		if err := board.LoadFEN(position.FEN); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			passed = false
+			continue
+		}
+		for d := 1; d <= depth && d <= len(position.Nodes); d++ {
+			start := time.Now()
+			nodes := board.PerftParallel(d, workers)
+			elapsed := time.Since(start)
+			expected := position.Nodes[d-1]
+			status := "ok"
+			if nodes != expected {
+				status = fmt.Sprintf("FAIL, expected %d", expected)
+				passed = false
+			}
+			fmt.Printf("  depth %d: %12d nodes %10v %14s  %s\n", d, nodes, elapsed.Round(time.Millisecond), nps(nodes, elapsed), status)
+		}
+	}
+	if passed {
+		fmt.Println("all counts match")
+	} else {
+		fmt.Println("some counts don't match")
+	}
+	return passed
+}
+
+// nodes per second as a readable string
+func nps(nodes uint64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "- nps"
+	}
+	return fmt.Sprintf("%.0f nps", float64(nodes)/elapsed.Seconds())
+}
@@ -2,6 +2,7 @@ package gui
 
 import (
 	"errors"
+	"log"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -15,6 +16,7 @@ type Game struct {
 	AudioPlayer  *AudioPlayer
 	Background   *ebiten.Image
 	Board        *chess.Board
+	ChessGame    *chess.Game
 	Dragging     bool
 	PieceImages  map[string]*ebiten.Image
 	Player1      string
@@ -35,12 +37,17 @@ func NewGame(FEN string) *Game {
 		panic(err)
 	}
 
-	board := chess.NewBoard()
-	board.LoadFEN(FEN)
-	board.GenerateLegalMoves()
+	// the chess game keeps the move history and legal moves up to date
+	chessGame, err := chess.NewGame(FEN)
+	if err != nil {
+		// a bad FEN from the command line shouldn't stop the app from starting
+		log.Printf("%v, using the start position", err)
+		chessGame, _ = chess.NewGame(chess.START_FEN)
+	}
 
 	return &Game{
-		Board:       board,
+		Board:       chessGame.Board,
+		ChessGame:   chessGame,
 		PieceImages: images,
 		Selected:    -1,
 		Dragging:    false,
@@ -50,12 +57,25 @@ func NewGame(FEN string) *Game {
 }
 
 func (g *Game) MakeMove(move chess.Move) {
-	_ = g.Board.MakeMove(move) // Ignore the return value for now
+	if err := g.ChessGame.MakeMove(move); err != nil {
+		return
+	}
 	// play a sound
 	// g.AudioPlayer.PlaySound("move")
 	g.PrevMove = move
-	// only generate legal moves when a move is made
-	g.Board.GenerateLegalMoves()
+}
+
+// takes back the last move, or replays the last undone move
+func (g *Game) stepHistory(forward bool) {
+	if forward {
+		g.ChessGame.Redo()
+	} else {
+		g.ChessGame.Undo()
+	}
+	g.PrevMove = g.ChessGame.LastMove()
+	g.Selected = -1
+	g.Dragging = false
+	g.UpdateLegalTargets()
 }
 
 func (g *Game) Update() error {
@@ -63,6 +83,15 @@ func (g *Game) Update() error {
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
 		return ErrReturnToMenu
 	}
+	// arrow keys step back and forth through the move history
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+		g.stepHistory(false)
+		return nil
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		g.stepHistory(true)
+		return nil
+	}
 	// start by getting the mouse coordinates
 	x, y := ebiten.CursorPosition()
 	rank, file := g.mouseCoordsToBoardCoords(x, y)
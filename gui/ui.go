@@ -1,14 +1,11 @@
 package gui
 
 import (
-	"bytes"
-	"image"
 	"image/color"
-	"os"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/srwiley/oksvg"
-	"github.com/srwiley/rasterx"
+	"github.com/jgerontis/go-chess/internal/chess"
+	"github.com/jgerontis/go-chess/internal/render"
 )
 
 const (
@@ -92,14 +89,15 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 
 func loadPieceImages() (map[string]*ebiten.Image, error) {
 	pieceImages := make(map[string]*ebiten.Image)
-	fenChars := []string{"p", "n", "b", "r", "q", "k", "P", "N", "B", "R", "Q", "K"}
-	fileNames := []string{"bp", "bn", "bb", "br", "bq", "bk", "wp", "wn", "wb", "wr", "wq", "wk"}
-	for i, fenChar := range fenChars {
-		img, err := loadSVG("assets/images/"+fileNames[i]+".svg", SquareSize, SquareSize)
-		if err != nil {
-			return nil, err
+	for _, side := range []byte{chess.WHITE, chess.BLACK} {
+		for pieceType := chess.PAWN; pieceType <= chess.KING; pieceType++ {
+			piece := chess.Piece(side | pieceType)
+			img, err := render.PieceImage(piece, SquareSize)
+			if err != nil {
+				return nil, err
+			}
+			pieceImages[piece.FenChar()] = ebiten.NewImageFromImage(img)
 		}
-		pieceImages[fenChar] = img
 	}
 	return pieceImages, nil
 }
@@ -111,36 +109,6 @@ func makeSquare(c color.RGBA) (*ebiten.Image, *ebiten.DrawImageOptions) {
 	return square, opts
 }
 
-func loadSVG(path string, width, height int) (*ebiten.Image, error) {
-	// Read the SVG file
-	svgBytes, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse the SVG
-	icon, err := oksvg.ReadIconStream(bytes.NewReader(svgBytes))
-	if err != nil {
-		return nil, err
-	}
-
-	// Set the SVG dimensions
-	icon.SetTarget(0, 0, float64(width), float64(height))
-
-	// Create a raster image to render to
-	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	// Rasterize the SVG
-	scanner := rasterx.NewScannerGV(width, height, rgba, rgba.Bounds())
-	raster := rasterx.NewDasher(width, height, scanner)
-	icon.Draw(raster, 1.0)
-
-	// Convert to Ebiten image
-	ebitenImg := ebiten.NewImageFromImage(rgba)
-
-	return ebitenImg, nil
-}
-
 // translates where the mouse is to board rank and file, returns -1, -1 if off the board
 func (g *Game) mouseCoordsToBoardCoords(x, y int) (int, int) {
 	margin := (WindowWidth - BoardSize) / 2
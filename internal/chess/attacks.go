@@ -0,0 +1,85 @@
+package chess
+
+/*
+	Attack maps answer "who attacks what" with bitboards instead of yes/no questions.
+	https://www.chessprogramming.org/Square_Attacked_By
+	AttackersTo looks outward from a square with each piece's own attack pattern, the same trick as IsSquareAttacked,
+	but returns every attacker of both colors. Passing an occupancy lets callers see through pieces, SEE uses that for x-rays.
+	Board.Attacks holds every square each color attacks. MakeMove, UnmakeMove and LoadFEN keep it current,
+	anything that places pieces with SetPieceAtIndex directly has to call UpdateAttacks afterwards.
+*/
+
+// AttackersTo returns every piece of either color attacking the square, with sliders blocked by the given occupancy
+func (b *Board) AttackersTo(square int, occupancy Bitboard) Bitboard {
+	white, black := &b.Pieces[WHITE_INDEX], &b.Pieces[BLACK_INDEX]
+	rookLike := white[ROOK] | white[QUEEN] | black[ROOK] | black[QUEEN]
+	bishopLike := white[BISHOP] | white[QUEEN] | black[BISHOP] | black[QUEEN]
+	// a white pawn attacks the square if a black pawn there would attack it, and the other way around
+	return pawnAttackMasks[BLACK_INDEX][square]&white[PAWN] |
+		pawnAttackMasks[WHITE_INDEX][square]&black[PAWN] |
+		KnightMasks[square]&(white[KNIGHT]|black[KNIGHT]) |
+		KingMasks[square]&(white[KING]|black[KING]) |
+		rookAttacks(square, occupancy)&rookLike |
+		bishopAttacks(square, occupancy)&bishopLike
+}
+
+// Checkers returns the enemy pieces giving check to the side to move
+func (b *Board) Checkers() Bitboard {
+	us, them := WHITE_INDEX, BLACK_INDEX
+	if !b.WhiteToMove {
+		us, them = BLACK_INDEX, WHITE_INDEX
+	}
+	if b.Pieces[us][KING] == 0 {
+		return 0
+	}
+	return b.AttackersTo(b.Pieces[us][KING].GetLSB(), b.Occupancy()) & b.Colors[them]
+}
+
+// Pinned returns the pieces of a color that can't leave the line between their king and an enemy slider
+func (b *Board) Pinned(color byte) Bitboard {
+	us := colorIndex(color)
+	if b.Pieces[us][KING] == 0 {
+		return 0
+	}
+	return b.pinnedTo(b.Pieces[us][KING].GetLSB(), us, us^1)
+}
+
+// AttackedSquares returns every square a color attacks, whether or not its own pieces are there
+func (b *Board) AttackedSquares(color byte) Bitboard {
+	return b.Attacks[colorIndex(color)]
+}
+
+// UpdateAttacks works out Attacks again from the pieces on the board
+func (b *Board) UpdateAttacks() {
+	b.Attacks[WHITE_INDEX] = b.attacksBy(WHITE_INDEX)
+	b.Attacks[BLACK_INDEX] = b.attacksBy(BLACK_INDEX)
+}
+
+// every square the pieces of a color index attack
+func (b *Board) attacksBy(color int) Bitboard {
+	pieces := &b.Pieces[color]
+	occupancy := b.Occupancy()
+
+	// all the pawns at once
+	pawns := pieces[PAWN]
+	var attacks Bitboard
+	if color == WHITE_INDEX {
+		attacks = (pawns<<7)&^FileH | (pawns<<9)&^FileA
+	} else {
+		attacks = (pawns>>9)&^FileH | (pawns>>7)&^FileA
+	}
+
+	for knights := pieces[KNIGHT]; knights != 0; {
+		attacks |= KnightMasks[knights.PopLSB()]
+	}
+	for bishops := pieces[BISHOP] | pieces[QUEEN]; bishops != 0; {
+		attacks |= bishopAttacks(bishops.PopLSB(), occupancy)
+	}
+	for rooks := pieces[ROOK] | pieces[QUEEN]; rooks != 0; {
+		attacks |= rookAttacks(rooks.PopLSB(), occupancy)
+	}
+	for kings := pieces[KING]; kings != 0; {
+		attacks |= KingMasks[kings.PopLSB()]
+	}
+	return attacks
+}
@@ -0,0 +1,88 @@
+package chess
+
+import "testing"
+
+// walks the move tree checking the attack maps against IsSquareAttacked and GivesCheck against making the move
+func checkAttacks(t *testing.T, b *Board, depth int) {
+	t.Helper()
+	occupancy := b.Occupancy()
+	for square := range 64 {
+		attackers := b.AttackersTo(square, occupancy)
+		for _, color := range []byte{WHITE, BLACK} {
+			attacked := b.IsSquareAttacked(square, color)
+			if b.AttackedSquares(color).Occupied(square) != attacked {
+				t.Fatalf("%s: AttackedSquares is wrong about %s", b.ExportFEN(), SquareToString(square))
+			}
+			if (attackers&b.ColorBitboard(color) != 0) != attacked {
+				t.Fatalf("%s: AttackersTo is wrong about %s", b.ExportFEN(), SquareToString(square))
+			}
+		}
+	}
+
+	var list MoveList
+	b.GenerateLegalMovesInto(&list)
+	for _, move := range list.Slice() {
+		if b.GivesCheck(move) != b.givesCheckByMaking(move) {
+			t.Fatalf("%s: GivesCheck is wrong about %s", b.ExportFEN(), move.String())
+		}
+		if depth > 1 {
+			state := b.MakeMove(move)
+			checkAttacks(t, b, depth-1)
+			b.UnmakeMove(move, state)
+		}
+	}
+}
+
+func TestAttackMaps(t *testing.T) {
+	for _, position := range PerftSuite {
+		board := NewBoard()
+		board.LoadFEN(position.FEN)
+		attacks := board.Attacks
+		checkAttacks(t, board, 2)
+		// unmaking the moves puts the old maps back
+		if board.Attacks != attacks {
+			t.Errorf("%s: attack maps changed after making and unmaking moves", position.Name)
+		}
+	}
+}
+
+func TestAttackersTo(t *testing.T) {
+	board := NewBoard()
+	board.LoadFEN("4k3/8/8/3p4/4R3/2N5/4Q3/4K3 w - - 0 1")
+	e4 := StringToSquare("e4")
+	// both colors are in the result
+	expected := Bitboard(1)<<StringToSquare("d5") | Bitboard(1)<<StringToSquare("c3") | Bitboard(1)<<StringToSquare("e2")
+	if attackers := board.AttackersTo(e4, board.Occupancy()); attackers != expected {
+		t.Errorf("Expected d5, c3 and e2 to attack e4, got %x", attackers)
+	}
+	// the rook on e4 hides the queen from e5, until it's taken out of the occupancy
+	if attackers := board.AttackersTo(StringToSquare("e5"), board.Occupancy()); attackers.Occupied(StringToSquare("e2")) {
+		t.Errorf("Expected the rook to block the queen, got %x", attackers)
+	}
+	occupancy := board.Occupancy() &^ (Bitboard(1) << e4)
+	if attackers := board.AttackersTo(StringToSquare("e5"), occupancy); !attackers.Occupied(StringToSquare("e2")) {
+		t.Errorf("Expected the queen to x-ray e5 through the rook, got %x", attackers)
+	}
+}
+
+func TestCheckers(t *testing.T) {
+	board := NewBoard()
+	board.LoadFEN(START_FEN)
+	if board.Checkers() != 0 {
+		t.Errorf("Expected no checkers, got %x", board.Checkers())
+	}
+	// the rook on c3 doesn't reach the king, only the queen checks
+	board.LoadFEN("8/8/8/8/8/2r5/4q3/4K3 w - - 0 1")
+	if checkers := board.Checkers(); checkers.Count() != 1 || !checkers.Occupied(StringToSquare("e2")) {
+		t.Errorf("Expected the queen to give check, got %x", checkers)
+	}
+	board.LoadFEN("4k3/8/8/8/8/5n2/8/r3K3 w - - 0 1")
+	if checkers := board.Checkers(); checkers.Count() != 2 {
+		t.Errorf("Expected a double check, got %x", checkers)
+	}
+	// black to move
+	board.LoadFEN("4k3/8/3N4/8/8/8/8/4K3 b - - 0 1")
+	if checkers := board.Checkers(); checkers != Bitboard(1)<<StringToSquare("d6") {
+		t.Errorf("Expected the d6 knight to give check, got %x", checkers)
+	}
+}
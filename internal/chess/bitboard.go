@@ -44,8 +44,8 @@ func (b *Bitboard) Clear(square int) {
 }
 
 // check if a square is occupied
-func (b *Bitboard) Occupied(square int) bool {
-	return (*b & (1 << square)) != 0
+func (b Bitboard) Occupied(square int) bool {
+	return (b & (1 << square)) != 0
 }
 
 // get the least significant bit and clear it
@@ -56,12 +56,28 @@ func (b *Bitboard) PopLSB() int {
 }
 
 // get the least significant bit
-func (b *Bitboard) GetLSB() int {
+func (b Bitboard) GetLSB() int {
 	return b.BitScanForward()
 }
 
-func (b *Bitboard) BitScanForward() int {
-	return bits.TrailingZeros64(uint64(*b))
+// count the number of occupied squares
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+func (b Bitboard) BitScanForward() int {
+	return bits.TrailingZeros64(uint64(b))
+}
+
+// FlipVertical swaps rank 1 with rank 8, rank 2 with rank 7 and so on, each rank is one byte
+func (b Bitboard) FlipVertical() Bitboard {
+	return Bitboard(bits.ReverseBytes64(uint64(b)))
+}
+
+// MirrorHorizontal swaps the a file with the h file, the b file with the g file and so on.
+// Reversing every bit flips the board both ways, reversing the bytes again undoes the vertical flip.
+func (b Bitboard) MirrorHorizontal() Bitboard {
+	return Bitboard(bits.ReverseBytes64(bits.Reverse64(uint64(b))))
 }
 
 // print the bitboard as an 8x8 grid with the lsb in the bottom left
@@ -233,3 +233,35 @@ func TestBitboardConstants(t *testing.T) {
 		}
 	}
 }
+
+func TestBitboardFlipVertical(t *testing.T) {
+	if Rank1.FlipVertical() != Rank8 || Rank3.FlipVertical() != Rank6 || FileC.FlipVertical() != FileC {
+		t.Error("FlipVertical should swap the ranks and keep the files")
+	}
+	for square := range 64 {
+		b := Bitboard(1) << square
+		if flipped := b.FlipVertical(); flipped != Bitboard(1)<<(square^56) {
+			t.Errorf("FlipVertical moved %s to %s", SquareToString(square), SquareToString(flipped.GetLSB()))
+		}
+	}
+	b := Bitboard(0x0123456789ABCDEF)
+	if b.FlipVertical().FlipVertical() != b {
+		t.Error("flipping twice should give back the bitboard")
+	}
+}
+
+func TestBitboardMirrorHorizontal(t *testing.T) {
+	if FileA.MirrorHorizontal() != FileH || FileD.MirrorHorizontal() != FileE || Rank2.MirrorHorizontal() != Rank2 {
+		t.Error("MirrorHorizontal should swap the files and keep the ranks")
+	}
+	for square := range 64 {
+		b := Bitboard(1) << square
+		if mirrored := b.MirrorHorizontal(); mirrored != Bitboard(1)<<(square^7) {
+			t.Errorf("MirrorHorizontal moved %s to %s", SquareToString(square), SquareToString(mirrored.GetLSB()))
+		}
+	}
+	b := Bitboard(0x0123456789ABCDEF)
+	if b.MirrorHorizontal().MirrorHorizontal() != b {
+		t.Error("mirroring twice should give back the bitboard")
+	}
+}
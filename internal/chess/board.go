@@ -1,8 +1,24 @@
 package chess
 
+import "strings"
+
+/*
+	The board is stored two ways at once.
+	Bitboards for every piece type and color are what move generation works with,
+	and the mailbox answers "what is on this square?" without probing all twelve bitboards.
+	Everything is a fixed size array so copying a Board is a plain value copy.
+	SetPieceAtIndex and ClearPieceAtIndex keep the two in sync, so always go through them.
+*/
+
 type Board struct {
-	// bitboards for each piece type
-	Bitboards map[byte]*Bitboard
+	// bitboards for each piece type, indexed by color index then piece type
+	Pieces [2][7]Bitboard
+	// bitboards with every piece of a color, indexed by color index
+	Colors [2]Bitboard
+	// squares attacked by each color, indexed by color index
+	Attacks [2]Bitboard
+	// the piece on each square, Piece(NONE) if it's empty
+	Mailbox [64]Piece
 
 	// other board state info, used by FEN
 	EnPassantSquare   int
@@ -12,6 +28,15 @@ type Board struct {
 	WhiteCastleRights string
 	WhiteToMove       bool
 
+	// Chess960 castling, see chess960.go
+	Chess960 bool
+	// the start square of the castling rook for each right, indexed by color index then kingSide or queenSide.
+	// Standard chess always castles with the corner rooks.
+	CastlingRooks [2][2]int
+
+	// Zobrist key of the position, kept up to date by MakeMove and UnmakeMove
+	Hash uint64
+
 	LegalMoves []Move
 }
 
@@ -20,63 +45,99 @@ func NewBoard() *Board {
 	return &Board{}
 }
 
-// get legal moves for the current position
+// get legal moves for the current position and store them in LegalMoves
 func (b *Board) GenerateLegalMoves() {
-	b.LegalMoves = b.GeneratePawnMoves()
-	b.LegalMoves = append(b.LegalMoves, b.GenerateKnightMoves()...)
-	b.LegalMoves = append(b.LegalMoves, b.GenerateBishopMoves()...)
-	b.LegalMoves = append(b.LegalMoves, b.GenerateRookMoves()...)
-	b.LegalMoves = append(b.LegalMoves, b.GenerateQueenMoves()...)
-	b.LegalMoves = append(b.LegalMoves, b.GenerateKingMoves()...)
+	var list MoveList
+	b.GenerateLegalMovesInto(&list)
+	b.LegalMoves = list.ToSlice()
+}
+
+// GenerateLegalMovesInto replaces the contents of the list with the legal moves for the current position
+func (b *Board) GenerateLegalMovesInto(list *MoveList) {
+	b.generateLegalMovesInto(list, genAll)
+}
+
+// fills the list with the legal moves of one kind
+func (b *Board) generateLegalMovesInto(list *MoveList, gen genType) {
+	list.Clear()
+	masks := b.legalityMasks()
+	b.addPseudoLegalMoves(list, &masks, gen)
+	b.filterLegalMoves(list, &masks)
+}
+
+// adds every pseudo-legal move of one kind in generation order, only king moves when in double check
+func (b *Board) addPseudoLegalMoves(list *MoveList, masks *legalityMasks, gen genType) {
+	if masks.checkers.Count() > 1 {
+		b.addKingMoves(list, gen)
+		return
+	}
+	b.addPawnMoves(list, gen)
+	b.addKnightMoves(list, gen)
+	b.addBishopMoves(list, gen)
+	b.addRookMoves(list, gen)
+	b.addQueenMoves(list, gen)
+	b.addKingMoves(list, gen)
 }
 
 // sets a piece in relevant bitboards at the given index
 func (b *Board) SetPieceAtIndex(piece Piece, index int) {
-	b.Bitboards[byte(piece)].Set(index)
-	b.Bitboards[piece.Color()].Set(index)
+	color := colorIndex(piece.Color())
+	b.Pieces[color][piece.Type()].Set(index)
+	b.Colors[color].Set(index)
+	b.Mailbox[index] = piece
+	b.Hash ^= zobristPieces[piece][index]
 }
 
 func (b *Board) ClearPieceAtIndex(piece Piece, index int) {
-	b.Bitboards[byte(piece)].Clear(index)
-	b.Bitboards[piece.Color()].Clear(index)
+	color := colorIndex(piece.Color())
+	b.Pieces[color][piece.Type()].Clear(index)
+	b.Colors[color].Clear(index)
+	b.Mailbox[index] = Piece(NONE)
+	b.Hash ^= zobristPieces[piece][index]
 }
 
 // get the type of piece at a given square
 func (b *Board) GetPieceAtIndex(square int) Piece {
-	switch {
-	case b.Bitboards[PAWN|WHITE].Occupied(square):
-		return Piece(PAWN | WHITE)
-	case b.Bitboards[KNIGHT|WHITE].Occupied(square):
-		return Piece(KNIGHT | WHITE)
-	case b.Bitboards[BISHOP|WHITE].Occupied(square):
-		return Piece(BISHOP | WHITE)
-	case b.Bitboards[ROOK|WHITE].Occupied(square):
-		return Piece(ROOK | WHITE)
-	case b.Bitboards[QUEEN|WHITE].Occupied(square):
-		return Piece(QUEEN | WHITE)
-	case b.Bitboards[KING|WHITE].Occupied(square):
-		return Piece(KING | WHITE)
-	case b.Bitboards[PAWN|BLACK].Occupied(square):
-		return Piece(PAWN | BLACK)
-	case b.Bitboards[KNIGHT|BLACK].Occupied(square):
-		return Piece(KNIGHT | BLACK)
-	case b.Bitboards[BISHOP|BLACK].Occupied(square):
-		return Piece(BISHOP | BLACK)
-	case b.Bitboards[ROOK|BLACK].Occupied(square):
-		return Piece(ROOK | BLACK)
-	case b.Bitboards[QUEEN|BLACK].Occupied(square):
-		return Piece(QUEEN | BLACK)
-	case b.Bitboards[KING|BLACK].Occupied(square):
-		return Piece(KING | BLACK)
-	default:
-		return Piece(0)
-	}
+	return b.Mailbox[square]
+}
+
+// get the bitboard for one type of piece of the given color
+func (b *Board) PieceBitboard(color, pieceType byte) Bitboard {
+	return b.Pieces[colorIndex(color)][pieceType]
+}
+
+// get the bitboard with all pieces of the given color
+func (b *Board) ColorBitboard(color byte) Bitboard {
+	return b.Colors[colorIndex(color)]
+}
+
+// get the bitboard with every piece on the board
+func (b *Board) Occupancy() Bitboard {
+	return b.Colors[WHITE_INDEX] | b.Colors[BLACK_INDEX]
+}
+
+// clears all pieces from the board
+func (b *Board) clearPieces() {
+	b.Pieces = [2][7]Bitboard{}
+	b.Colors = [2]Bitboard{}
+	b.Attacks = [2]Bitboard{}
+	b.Mailbox = [64]Piece{}
+	b.Hash = 0
 }
 
 // updates the board state from a Move object and returns the previous state
 func (b *Board) MakeMove(move Move) BoardState {
 	// Save the current state before making changes
 	state := b.SaveState()
+	// take the old castling rights, en passant square and side out of the hash
+	b.Hash ^= b.stateHash()
+
+	// castling moves two pieces and in Chess960 the king can land on the rook's square
+	if move.Flag() == CASTLE_FLAG {
+		b.castle(move, &state)
+		b.UpdateAttacks()
+		return state
+	}
 
 	// get the original piece
 	piece := b.GetPieceAtIndex(move.Source())
@@ -87,42 +148,26 @@ func (b *Board) MakeMove(move Move) BoardState {
 		b.ClearPieceAtIndex(enemyPiece, move.Target())
 	}
 
-	// increment move counter
-	b.HalfMoves++
+	// the halfmove clock counts moves since the last pawn move or capture
+	if piece.Type() == PAWN || !enemyPiece.IsNone() {
+		b.HalfMoves = 0
+	} else {
+		b.HalfMoves++
+	}
 	// increment full move counter on black moves
 	if !b.WhiteToMove {
 		b.FullMoves++
 	}
 
+	// moving the king or a rook, or capturing a rook, loses castling rights
+	b.updateCastleRights(piece, move.Source(), move.Target())
+
 	// clear the source square from the piece's board and the color board
 	b.ClearPieceAtIndex(piece, move.Source())
 	// set the original piece on the target square
 	b.SetPieceAtIndex(piece, move.Target())
 
 	switch move.Flag() {
-	case CASTLE_FLAG:
-		// move the rook
-		if b.WhiteToMove {
-			// kingside
-			if move.Target() == 6 {
-				b.ClearPieceAtIndex(Piece(ROOK|WHITE), 7)
-				b.SetPieceAtIndex(Piece(ROOK|WHITE), 5)
-			} else {
-				// queenside
-				b.ClearPieceAtIndex(Piece(ROOK|WHITE), 0)
-				b.SetPieceAtIndex(Piece(ROOK|WHITE), 3)
-			}
-		} else {
-			// kingside
-			if move.Target() == 62 {
-				b.ClearPieceAtIndex(Piece(ROOK|BLACK), 63)
-				b.SetPieceAtIndex(Piece(ROOK|BLACK), 61)
-			} else {
-				// queenside
-				b.ClearPieceAtIndex(Piece(ROOK|BLACK), 56)
-				b.SetPieceAtIndex(Piece(ROOK|BLACK), 59)
-			}
-		}
 	case PROMOTE_KNIGHT_FLAG:
 		b.ClearPieceAtIndex(piece, move.Target())
 		b.SetPieceAtIndex(Piece(KNIGHT|piece.Color()), move.Target())
@@ -161,10 +206,54 @@ func (b *Board) MakeMove(move Move) BoardState {
 
 	// change turns
 	b.WhiteToMove = !b.WhiteToMove
+	// and put the new ones back in
+	b.Hash ^= b.stateHash()
+	b.UpdateAttacks()
 
 	return state
 }
 
+// removes any castling rights that a move from source to target takes away
+func (b *Board) updateCastleRights(piece Piece, source, target int) {
+	if b.WhiteCastleRights == "" && b.BlackCastleRights == "" {
+		return
+	}
+	if piece == Piece(WHITE|KING) {
+		b.WhiteCastleRights = ""
+	}
+	if piece == Piece(BLACK|KING) {
+		b.BlackCastleRights = ""
+	}
+	// a castling rook leaving its square, or being captured there
+	for _, square := range [2]int{source, target} {
+		switch square {
+		case b.castlingRook(WHITE_INDEX, queenSide):
+			b.WhiteCastleRights = removeCastleRight(b.WhiteCastleRights, 'Q')
+		case b.castlingRook(WHITE_INDEX, kingSide):
+			b.WhiteCastleRights = removeCastleRight(b.WhiteCastleRights, 'K')
+		case b.castlingRook(BLACK_INDEX, queenSide):
+			b.BlackCastleRights = removeCastleRight(b.BlackCastleRights, 'q')
+		case b.castlingRook(BLACK_INDEX, kingSide):
+			b.BlackCastleRights = removeCastleRight(b.BlackCastleRights, 'k')
+		}
+	}
+}
+
+// removes one castling right from a rights string.
+// Slicing instead of building a new string keeps MakeMove from allocating.
+func removeCastleRight(rights string, right byte) string {
+	i := strings.IndexByte(rights, right)
+	switch {
+	case i < 0:
+		return rights
+	case i == 0:
+		return rights[1:]
+	case i == len(rights)-1:
+		return rights[:i]
+	}
+	return rights[:i] + rights[i+1:]
+}
+
 // BoardState represents the board state that needs to be restored when unmaking a move
 type BoardState struct {
 	WhiteToMove       bool
@@ -174,6 +263,8 @@ type BoardState struct {
 	BlackCastleRights string
 	WhiteCastleRights string
 	CapturedPiece     Piece
+	Hash              uint64
+	Attacks           [2]Bitboard
 }
 
 // SaveState saves the current board state before making a move
@@ -185,6 +276,8 @@ func (b *Board) SaveState() BoardState {
 		FullMoves:         b.FullMoves,
 		BlackCastleRights: b.BlackCastleRights,
 		WhiteCastleRights: b.WhiteCastleRights,
+		Hash:              b.Hash,
+		Attacks:           b.Attacks,
 	}
 }
 
@@ -196,6 +289,8 @@ func (b *Board) RestoreState(state BoardState) {
 	b.FullMoves = state.FullMoves
 	b.BlackCastleRights = state.BlackCastleRights
 	b.WhiteCastleRights = state.WhiteCastleRights
+	b.Hash = state.Hash
+	b.Attacks = state.Attacks
 }
 
 // UnmakeMove reverses a move that was previously made
@@ -206,24 +301,8 @@ func (b *Board) UnmakeMove(move Move, state BoardState) {
 	// Handle special moves first
 	switch move.Flag() {
 	case CASTLE_FLAG:
-		// Undo castling - move king back and rook back
-		if state.WhiteToMove { // Was white's move
-			if move.Target() == 6 { // Kingside
-				b.ClearPieceAtIndex(Piece(ROOK|WHITE), 5)
-				b.SetPieceAtIndex(Piece(ROOK|WHITE), 7)
-			} else { // Queenside
-				b.ClearPieceAtIndex(Piece(ROOK|WHITE), 3)
-				b.SetPieceAtIndex(Piece(ROOK|WHITE), 0)
-			}
-		} else { // Was black's move
-			if move.Target() == 62 { // Kingside
-				b.ClearPieceAtIndex(Piece(ROOK|BLACK), 61)
-				b.SetPieceAtIndex(Piece(ROOK|BLACK), 63)
-			} else { // Queenside
-				b.ClearPieceAtIndex(Piece(ROOK|BLACK), 59)
-				b.SetPieceAtIndex(Piece(ROOK|BLACK), 56)
-			}
-		}
+		b.uncastle(move, state)
+		return
 	case PROMOTE_KNIGHT_FLAG, PROMOTE_BISHOP_FLAG, PROMOTE_ROOK_FLAG, PROMOTE_QUEEN_FLAG:
 		// Clear the promoted piece and restore the original pawn
 		b.ClearPieceAtIndex(piece, move.Target())
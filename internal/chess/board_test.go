@@ -62,29 +62,53 @@ func TestGetPieceAtIndex(t *testing.T) {
 func TestSetPieceAtIndex(t *testing.T) {
 	board := NewBoard()
 	wr := WHITE | ROOK
-	board.Bitboards = make(map[byte]*Bitboard)
-	board.Bitboards[wr] = NewBitboard()
-	board.Bitboards[WHITE] = NewBitboard()
 	board.SetPieceAtIndex(Piece(wr), 0)
-	occupied := board.Bitboards[wr].Occupied(0)
+	occupied := board.Pieces[WHITE_INDEX][ROOK].Occupied(0)
 	if !occupied {
 		t.Errorf("Expected square 0 to be occupied")
 	}
-	occupied = board.Bitboards[WHITE].Occupied(0)
+	occupied = board.Colors[WHITE_INDEX].Occupied(0)
 	if !occupied {
 		t.Errorf("Expected square 0 to be occupied")
 	}
+	if board.Mailbox[0] != Piece(wr) {
+		t.Errorf("Expected white rook in the mailbox, got %v", board.Mailbox[0])
+	}
+	board.ClearPieceAtIndex(Piece(wr), 0)
+	if board.Pieces[WHITE_INDEX][ROOK] != 0 || board.Colors[WHITE_INDEX] != 0 || !board.Mailbox[0].IsNone() {
+		t.Errorf("Expected square 0 to be cleared")
+	}
 }
 
 func TestBitboardInitialization(t *testing.T) {
 	board := NewBoard()
 	board.LoadFEN(START_FEN)
-	// check that all bitboards are initialized
-	for _, bb := range board.Bitboards {
-		if bb == nil {
-			t.Fatalf("Expected bitboard to be initialized")
+	// check that the bitboards and the mailbox agree
+	for square := range 64 {
+		piece := board.GetPieceAtIndex(square)
+		for _, color := range []byte{WHITE, BLACK} {
+			for pieceType := PAWN; pieceType <= KING; pieceType++ {
+				expected := piece == Piece(color|pieceType)
+				if board.PieceBitboard(color, pieceType).Occupied(square) != expected {
+					t.Errorf("Bitboard for %v disagrees with the mailbox on square %d", Piece(color|pieceType), square)
+				}
+			}
+			expected := !piece.IsNone() && piece.Color() == color
+			if board.ColorBitboard(color).Occupied(square) != expected {
+				t.Errorf("Color bitboard disagrees with the mailbox on square %d", square)
+			}
 		}
 	}
+	if board.Occupancy() != Rank1|Rank2|Rank7|Rank8 {
+		t.Errorf("Expected the first and last two ranks to be occupied")
+	}
+
+	// boards are plain values, a copy doesn't share anything with the original
+	copied := *board
+	copied.ClearPieceAtIndex(copied.GetPieceAtIndex(0), 0)
+	if board.GetPieceAtIndex(0).IsNone() || !board.Pieces[WHITE_INDEX][ROOK].Occupied(0) {
+		t.Errorf("Changing a copy should not change the original board")
+	}
 }
 
 func TestIsSquareAttacked(t *testing.T) {
@@ -503,3 +527,78 @@ func TestCaptureRestoration(t *testing.T) {
 		t.Error("White pawn should be back on e4")
 	}
 }
+
+func TestCastlingRightsUpdates(t *testing.T) {
+	testCases := []struct {
+		name          string
+		fen           string
+		move          Move
+		expectedWhite string
+		expectedBlack string
+	}{
+		{"king move", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", NewMove(4, 12, 0), "", "kq"},
+		{"castling", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", NewMove(4, 6, CASTLE_FLAG), "", "kq"},
+		{"king side rook move", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", NewMove(7, 15, 0), "Q", "kq"},
+		{"queen side rook move", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", NewMove(0, 8, 0), "K", "kq"},
+		{"rook captures rook", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", NewMove(7, 63, 0), "Q", "q"},
+		{"black king move", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", NewMove(60, 52, 0), "KQ", ""},
+		{"black rook captured", "r3k2r/8/8/8/8/8/6B1/R3K2R w KQkq - 0 1", NewMove(14, 56, 0), "KQ", "k"},
+		{"other move", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", NewMove(0, 1, 0), "K", "kq"},
+	}
+
+	for _, tc := range testCases {
+		board := NewBoard()
+		board.LoadFEN(tc.fen)
+		state := board.MakeMove(tc.move)
+		if board.WhiteCastleRights != tc.expectedWhite || board.BlackCastleRights != tc.expectedBlack {
+			t.Errorf("%s: expected rights %q %q, got %q %q", tc.name, tc.expectedWhite, tc.expectedBlack,
+				board.WhiteCastleRights, board.BlackCastleRights)
+		}
+		board.UnmakeMove(tc.move, state)
+		if board.ExportFEN() != tc.fen {
+			t.Errorf("%s: unmake did not restore %s, got %s", tc.name, tc.fen, board.ExportFEN())
+		}
+	}
+}
+
+func TestNoCastlingAfterRookReturns(t *testing.T) {
+	g := mustNewGame(t, "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	// the rook leaves and comes back, the right is gone for good
+	playMoves(t, g, "a1a2", "a8a7", "a2a1", "a7a8")
+	castles := countCastles(g.Board.GenerateKingMoves())
+	if castles != 1 {
+		t.Errorf("Expected only king side castling to remain, got %d castling moves", castles)
+	}
+	if g.Board.ExportFEN() != "r3k2r/8/8/8/8/8/8/R3K2R w Kk - 4 3" {
+		t.Errorf("Unexpected position %s", g.Board.ExportFEN())
+	}
+}
+
+func TestHalfMoveClock(t *testing.T) {
+	board := NewBoard()
+	board.LoadFEN("4k3/8/8/3p4/8/8/4P3/R3K3 w - - 10 20")
+
+	// a quiet piece move increments the clock
+	state := board.MakeMove(NewMove(0, 8, 0))
+	if board.HalfMoves != 11 {
+		t.Errorf("Expected 11 half moves after a quiet move, got %d", board.HalfMoves)
+	}
+	board.UnmakeMove(NewMove(0, 8, 0), state)
+
+	// a pawn move resets it
+	state = board.MakeMove(NewMove(12, 28, PAWN_DOUBLE_FLAG))
+	if board.HalfMoves != 0 {
+		t.Errorf("Expected 0 half moves after a pawn move, got %d", board.HalfMoves)
+	}
+	board.UnmakeMove(NewMove(12, 28, PAWN_DOUBLE_FLAG), state)
+	if board.HalfMoves != 10 {
+		t.Errorf("Expected the clock to be restored to 10, got %d", board.HalfMoves)
+	}
+
+	// and so does a capture
+	board.LoadFEN("4k3/8/8/3p4/8/8/4P3/3RK3 w - - 10 20")
+	board.MakeMove(NewMove(3, 35, 0))
+	if board.HalfMoves != 0 {
+		t.Errorf("Expected 0 half moves after a capture, got %d", board.HalfMoves)
+	}
+}
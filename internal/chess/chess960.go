@@ -0,0 +1,195 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+	Chess960 (Fischer Random) shuffles the back rank pieces, with the bishops on opposite colors
+	and the king somewhere between the rooks. Black mirrors white.
+	https://www.chessprogramming.org/Chess960
+	Castling ends up on the same squares as standard chess: the king on the g or c file and the rook next to it
+	on the f or d file, but the king and rook can start on any file, so a castling right remembers
+	which rook it belongs to in Board.CastlingRooks.
+	In Chess960 mode a castling move is encoded as the king capturing its own rook, which is also how UCI writes them.
+	Otherwise it's the king moving two squares, like e1g1.
+	FENs write the rights as X-FEN (KQkq, with a rook file when it isn't the outermost rook)
+	or Shredder-FEN (always the rook file, like HAha), and LoadFEN reads both.
+*/
+
+// the sides of the board a king can castle to, used to index Board.CastlingRooks
+const (
+	kingSide  = 0
+	queenSide = 1
+)
+
+// the corner rooks standard chess castles with
+var standardCastlingRooks = [2][2]int{
+	WHITE_INDEX: {kingSide: 7, queenSide: 0},
+	BLACK_INDEX: {kingSide: 63, queenSide: 56},
+}
+
+// the Chess960 start position numbered 518 is the standard start position
+const CHESS960_STANDARD_INDEX = 518
+
+var ErrChess960Index = errors.New("Chess960 position index must be between 0 and 959")
+
+// the knight placements on the five squares left after the bishops and queen, in Scharnagl numbering
+var chess960Knights = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960FEN returns the FEN for one of the 960 start positions, numbered 0 to 959 as in the Scharnagl scheme
+func Chess960FEN(index int) (string, error) {
+	if index < 0 || index > 959 {
+		return "", fmt.Errorf("%w, got %d", ErrChess960Index, index)
+	}
+	var rank [8]byte
+	n := index
+	// light squared bishop on b, d, f or h, then the dark squared one on a, c, e or g
+	rank[n%4*2+1] = 'B'
+	n /= 4
+	rank[n%4*2] = 'B'
+	n /= 4
+	// the queen goes on one of the six empty squares
+	placeOnEmpty(&rank, n%6, 'Q')
+	n /= 6
+	// the knights on two of the five left, counting the empty squares before placing either
+	knights := chess960Knights[n]
+	placeOnEmpty(&rank, knights[1], 'N')
+	placeOnEmpty(&rank, knights[0], 'N')
+	// and the king between the rooks on the last three
+	placeOnEmpty(&rank, 0, 'R')
+	placeOnEmpty(&rank, 0, 'K')
+	placeOnEmpty(&rank, 0, 'R')
+
+	white := string(rank[:])
+	black := make([]byte, 8)
+	for i, c := range rank {
+		black[i] = c + 'a' - 'A'
+	}
+	return string(black) + "/pppppppp/8/8/8/8/PPPPPPPP/" + white + " w KQkq - 0 1", nil
+}
+
+// puts a piece on the nth empty square of the rank
+func placeOnEmpty(rank *[8]byte, n int, piece byte) {
+	for file := range 8 {
+		if rank[file] != 0 {
+			continue
+		}
+		if n == 0 {
+			rank[file] = piece
+			return
+		}
+		n--
+	}
+}
+
+// the start square of the rook for a castling right
+func (b *Board) castlingRook(us, side int) int {
+	if !b.Chess960 {
+		return standardCastlingRooks[us][side]
+	}
+	return b.CastlingRooks[us][side]
+}
+
+// where the king and rook end up after castling on a back rank
+func castlingTargets(rank, side int) (kingTarget, rookTarget int) {
+	if side == kingSide {
+		return rank + 6, rank + 5
+	}
+	return rank + 2, rank + 3
+}
+
+// the color and side a castling right letter stands for
+func castleRightSide(right byte) (color byte, side int) {
+	switch right {
+	case 'K':
+		return WHITE, kingSide
+	case 'Q':
+		return WHITE, queenSide
+	case 'k':
+		return BLACK, kingSide
+	}
+	return BLACK, queenSide
+}
+
+// the castling move for a king and side, the king takes its rook in Chess960
+func (b *Board) castleMove(kingSquare, side int) Move {
+	us := colorIndex(b.Mailbox[kingSquare].Color())
+	rook := b.castlingRook(us, side)
+	if b.Chess960 {
+		return NewMove(kingSquare, rook, CASTLE_FLAG)
+	}
+	kingTarget, _ := castlingTargets(rook&^7, side)
+	return NewMove(kingSquare, kingTarget, CASTLE_FLAG)
+}
+
+// the squares the king and rook of a castling move start and end on
+func (b *Board) castlingSquares(move Move, us int) (kingFrom, kingTo, rookFrom, rookTo int) {
+	kingFrom = move.Source()
+	side := queenSide
+	if move.Target() > kingFrom {
+		side = kingSide
+	}
+	rookFrom = b.castlingRook(us, side)
+	kingTo, rookTo = castlingTargets(kingFrom&^7, side)
+	return kingFrom, kingTo, rookFrom, rookTo
+}
+
+// checks that the king and the rook for a castling right are where they need to be
+func (b *Board) castlingPiecesInPlace(color byte, kingSquare, side int) bool {
+	rook := b.castlingRook(colorIndex(color), side)
+	rank := rook &^ 7
+	if kingSquare&^7 != rank || b.GetPieceAtIndex(kingSquare) != Piece(color|KING) || b.GetPieceAtIndex(rook) != Piece(color|ROOK) {
+		return false
+	}
+	if !b.Chess960 && kingSquare != rank+4 {
+		return false
+	}
+	// the king side rook is on the h side of the king
+	return (side == kingSide) == (rook > kingSquare)
+}
+
+// plays a castling move, MakeMove has already saved the state and taken it out of the hash
+func (b *Board) castle(move Move, state *BoardState) {
+	color, us := BLACK, BLACK_INDEX
+	if b.WhiteToMove {
+		color, us = WHITE, WHITE_INDEX
+	}
+	kingFrom, kingTo, rookFrom, rookTo := b.castlingSquares(move, us)
+	// take both off first, the king can land where the rook was
+	b.ClearPieceAtIndex(Piece(color|KING), kingFrom)
+	b.ClearPieceAtIndex(Piece(color|ROOK), rookFrom)
+	b.SetPieceAtIndex(Piece(color|KING), kingTo)
+	b.SetPieceAtIndex(Piece(color|ROOK), rookTo)
+
+	state.CapturedPiece = Piece(NONE)
+	b.HalfMoves++
+	if !b.WhiteToMove {
+		b.FullMoves++
+	}
+	if b.WhiteToMove {
+		b.WhiteCastleRights = ""
+	} else {
+		b.BlackCastleRights = ""
+	}
+	b.EnPassantSquare = -1
+	b.WhiteToMove = !b.WhiteToMove
+	b.Hash ^= b.stateHash()
+}
+
+// takes back a castling move
+func (b *Board) uncastle(move Move, state BoardState) {
+	color, us := BLACK, BLACK_INDEX
+	if state.WhiteToMove {
+		color, us = WHITE, WHITE_INDEX
+	}
+	kingFrom, kingTo, rookFrom, rookTo := b.castlingSquares(move, us)
+	b.ClearPieceAtIndex(Piece(color|KING), kingTo)
+	b.ClearPieceAtIndex(Piece(color|ROOK), rookTo)
+	b.SetPieceAtIndex(Piece(color|KING), kingFrom)
+	b.SetPieceAtIndex(Piece(color|ROOK), rookFrom)
+	b.RestoreState(state)
+}
@@ -0,0 +1,192 @@
+package chess
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChess960FEN(t *testing.T) {
+	fen, err := Chess960FEN(CHESS960_STANDARD_INDEX)
+	if err != nil || fen != START_FEN {
+		t.Errorf("Expected position 518 to be the start position, got %s %v", fen, err)
+	}
+	fen, _ = Chess960FEN(0)
+	if fen != "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1" {
+		t.Errorf("Unexpected position 0: %s", fen)
+	}
+
+	seen := make(map[string]bool)
+	for index := range 960 {
+		fen, err := Chess960FEN(index)
+		if err != nil {
+			t.Fatalf("Chess960FEN(%d) failed: %v", index, err)
+		}
+		rank := fen[strings.LastIndex(fen, "/")+1 : strings.Index(fen, " ")]
+		if seen[rank] {
+			t.Errorf("Position %d repeats %s", index, rank)
+		}
+		seen[rank] = true
+		// bishops on opposite colors, king between the rooks
+		bishops := strings.Index(rank, "B") + strings.LastIndex(rank, "B")
+		king := strings.Index(rank, "K")
+		if bishops%2 == 0 || king < strings.Index(rank, "R") || king > strings.LastIndex(rank, "R") {
+			t.Errorf("Position %d is not a Chess960 position: %s", index, rank)
+		}
+		board, err := ParseFEN(fen)
+		if err != nil {
+			t.Errorf("ParseFEN(%q) failed: %v", fen, err)
+			continue
+		}
+		// with the king on e1 and the rooks in the corners castling is the same as standard chess
+		standard := rank[0] == 'R' && rank[4] == 'K' && rank[7] == 'R'
+		if board.Chess960 == standard {
+			t.Errorf("Position %d: expected Chess960 %v", index, !standard)
+		}
+	}
+
+	for _, index := range []int{-1, 960} {
+		if _, err := Chess960FEN(index); !errors.Is(err, ErrChess960Index) {
+			t.Errorf("Chess960FEN(%d): expected ErrChess960Index, got %v", index, err)
+		}
+	}
+}
+
+func TestChess960Perft(t *testing.T) {
+	testCases := []struct {
+		fen   string
+		nodes []uint64
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint64{21, 528, 12189, 326672}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []uint64{21, 807, 18002}},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []uint64{20, 479, 10471}},
+	}
+	for _, tc := range testCases {
+		board, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q) failed: %v", tc.fen, err)
+		}
+		for depth, expected := range tc.nodes {
+			if nodes := board.Perft(depth + 1); nodes != expected {
+				t.Errorf("%s depth %d: expected %d nodes, got %d", tc.fen, depth+1, expected, nodes)
+			}
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	// king on b1 with rooks on a1 and h1, the queenside castle ends with the king on c1 and the rook on d1
+	board, err := ParseFEN("4k3/8/8/8/8/8/8/RK5R w KQ - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !board.Chess960 {
+		t.Fatal("Expected a Chess960 board")
+	}
+	queenSide, kingSide := NewMove(1, 0, CASTLE_FLAG), NewMove(1, 7, CASTLE_FLAG)
+	board.GenerateLegalMoves()
+	if !slices.Contains(board.LegalMoves, queenSide) || !slices.Contains(board.LegalMoves, kingSide) {
+		t.Fatalf("Expected both castling moves, got %v", board.LegalMoves)
+	}
+	if queenSide.String() != "b1a1" || board.MoveToSAN(queenSide) != "O-O-O" || board.MoveToSAN(kingSide) != "O-O" {
+		t.Errorf("Unexpected notation %s %s %s", queenSide.String(), board.MoveToSAN(queenSide), board.MoveToSAN(kingSide))
+	}
+
+	before := board.ExportFEN()
+	hash := board.Hash
+	state := board.MakeMove(queenSide)
+	if board.ExportFEN() != "4k3/8/8/8/8/8/8/2KR3R b - - 1 1" {
+		t.Errorf("Unexpected position after O-O-O: %s", board.ExportFEN())
+	}
+	if board.Hash != board.ComputeHash() {
+		t.Error("Hash not updated by castling")
+	}
+	board.UnmakeMove(queenSide, state)
+	if board.ExportFEN() != before || board.Hash != hash {
+		t.Errorf("Unmake didn't restore the position: %s", board.ExportFEN())
+	}
+
+	// the king already on g1 only moves the rook
+	board, _ = ParseFEN("4k3/8/8/8/8/8/8/6KR w K - 0 1")
+	move := NewMove(6, 7, CASTLE_FLAG)
+	board.GenerateLegalMoves()
+	if !slices.Contains(board.LegalMoves, move) {
+		t.Fatalf("Expected g1h1 castling, got %v", board.LegalMoves)
+	}
+	board.MakeMove(move)
+	if board.ExportFEN() != "4k3/8/8/8/8/8/8/5RK1 b - - 1 1" {
+		t.Errorf("Unexpected position after O-O: %s", board.ExportFEN())
+	}
+
+	// a piece on the rook's destination blocks castling even if it's outside the king's path
+	board, _ = ParseFEN("4k3/8/8/8/8/8/8/1RKN4 w Q - 0 1")
+	board.GenerateLegalMoves()
+	if slices.Contains(board.LegalMoves, NewMove(2, 1, CASTLE_FLAG)) {
+		t.Error("Castling allowed with the rook's destination occupied")
+	}
+	// the castling rook shields the king's destination from the queen on a1
+	board, _ = ParseFEN("4k3/8/8/8/8/8/8/qRK5 w Q - 0 1")
+	board.GenerateLegalMoves()
+	if slices.Contains(board.LegalMoves, NewMove(2, 1, CASTLE_FLAG)) {
+		t.Error("Castling allowed into check from behind the rook")
+	}
+}
+
+func TestChess960FENCastlingFields(t *testing.T) {
+	testCases := []struct {
+		fen, xfen, shredder string
+	}{
+		{START_FEN, START_FEN, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", START_FEN, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1"},
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w KQkq - 2 9", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9"},
+		// the inner rook needs its file in X-FEN
+		{"4k3/8/8/8/8/8/8/RR2K2R w BH - 0 1", "4k3/8/8/8/8/8/8/RR2K2R w KB - 0 1", "4k3/8/8/8/8/8/8/RR2K2R w HB - 0 1"},
+		{"4k3/8/8/8/8/8/8/RR2K2R w KB - 0 1", "4k3/8/8/8/8/8/8/RR2K2R w KB - 0 1", "4k3/8/8/8/8/8/8/RR2K2R w HB - 0 1"},
+	}
+	for _, tc := range testCases {
+		board, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Errorf("ParseFEN(%q) failed: %v", tc.fen, err)
+			continue
+		}
+		if board.ExportFEN() != tc.xfen {
+			t.Errorf("ExportFEN(%q): expected %s, got %s", tc.fen, tc.xfen, board.ExportFEN())
+		}
+		if board.ExportShredderFEN() != tc.shredder {
+			t.Errorf("ExportShredderFEN(%q): expected %s, got %s", tc.fen, tc.shredder, board.ExportShredderFEN())
+		}
+	}
+
+	for _, fen := range []string{
+		"4k3/8/8/8/8/8/8/R3K2R w KH - 0 1",
+		"4k3/8/8/8/8/8/8/R3K2R w E - 0 1",
+		"4k3/8/8/8/8/8/8/R3K2R w KZ - 0 1",
+	} {
+		if err := NewBoard().LoadFEN(fen); !errors.Is(err, ErrFENCastling) {
+			t.Errorf("LoadFEN(%q): expected ErrFENCastling, got %v", fen, err)
+		}
+	}
+}
+
+func TestNewChess960Game(t *testing.T) {
+	g, err := NewChess960Game("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	move, err := g.Board.ParseUCIMove("g1f3")
+	if err == nil {
+		err = g.MakeMove(move)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	// castling in a Chess960 game is always the king taking its rook, even from the standard position
+	g, _ = NewChess960Game("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if _, err := g.Board.ParseUCIMove("e1h1"); err != nil {
+		t.Errorf("Expected e1h1 to castle, got %v", err)
+	}
+	if _, err := g.Board.ParseUCIMove("e1g1"); err == nil {
+		t.Error("Expected e1g1 to be rejected in Chess960")
+	}
+}
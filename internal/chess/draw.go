@@ -0,0 +1,36 @@
+package chess
+
+/*
+	Draw rules, see https://www.chessprogramming.org/Draw
+	Some draws have to be claimed by a player (threefold repetition, fifty move rule),
+	others end the game on their own (fivefold repetition, seventy-five move rule, dead positions).
+	The repetition rules need the position history, so they live on Game and compare Zobrist keys.
+	Insufficient material only needs the pieces, so it lives on Board.
+*/
+
+// IsInsufficientMaterial returns true if neither side can possibly checkmate.
+// Covers K vs K, K+B vs K, K+N vs K and any number of bishops that all stand on the same color.
+func (b *Board) IsInsufficientMaterial() bool {
+	white, black := &b.Pieces[WHITE_INDEX], &b.Pieces[BLACK_INDEX]
+	heavy := white[PAWN] | black[PAWN] | white[ROOK] | black[ROOK] | white[QUEEN] | black[QUEEN]
+	if heavy != 0 {
+		return false
+	}
+
+	knights := white[KNIGHT] | black[KNIGHT]
+	bishops := white[BISHOP] | black[BISHOP]
+	minors := knights.Count() + bishops.Count()
+
+	// bare kings, or a single minor piece
+	if minors <= 1 {
+		return true
+	}
+	// more than one minor piece is only dead if they are all bishops on the same color
+	if knights != 0 {
+		return false
+	}
+	return bishops&lightSquares == 0 || bishops&^lightSquares == 0
+}
+
+// all of the light squares on the board, a1 is dark
+const lightSquares Bitboard = 0x55AA55AA55AA55AA
@@ -0,0 +1,31 @@
+package chess
+
+import (
+	"testing"
+)
+
+func TestInsufficientMaterial(t *testing.T) {
+	testCases := []struct {
+		fen      string
+		expected bool
+	}{
+		{"8/8/8/4k3/8/8/8/4K3 w - - 0 1", true},     // K vs K
+		{"8/8/8/4k3/8/8/8/2B1K3 w - - 0 1", true},   // KB vs K
+		{"8/8/8/4k3/8/8/8/1N2K3 w - - 0 1", true},   // KN vs K
+		{"8/8/8/2b1k3/8/8/8/2B1K3 w - - 0 1", true}, // KB vs KB, both bishops on dark squares
+		{"8/8/8/3bk3/8/8/8/2B1K3 w - - 0 1", false}, // KB vs KB, opposite colored bishops
+		{"8/8/8/4k3/8/8/8/1NN1K3 w - - 0 1", false}, // KNN vs K
+		{"8/8/8/4k3/8/8/8/1NB1K3 w - - 0 1", false}, // KBN vs K
+		{"8/8/8/4k3/8/8/4P3/4K3 w - - 0 1", false},  // KP vs K
+		{"8/8/8/4k3/8/8/8/R3K3 w - - 0 1", false},   // KR vs K
+		{START_FEN, false},
+	}
+
+	for _, tc := range testCases {
+		board := NewBoard()
+		board.LoadFEN(tc.fen)
+		if board.IsInsufficientMaterial() != tc.expected {
+			t.Errorf("IsInsufficientMaterial(%s) = %v, expected %v", tc.fen, !tc.expected, tc.expected)
+		}
+	}
+}
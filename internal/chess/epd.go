@@ -0,0 +1,387 @@
+package chess
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+	EPD (Extended Position Description) is how test suites like WAC, ECM and STS and most tuning datasets are shipped.
+	https://www.chessprogramming.org/Extended_Position_Description
+	A record is the first four fields of a FEN followed by operations, each an opcode, its operands and a semicolon:
+		2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";
+	Operands are separated by spaces, string operands are in double quotes and can contain spaces and semicolons.
+	Move operands (bm, am, pm, sm and the pv line) are SAN and get resolved to Moves for the position.
+	The move counters aren't part of the position, the hmvc and fmvn opcodes set them instead.
+*/
+
+var (
+	ErrInvalidEPD = errors.New("invalid EPD")
+)
+
+// opcodes with moves in SAN as operands, every one a move from the record's position
+var epdMoveOpcodes = map[string]bool{
+	"am": true, // avoid move
+	"bm": true, // best move
+	"pm": true, // predicted move
+	"sm": true, // supplied move
+}
+
+// EPDOperation is one opcode and its operands
+type EPDOperation struct {
+	Opcode   string
+	Operands []string
+	// the operands resolved to moves for move opcodes and pv, nil otherwise.
+	// pv is a line, each move is played from the position after the one before.
+	Moves []Move
+}
+
+// EPD is a position and the operations describing it
+type EPD struct {
+	Board *Board
+	// in the order they were read or set
+	Operations []EPDOperation
+}
+
+// ParseEPD reads a single EPD record
+func ParseEPD(line string) (*EPD, error) {
+	// the position is the first four fields, operations start after them
+	rest := strings.TrimSpace(line)
+	position := make([]string, 0, 4)
+	for len(position) < 4 {
+		end := strings.IndexAny(rest, " \t")
+		if end == -1 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("%w: expected 4 position fields in %q", ErrInvalidEPD, line)
+		}
+		position = append(position, rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+	board, err := ParseFEN(strings.Join(position, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	operations, err := parseEPDOperations(rest)
+	if err != nil {
+		return nil, err
+	}
+	epd := &EPD{Board: board}
+	for _, op := range operations {
+		if err := epd.SetOperation(op.Opcode, op.Operands...); err != nil {
+			return nil, err
+		}
+	}
+	return epd, nil
+}
+
+// a word or a quoted string in the operations
+type epdToken struct {
+	text   string
+	quoted bool
+}
+
+// splits the operations part of a record into opcodes and operands
+func parseEPDOperations(text string) ([]EPDOperation, error) {
+	var operations []EPDOperation
+	var tokens []epdToken
+	// an operation ends at a semicolon, be forgiving about a missing one at the very end
+	finish := func() error {
+		if len(tokens) == 0 {
+			return nil
+		}
+		opcode := tokens[0]
+		if opcode.quoted || !isEPDOpcode(opcode.text) {
+			return fmt.Errorf("%w: bad opcode %q", ErrInvalidEPD, opcode.text)
+		}
+		op := EPDOperation{Opcode: opcode.text}
+		for _, operand := range tokens[1:] {
+			op.Operands = append(op.Operands, operand.text)
+		}
+		operations = append(operations, op)
+		tokens = tokens[:0]
+		return nil
+	}
+
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == ';':
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			i++
+		case c == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("%w: unterminated string %s", ErrInvalidEPD, text[i:])
+			}
+			tokens = append(tokens, epdToken{text: text[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		default:
+			end := strings.IndexAny(text[i:], " \t;\"")
+			if end == -1 {
+				end = len(text) - i
+			}
+			tokens = append(tokens, epdToken{text: text[i : i+end]})
+			i += end
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// opcodes start with a letter and are at most 15 letters, digits and underscores
+func isEPDOpcode(s string) bool {
+	if len(s) == 0 || len(s) > 15 || !isLetter(s[0]) {
+		return false
+	}
+	for i := range len(s) {
+		if !isLetter(s[i]) && !(s[i] >= '0' && s[i] <= '9') && s[i] != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Operation returns the operation with the given opcode
+func (e *EPD) Operation(opcode string) (EPDOperation, bool) {
+	for _, op := range e.Operations {
+		if op.Opcode == opcode {
+			return op, true
+		}
+	}
+	return EPDOperation{}, false
+}
+
+// SetOperation adds an operation, or replaces the one with the same opcode.
+// Move operands have to be legal SAN for the position, hmvc and fmvn update the board's move counters.
+func (e *EPD) SetOperation(opcode string, operands ...string) error {
+	if !isEPDOpcode(opcode) {
+		return fmt.Errorf("%w: bad opcode %q", ErrInvalidEPD, opcode)
+	}
+	op := EPDOperation{Opcode: opcode, Operands: operands}
+	switch {
+	case epdMoveOpcodes[opcode]:
+		for _, san := range operands {
+			move, err := e.Board.ParseSAN(san)
+			if err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrInvalidEPD, opcode, err)
+			}
+			op.Moves = append(op.Moves, move)
+		}
+	case opcode == "pv":
+		board := *e.Board
+		for _, san := range operands {
+			move, err := board.ParseSAN(san)
+			if err != nil {
+				return fmt.Errorf("%w: pv: %w", ErrInvalidEPD, err)
+			}
+			op.Moves = append(op.Moves, move)
+			board.MakeMove(move)
+		}
+	case opcode == "hmvc" || opcode == "fmvn":
+		if len(operands) != 1 {
+			return fmt.Errorf("%w: %s needs one operand", ErrInvalidEPD, opcode)
+		}
+		n, err := strconv.Atoi(operands[0])
+		if err != nil || n < 0 {
+			return fmt.Errorf("%w: %s %q", ErrInvalidEPD, opcode, operands[0])
+		}
+		if opcode == "hmvc" {
+			e.Board.HalfMoves = n
+		} else {
+			e.Board.FullMoves = n
+		}
+	}
+	e.setOperation(op)
+	return nil
+}
+
+// SetMoves sets a move opcode or pv to moves from the position, writing the operands as SAN
+func (e *EPD) SetMoves(opcode string, moves ...Move) error {
+	if !epdMoveOpcodes[opcode] && opcode != "pv" {
+		return fmt.Errorf("%w: %s doesn't take moves", ErrInvalidEPD, opcode)
+	}
+	op := EPDOperation{Opcode: opcode, Moves: moves}
+	board := *e.Board
+	for _, move := range moves {
+		op.Operands = append(op.Operands, board.MoveToSAN(move))
+		if opcode == "pv" {
+			board.MakeMove(move)
+		}
+	}
+	e.setOperation(op)
+	return nil
+}
+
+func (e *EPD) setOperation(op EPDOperation) {
+	for i := range e.Operations {
+		if e.Operations[i].Opcode == op.Opcode {
+			e.Operations[i] = op
+			return
+		}
+	}
+	e.Operations = append(e.Operations, op)
+}
+
+// RemoveOperation removes the operation with the given opcode if there is one
+func (e *EPD) RemoveOperation(opcode string) {
+	for i := range e.Operations {
+		if e.Operations[i].Opcode == opcode {
+			e.Operations = append(e.Operations[:i], e.Operations[i+1:]...)
+			return
+		}
+	}
+}
+
+// the first operand of an operation, empty if there isn't one
+func (e *EPD) operand(opcode string) string {
+	op, ok := e.Operation(opcode)
+	if !ok || len(op.Operands) == 0 {
+		return ""
+	}
+	return op.Operands[0]
+}
+
+// the first operand of an operation as a number
+func (e *EPD) intOperand(opcode string) (int, bool) {
+	n, err := strconv.Atoi(e.operand(opcode))
+	return n, err == nil
+}
+
+// ID returns the id operand, the name of the position in its test suite
+func (e *EPD) ID() string {
+	return e.operand("id")
+}
+
+// Comment returns one of the c0 to c9 comments
+func (e *EPD) Comment(n int) string {
+	return e.operand("c" + strconv.Itoa(n))
+}
+
+// BestMoves returns the bm moves, any of them solves the position
+func (e *EPD) BestMoves() []Move {
+	op, _ := e.Operation("bm")
+	return op.Moves
+}
+
+// AvoidMoves returns the am moves, playing any of them fails the position
+func (e *EPD) AvoidMoves() []Move {
+	op, _ := e.Operation("am")
+	return op.Moves
+}
+
+// PV returns the predicted variation starting from the position
+func (e *EPD) PV() []Move {
+	op, _ := e.Operation("pv")
+	return op.Moves
+}
+
+// CentipawnEval returns the ce evaluation, from the side to move's point of view
+func (e *EPD) CentipawnEval() (int, bool) {
+	return e.intOperand("ce")
+}
+
+// AnalysisDepth returns the acd analysis depth in plies
+func (e *EPD) AnalysisDepth() (int, bool) {
+	return e.intOperand("acd")
+}
+
+// String returns the record as a line of EPD
+func (e *EPD) String() string {
+	fields := strings.Fields(e.Board.ExportFEN())
+	var sb strings.Builder
+	sb.WriteString(strings.Join(fields[:4], " "))
+	for _, op := range e.Operations {
+		sb.WriteByte(' ')
+		sb.WriteString(op.Opcode)
+		for _, operand := range op.Operands {
+			sb.WriteByte(' ')
+			if isEPDStringOpcode(op.Opcode) || operand == "" || strings.ContainsAny(operand, " \t;") {
+				sb.WriteString(`"` + operand + `"`)
+			} else {
+				sb.WriteString(operand)
+			}
+		}
+		sb.WriteByte(';')
+	}
+	return sb.String()
+}
+
+// id, the c0-c9 comments and the v0-v9 variation names are always quoted
+func isEPDStringOpcode(opcode string) bool {
+	if opcode == "id" {
+		return true
+	}
+	return len(opcode) == 2 && (opcode[0] == 'c' || opcode[0] == 'v') && opcode[1] >= '0' && opcode[1] <= '9'
+}
+
+// EPDReader reads EPD records one line at a time, so large datasets don't have to fit in memory
+type EPDReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func NewEPDReader(r io.Reader) *EPDReader {
+	return &EPDReader{scanner: bufio.NewScanner(r)}
+}
+
+// Read returns the next record, skipping blank lines, or io.EOF when there are no more
+func (r *EPDReader) Read() (*EPD, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		epd, err := ParseEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return epd, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ReadEPD reads every record, stopping at the first bad one
+func ReadEPD(r io.Reader) ([]*EPD, error) {
+	reader := NewEPDReader(r)
+	var records []*EPD
+	for {
+		epd, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, epd)
+	}
+}
+
+// WriteEPD writes the records one per line
+func WriteEPD(w io.Writer, records []*EPD) error {
+	for _, epd := range records {
+		if _, err := fmt.Fprintln(w, epd.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
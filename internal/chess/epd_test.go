@@ -0,0 +1,171 @@
+package chess
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const wac001 = `2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";`
+
+func TestParseEPD(t *testing.T) {
+	epd, err := ParseEPD(wac001)
+	if err != nil {
+		t.Fatalf("ParseEPD failed: %v", err)
+	}
+	if epd.ID() != "WAC.001" {
+		t.Errorf("Expected id WAC.001, got %q", epd.ID())
+	}
+	best := epd.BestMoves()
+	if len(best) != 1 || best[0].String() != "g3g6" {
+		t.Errorf("Expected bm g3g6, got %v", best)
+	}
+	if epd.Board.ExportFEN() != "2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1" {
+		t.Errorf("Unexpected position %s", epd.Board.ExportFEN())
+	}
+	if epd.String() != wac001 {
+		t.Errorf("Round trip failed:\nexpected %s\ngot      %s", wac001, epd.String())
+	}
+}
+
+func TestEPDOperations(t *testing.T) {
+	line := `rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - am a4 h4; bm e4 d4; ce 25; acd 12; pv e4 e5 Nf3; ` +
+		`c0 "opening; main line"; c7 "second comment"; hmvc 3; fmvn 7; noop; custom 1 two "three four"`
+	epd, err := ParseEPD(line)
+	if err != nil {
+		t.Fatalf("ParseEPD failed: %v", err)
+	}
+	if moves := epd.AvoidMoves(); len(moves) != 2 || moves[0].String() != "a2a4" || moves[1].String() != "h2h4" {
+		t.Errorf("Unexpected am %v", moves)
+	}
+	if moves := epd.BestMoves(); len(moves) != 2 || moves[0].String() != "e2e4" || moves[1].String() != "d2d4" {
+		t.Errorf("Unexpected bm %v", moves)
+	}
+	// each pv move is from the position after the previous one
+	pv := epd.PV()
+	if len(pv) != 3 || pv[0].String() != "e2e4" || pv[1].String() != "e7e5" || pv[2].String() != "g1f3" {
+		t.Errorf("Unexpected pv %v", pv)
+	}
+	if ce, ok := epd.CentipawnEval(); !ok || ce != 25 {
+		t.Errorf("Expected ce 25, got %d %v", ce, ok)
+	}
+	if acd, ok := epd.AnalysisDepth(); !ok || acd != 12 {
+		t.Errorf("Expected acd 12, got %d %v", acd, ok)
+	}
+	if epd.Comment(0) != "opening; main line" || epd.Comment(7) != "second comment" || epd.Comment(1) != "" {
+		t.Errorf("Unexpected comments %q %q %q", epd.Comment(0), epd.Comment(7), epd.Comment(1))
+	}
+	if epd.Board.HalfMoves != 3 || epd.Board.FullMoves != 7 {
+		t.Errorf("Expected move counters 3 7, got %d %d", epd.Board.HalfMoves, epd.Board.FullMoves)
+	}
+	if op, ok := epd.Operation("noop"); !ok || len(op.Operands) != 0 {
+		t.Errorf("Expected noop without operands, got %v %v", op, ok)
+	}
+	if op, ok := epd.Operation("custom"); !ok || strings.Join(op.Operands, "|") != "1|two|three four" {
+		t.Errorf("Unexpected custom operands %v", op.Operands)
+	}
+	if _, ok := epd.Operation("dm"); ok {
+		t.Error("Expected no dm operation")
+	}
+
+	// writing and reading again gives the same record
+	again, err := ParseEPD(epd.String())
+	if err != nil {
+		t.Fatalf("ParseEPD(%q) failed: %v", epd.String(), err)
+	}
+	if again.String() != epd.String() {
+		t.Errorf("Round trip failed:\nexpected %s\ngot      %s", epd.String(), again.String())
+	}
+}
+
+func TestEPDSetOperations(t *testing.T) {
+	epd, err := ParseEPD("4k3/8/8/8/8/8/4P3/4K3 w - -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := epd.SetOperation("id", "pawn ending"); err != nil {
+		t.Fatal(err)
+	}
+	push := NewMove(StringToSquare("e2"), StringToSquare("e4"), PAWN_DOUBLE_FLAG)
+	if err := epd.SetMoves("bm", push); err != nil {
+		t.Fatal(err)
+	}
+	if err := epd.SetMoves("pv", push, NewMove(StringToSquare("e8"), StringToSquare("e7"), NO_FLAG)); err != nil {
+		t.Fatal(err)
+	}
+	epd.SetOperation("ce", "100")
+	epd.SetOperation("ce", "150")
+	expected := `4k3/8/8/8/8/8/4P3/4K3 w - - id "pawn ending"; bm e4; pv e4 Ke7; ce 150;`
+	if epd.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, epd.String())
+	}
+	epd.RemoveOperation("pv")
+	if _, ok := epd.Operation("pv"); ok || len(epd.PV()) != 0 {
+		t.Error("pv wasn't removed")
+	}
+
+	if err := epd.SetOperation("bm", "e5"); !errors.Is(err, ErrIllegalMove) {
+		t.Errorf("Expected ErrIllegalMove, got %v", err)
+	}
+	if err := epd.SetOperation("1bad"); !errors.Is(err, ErrInvalidEPD) {
+		t.Errorf("Expected ErrInvalidEPD, got %v", err)
+	}
+	if err := epd.SetMoves("id", push); !errors.Is(err, ErrInvalidEPD) {
+		t.Errorf("Expected ErrInvalidEPD, got %v", err)
+	}
+}
+
+func TestParseEPDErrors(t *testing.T) {
+	testCases := []struct {
+		line string
+		err  error
+	}{
+		{"", ErrInvalidEPD},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq", ErrInvalidEPD},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNX w KQkq - bm e4;", ErrFENPieceChar},
+		{"8/8/8/8/8/8/8/8 w - - id \"empty\";", ErrMissingKing},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e5;", ErrIllegalMove},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - pv e4 e4;", ErrIllegalMove},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - id \"unterminated;", ErrInvalidEPD},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 9x 1;", ErrInvalidEPD},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - hmvc x;", ErrInvalidEPD},
+	}
+	for _, tc := range testCases {
+		if _, err := ParseEPD(tc.line); !errors.Is(err, tc.err) {
+			t.Errorf("ParseEPD(%q): expected %v, got %v", tc.line, tc.err, err)
+		}
+	}
+}
+
+func TestReadWriteEPD(t *testing.T) {
+	input := wac001 + "\n\n" +
+		`r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - bm Nf5; id "WAC.002";` + "\n"
+	records, err := ReadEPD(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadEPD failed: %v", err)
+	}
+	if len(records) != 2 || records[1].ID() != "WAC.002" {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	var out bytes.Buffer
+	if err := WriteEPD(&out, records); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != strings.Replace(input, "\n\n", "\n", 1) {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+
+	// errors say which line they're on
+	reader := NewEPDReader(strings.NewReader(wac001 + "\n\nnot an epd record\n"))
+	if _, err := reader.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Read(); err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("Expected an error on line 3, got %v", err)
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
@@ -1,10 +1,10 @@
 package chess
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
-
-	"log"
 )
 
 const START_FEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
@@ -15,125 +15,368 @@ const START_FEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 	https://www.chess.com/terms/fen-chess
 */
 
-// convert FEN position to game state
-func (b *Board) LoadFEN(fen string) {
-	// split the FEN string into parts, 6 total
+// FENError is returned when a FEN can't be loaded, Err says what is wrong with it
+type FENError struct {
+	FEN string
+	Err error
+}
+
+func (e *FENError) Error() string {
+	return fmt.Sprintf("invalid FEN %q: %v", e.FEN, e.Err)
+}
+
+func (e *FENError) Unwrap() error {
+	return e.Err
+}
+
+// problems with the FEN text itself, returned by LoadFEN
+var (
+	ErrFENFieldCount  = errors.New("expected 4 or 6 fields")
+	ErrFENRankCount   = errors.New("expected 8 ranks")
+	ErrFENRankLength  = errors.New("rank doesn't have 8 squares")
+	ErrFENPieceChar   = errors.New("unknown piece character")
+	ErrFENSideToMove  = errors.New("side to move must be w or b")
+	ErrFENCastling    = errors.New("invalid castling rights")
+	ErrFENEnPassant   = errors.New("invalid en passant square")
+	ErrFENMoveCounter = errors.New("invalid move counter")
+)
+
+// problems with the position, returned by Validate and ParseFEN
+var (
+	ErrMissingKing           = errors.New("missing king")
+	ErrTooManyKings          = errors.New("more than one king")
+	ErrPawnOnBackRank        = errors.New("pawn on the first or last rank")
+	ErrOpponentInCheck       = errors.New("side not to move is in check")
+	ErrInconsistentEnPassant = errors.New("en passant square doesn't match the position")
+	ErrInconsistentCastling  = errors.New("castling rights don't match the king and rook placement")
+)
+
+// ParseFEN creates a board from a FEN and checks that the position is legal
+func ParseFEN(fen string) (*Board, error) {
+	b := NewBoard()
+	if err := b.LoadFEN(fen); err != nil {
+		return nil, err
+	}
+	if err := b.Validate(); err != nil {
+		return nil, &FENError{FEN: fen, Err: err}
+	}
+	return b, nil
+}
+
+// LoadFEN sets the board to the position in a FEN.
+// It only checks that the FEN is well formed, use ParseFEN or Validate to also check the position is legal.
+// EPD style FENs without the move counters are accepted. The board is left unchanged if there's an error.
+func (b *Board) LoadFEN(fen string) error {
+	// split the FEN string into parts, 6 total or 4 without the move counters
 	parts := strings.Fields(fen)
+	if len(parts) != 4 && len(parts) != 6 {
+		return &FENError{FEN: fen, Err: fmt.Errorf("%w, got %d", ErrFENFieldCount, len(parts))}
+	}
+	// parse into a new board so a bad FEN doesn't leave this one half loaded
+	loaded := Board{}
+	if err := loaded.loadFENFields(parts); err != nil {
+		return &FENError{FEN: fen, Err: err}
+	}
+	loaded.UpdateAttacks()
+	*b = loaded
+	return nil
+}
 
+// fills out an empty board from the fields of a FEN
+func (b *Board) loadFENFields(parts []string) error {
 	// first part is the pieces on the board
 	ranks := strings.Split(parts[0], "/")
-	// initialize bitboards
-	b.Bitboards = make(map[byte]*Bitboard)
-	b.Bitboards[WHITE|PAWN] = NewBitboard()
-	b.Bitboards[WHITE|KNIGHT] = NewBitboard()
-	b.Bitboards[WHITE|BISHOP] = NewBitboard()
-	b.Bitboards[WHITE|ROOK] = NewBitboard()
-	b.Bitboards[WHITE|QUEEN] = NewBitboard()
-	b.Bitboards[WHITE|KING] = NewBitboard()
-	b.Bitboards[BLACK|PAWN] = NewBitboard()
-	b.Bitboards[BLACK|KNIGHT] = NewBitboard()
-	b.Bitboards[BLACK|BISHOP] = NewBitboard()
-	b.Bitboards[BLACK|ROOK] = NewBitboard()
-	b.Bitboards[BLACK|QUEEN] = NewBitboard()
-	b.Bitboards[BLACK|KING] = NewBitboard()
-	// extra bitboards for convenience
-	b.Bitboards[WHITE] = NewBitboard()
-	b.Bitboards[BLACK] = NewBitboard()
-	b.Bitboards[WHITE|ATTACK] = NewBitboard()
-	b.Bitboards[BLACK|ATTACK] = NewBitboard()
-	// this one exists so that tests don't panic
-	b.Bitboards[NONE] = NewBitboard()
+	if len(ranks) != 8 {
+		return fmt.Errorf("%w, got %d", ErrFENRankCount, len(ranks))
+	}
 	// fill them out
 	for rank, row := range ranks {
 		file := 0
 		for _, char := range row {
-			square := (7-rank)*8 + file
-			var piece Piece
-
-			switch char {
-			case 'P':
-				piece = Piece(WHITE | PAWN)
-			case 'N':
-				piece = Piece(WHITE | KNIGHT)
-			case 'B':
-				piece = Piece(WHITE | BISHOP)
-			case 'R':
-				piece = Piece(WHITE | ROOK)
-			case 'Q':
-				piece = Piece(WHITE | QUEEN)
-			case 'K':
-				piece = Piece(WHITE | KING)
-			case 'p':
-				piece = Piece(BLACK | PAWN)
-			case 'n':
-				piece = Piece(BLACK | KNIGHT)
-			case 'b':
-				piece = Piece(BLACK | BISHOP)
-			case 'r':
-				piece = Piece(BLACK | ROOK)
-			case 'q':
-				piece = Piece(BLACK | QUEEN)
-			case 'k':
-				piece = Piece(BLACK | KING)
-			default:
-				// Skip empty squares
-				if char >= '1' && char <= '8' {
-					file += int(char - '1') // -1 because we already increment file after the switch
-				}
+			if char >= '1' && char <= '8' {
+				// skip empty squares
+				file += int(char - '0')
+				continue
 			}
-			b.SetPieceAtIndex(piece, square)
+			piece := pieceFromFENChar(char)
+			if piece.IsNone() {
+				return fmt.Errorf("%w %q", ErrFENPieceChar, char)
+			}
+			if file >= 8 {
+				return fmt.Errorf("%w: rank %d", ErrFENRankLength, 8-rank)
+			}
+			b.SetPieceAtIndex(piece, (7-rank)*8+file)
 			file++
 		}
+		if file != 8 {
+			return fmt.Errorf("%w: rank %d", ErrFENRankLength, 8-rank)
+		}
 	}
 
 	// second part side to move is 'w' or 'b' (white or black)
-	b.WhiteToMove = parts[1] == "w"
+	switch parts[1] {
+	case "w":
+		b.WhiteToMove = true
+	case "b":
+		b.WhiteToMove = false
+	default:
+		return fmt.Errorf("%w, got %q", ErrFENSideToMove, parts[1])
+	}
 
 	// third is castling rights, capital letters for white, lowercase for black, '-' for none
 	// i.e. KQk for white kingside and queenside, black kingside
-	b.WhiteCastleRights = ""
-	b.BlackCastleRights = ""
-
-	castleRights := parts[2]
-	if castleRights != "-" {
-		for _, char := range castleRights {
-			switch char {
-			case 'K':
-				b.WhiteCastleRights += "K"
-			case 'Q':
-				b.WhiteCastleRights += "Q"
-			case 'k':
-				b.BlackCastleRights += "k"
-			case 'q':
-				b.BlackCastleRights += "q"
-			}
-		}
+	if err := b.loadCastling(parts[2]); err != nil {
+		return err
 	}
+
 	// fourth is en passant target square e.g. "e3" or '-' if none
 	enPassantSquare := parts[3]
 	if enPassantSquare == "-" {
 		b.EnPassantSquare = -1 // No en passant target square
-	} else {
+	} else if isSquare(enPassantSquare) {
 		b.EnPassantSquare = StringToSquare(enPassantSquare)
+	} else {
+		return fmt.Errorf("%w %q", ErrFENEnPassant, enPassantSquare)
 	}
 
-	// fifth is halfmove clock, number of halfmoves since the last pawn move or capture
-	halfMoves, err := strconv.Atoi(parts[4])
-	if err != nil {
-		log.Println("invalid half move count in fen, using 0, err: ", err)
+	// the move counters are optional, EPD leaves them out
+	b.HalfMoves = 0
+	b.FullMoves = 1
+	if len(parts) == 6 {
+		// fifth is halfmove clock, number of halfmoves since the last pawn move or capture
+		halfMoves, err := strconv.Atoi(parts[4])
+		if err != nil || halfMoves < 0 {
+			return fmt.Errorf("%w: halfmove clock %q", ErrFENMoveCounter, parts[4])
+		}
+		b.HalfMoves = halfMoves
+
+		// last is fullmove clock, starts at 1 and is incremented after black moves
+		fullMoves, err := strconv.Atoi(parts[5])
+		if err != nil || fullMoves < 0 {
+			return fmt.Errorf("%w: fullmove number %q", ErrFENMoveCounter, parts[5])
+		}
+		b.FullMoves = fullMoves
 	}
-	b.HalfMoves = halfMoves
 
-	// last is fullmove clock, starts at 1 and is incremented after black moves
-	fullMoves, err := strconv.Atoi(parts[5])
-	if err != nil {
-		log.Println("invalid full move count in fen, using 0, err: ", err)
+	// hash everything that isn't a piece
+	b.Hash ^= b.stateHash()
+	return nil
+}
+
+// reads the castling rights field, as standard KQkq, X-FEN or Shredder-FEN.
+// K and Q castle with the outermost rook on that side of the king, a file letter names the rook.
+// A right for a rook that isn't in a corner, or a king that isn't on the e file, makes the board Chess960.
+func (b *Board) loadCastling(field string) error {
+	b.CastlingRooks = standardCastlingRooks
+	if field == "-" {
+		return nil
+	}
+	for _, char := range field {
+		if char > 'z' {
+			return fmt.Errorf("%w %q", ErrFENCastling, field)
+		}
+		letter := byte(char)
+		color, us, rank, rights := WHITE, WHITE_INDEX, 0, &b.WhiteCastleRights
+		if letter >= 'a' {
+			color, us, rank, rights = BLACK, BLACK_INDEX, 56, &b.BlackCastleRights
+			letter -= 'a' - 'A'
+		}
+		// without a king on the back rank assume it's on the e file, Validate will complain
+		kingFile := 4
+		if king := b.Pieces[us][KING] & (Rank1 << rank); king != 0 {
+			kingFile = king.GetLSB() % 8
+		}
+
+		var side, rook int
+		switch {
+		case letter == 'K':
+			side, rook = kingSide, b.outermostRook(color, rank, kingFile, 1)
+		case letter == 'Q':
+			side, rook = queenSide, b.outermostRook(color, rank, kingFile, -1)
+		case letter >= 'A' && letter <= 'H' && int(letter-'A') != kingFile:
+			side, rook = queenSide, rank+int(letter-'A')
+			if rook%8 > kingFile {
+				side = kingSide
+			}
+		default:
+			return fmt.Errorf("%w %q", ErrFENCastling, field)
+		}
+
+		right := "KQ"[side : side+1]
+		if color == BLACK {
+			right = "kq"[side : side+1]
+		}
+		if strings.Contains(*rights, right) {
+			return fmt.Errorf("%w %q", ErrFENCastling, field)
+		}
+		*rights += right
+		b.CastlingRooks[us][side] = rook
+		if rook != standardCastlingRooks[us][side] || kingFile != 4 {
+			b.Chess960 = true
+		}
+	}
+	// however they were typed, the rights are kept and written back in KQkq order
+	b.WhiteCastleRights = sortCastleRights(b.WhiteCastleRights, "KQ")
+	b.BlackCastleRights = sortCastleRights(b.BlackCastleRights, "kq")
+	return nil
+}
+
+// the rights that are in rights, in the order of order
+func sortCastleRights(rights, order string) string {
+	sorted := ""
+	for _, right := range order {
+		if strings.ContainsRune(rights, right) {
+			sorted += string(right)
+		}
 	}
-	b.FullMoves = fullMoves
+	return sorted
+}
+
+// the rook furthest from the king in a direction along the back rank, or the corner if there isn't one
+func (b *Board) outermostRook(color byte, rank, kingFile, step int) int {
+	edge := 7
+	if step < 0 {
+		edge = 0
+	}
+	for file := edge; file != kingFile; file -= step {
+		if b.Mailbox[rank+file] == Piece(color|ROOK) {
+			return rank + file
+		}
+	}
+	return rank + edge
+}
+
+// converts a FEN piece letter to a piece, Piece(NONE) if it isn't one
+func pieceFromFENChar(char rune) Piece {
+	color := WHITE
+	if char >= 'a' && char <= 'z' {
+		color = BLACK
+	}
+	if char > 255 {
+		return Piece(NONE)
+	}
+	pieceType := pieceTypeFromLetter(byte(char))
+	if pieceType == NONE {
+		if char != 'P' && char != 'p' {
+			return Piece(NONE)
+		}
+		pieceType = PAWN
+	}
+	return Piece(color | pieceType)
+}
+
+// Validate checks that the position could be reached in a legal game, as far as FEN loading cares:
+// one king each, no pawns on the back ranks, the side that just moved isn't in check,
+// and the en passant square and castling rights match the pieces
+func (b *Board) Validate() error {
+	for _, color := range []byte{WHITE, BLACK} {
+		kings := b.PieceBitboard(color, KING).Count()
+		if kings == 0 {
+			return fmt.Errorf("%w: %s", ErrMissingKing, colorName(color))
+		}
+		if kings > 1 {
+			return fmt.Errorf("%w: %s has %d", ErrTooManyKings, colorName(color), kings)
+		}
+	}
+
+	pawns := b.PieceBitboard(WHITE, PAWN) | b.PieceBitboard(BLACK, PAWN)
+	if pawns&(Rank1|Rank8) != 0 {
+		return fmt.Errorf("%w: %s", ErrPawnOnBackRank, SquareToString((pawns & (Rank1 | Rank8)).GetLSB()))
+	}
+
+	opponent := WHITE
+	if b.WhiteToMove {
+		opponent = BLACK
+	}
+	if b.IsInCheck(opponent) {
+		return fmt.Errorf("%w: %s", ErrOpponentInCheck, colorName(opponent))
+	}
+
+	if err := b.validateEnPassant(); err != nil {
+		return err
+	}
+	return b.validateCastling()
+}
+
+// the en passant square has to be right behind a pawn that just moved two squares
+func (b *Board) validateEnPassant() error {
+	if b.EnPassantSquare == -1 {
+		return nil
+	}
+	square := b.EnPassantSquare
+	// the square the pawn came from, and where it is now
+	from, pawn, rank, pushed := square+8, square-8, 5, Piece(BLACK|PAWN)
+	if !b.WhiteToMove {
+		from, pawn, rank, pushed = square-8, square+8, 2, Piece(WHITE|PAWN)
+	}
+	if square/8 != rank || !b.GetPieceAtIndex(square).IsNone() || !b.GetPieceAtIndex(from).IsNone() || b.GetPieceAtIndex(pawn) != pushed {
+		return fmt.Errorf("%w: %s", ErrInconsistentEnPassant, SquareToString(square))
+	}
+	return nil
+}
+
+// every castling right needs the king on its back rank and that rook on its starting square
+func (b *Board) validateCastling() error {
+	for _, right := range []byte(b.WhiteCastleRights + b.BlackCastleRights) {
+		color, side := castleRightSide(right)
+		king := b.Pieces[colorIndex(color)][KING].GetLSB()
+		if !b.castlingPiecesInPlace(color, king, side) {
+			return fmt.Errorf("%w: %c", ErrInconsistentCastling, right)
+		}
+	}
+	return nil
+}
+
+// the name of a color for error messages
+func colorName(color byte) string {
+	if color == WHITE {
+		return "white"
+	}
+	return "black"
+}
+
+// ExportShredderFEN returns the FEN with the castling rights as rook files, like HAha for the standard start position
+func (b *Board) ExportShredderFEN() string {
+	return b.exportFEN(true)
+}
+
+// the castling rights with rook files where they're needed, or always for Shredder-FEN
+func (b *Board) castlingFiles(shredder bool) string {
+	field := ""
+	for _, right := range []byte(b.WhiteCastleRights + b.BlackCastleRights) {
+		color, side := castleRightSide(right)
+		rook := b.castlingRook(colorIndex(color), side)
+		if !shredder {
+			// X-FEN only needs the file when another rook is further out on that side
+			kingFile := 4
+			if king := b.Pieces[colorIndex(color)][KING] & (Rank1 << (rook &^ 7)); king != 0 {
+				kingFile = king.GetLSB() % 8
+			}
+			step := 1
+			if side == queenSide {
+				step = -1
+			}
+			if b.outermostRook(color, rook&^7, kingFile, step) == rook {
+				field += string(right)
+				continue
+			}
+		}
+		file := 'A' + byte(rook%8)
+		if color == BLACK {
+			file += 'a' - 'A'
+		}
+		field += string(file)
+	}
+	return field
 }
 
 // given a game state, return the FEN string
 func (b *Board) ExportFEN() string {
+	return b.exportFEN(false)
+}
+
+// builds the FEN, with X-FEN or Shredder-FEN castling rights
+func (b *Board) exportFEN(shredder bool) string {
 	FEN := ""
 	// first part is the pieces on the board
 	for rank := 7; rank >= 0; rank-- {
@@ -169,8 +412,10 @@ func (b *Board) ExportFEN() string {
 	// third is castling rights, capital letters for white, lowercase for black, '-' for none
 	if b.WhiteCastleRights == "" && b.BlackCastleRights == "" {
 		FEN += "-"
-	} else {
+	} else if !b.Chess960 && !shredder {
 		FEN += b.WhiteCastleRights + b.BlackCastleRights
+	} else {
+		FEN += b.castlingFiles(shredder)
 	}
 	FEN += " "
 	// fourth is en passant target square e.g. "e3" or '-' if none
@@ -1,6 +1,7 @@
 package chess
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -131,6 +132,27 @@ func TestFENCastlingRights(t *testing.T) {
 		t.Errorf("Expected black castle rights 'q', got '%s'", board.BlackCastleRights)
 	}
 
+	// rights typed out of order are kept in KQkq order
+	for _, fen := range []string{"r3k2r/8/8/8/8/8/8/R3K2R w QkKq - 0 1", "r3k2r/8/8/8/8/8/8/R3K2R w qQkK - 0 1"} {
+		ordered, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ordered.ExportFEN(); got != "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1" {
+			t.Errorf("Expected %s to export as KQkq, got %s", fen, got)
+		}
+		if got := ordered.ExportShredderFEN(); got != "r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1" {
+			t.Errorf("Expected %s to export as HAha, got %s", fen, got)
+		}
+	}
+	shredder, err := ParseFEN("r3k2r/8/8/8/8/8/8/R3K2R w ahAH - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shredder.WhiteCastleRights != "KQ" || shredder.BlackCastleRights != "kq" {
+		t.Errorf("Expected KQ and kq from Shredder-FEN ahAH, got %s and %s", shredder.WhiteCastleRights, shredder.BlackCastleRights)
+	}
+
 	// Test no castling rights
 	board.LoadFEN("8/8/8/8/8/8/8/8 w - - 0 1")
 	if board.WhiteCastleRights != "" {
@@ -207,3 +229,109 @@ func TestFENEdgeCases(t *testing.T) {
 		}
 	}
 }
+
+func TestFENEPD(t *testing.T) {
+	board := NewBoard()
+	if err := board.LoadFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3"); err != nil {
+		t.Fatalf("Expected 4 field FEN to load, got %v", err)
+	}
+	if board.HalfMoves != 0 || board.FullMoves != 1 {
+		t.Errorf("Expected move counters 0 1, got %d %d", board.HalfMoves, board.FullMoves)
+	}
+	if board.ExportFEN() != "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1" {
+		t.Errorf("Unexpected FEN %s", board.ExportFEN())
+	}
+}
+
+func TestLoadFENErrors(t *testing.T) {
+	testCases := []struct {
+		fen string
+		err error
+	}{
+		{"", ErrFENFieldCount},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq", ErrFENFieldCount},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0", ErrFENFieldCount},
+		{"rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ErrFENRankCount},
+		{"rnbqkbnr/ppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ErrFENRankLength},
+		{"rnbqkbnr/ppppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ErrFENRankLength},
+		{"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ErrFENPieceChar},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNX w KQkq - 0 1", ErrFENPieceChar},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1", ErrFENSideToMove},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQxq - 0 1", ErrFENCastling},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KKkq - 0 1", ErrFENCastling},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e9 0 1", ErrFENEnPassant},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1", ErrFENMoveCounter},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 -1", ErrFENMoveCounter},
+	}
+	for _, tc := range testCases {
+		board := NewBoard()
+		board.LoadFEN(START_FEN)
+		err := board.LoadFEN(tc.fen)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("LoadFEN(%q): expected %v, got %v", tc.fen, tc.err, err)
+		}
+		var fenErr *FENError
+		if !errors.As(err, &fenErr) || fenErr.FEN != tc.fen {
+			t.Errorf("LoadFEN(%q): expected a FENError, got %v", tc.fen, err)
+		}
+		// a failed load leaves the board alone
+		if board.ExportFEN() != START_FEN {
+			t.Errorf("LoadFEN(%q) changed the board to %s", tc.fen, board.ExportFEN())
+		}
+	}
+}
+
+func TestParseFEN(t *testing.T) {
+	valid := []string{
+		START_FEN,
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3",
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+		"4k3/8/8/8/8/8/8/4K3 w - -",
+	}
+	for _, fen := range valid {
+		board, err := ParseFEN(fen)
+		if err != nil {
+			t.Errorf("ParseFEN(%q) failed: %v", fen, err)
+			continue
+		}
+		if board.WhiteToMove != (fen == START_FEN || fen == valid[3] || fen == valid[4]) {
+			t.Errorf("ParseFEN(%q) loaded the wrong side to move", fen)
+		}
+	}
+
+	testCases := []struct {
+		fen string
+		err error
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0", ErrFENFieldCount},
+		{"rnbq1bnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1", ErrMissingKing},
+		{"8/8/8/8/8/8/8/8 w - - 0 1", ErrMissingKing},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBKKBNR w kq - 0 1", ErrTooManyKings},
+		{"4k3/8/8/8/8/8/8/3PK3 w - - 0 1", ErrPawnOnBackRank},
+		{"3pk3/8/8/8/8/8/8/4K3 w - - 0 1", ErrPawnOnBackRank},
+		{"4k3/8/8/8/8/8/8/4R1K1 w - - 0 1", ErrOpponentInCheck},
+		{"4k3/8/8/8/8/8/8/4R1K1 b - - 0 1", nil},
+		{"4k3/4r3/8/8/8/8/8/4K3 b - - 0 1", ErrOpponentInCheck},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e3 0 1", ErrInconsistentEnPassant},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq e3 0 1", ErrInconsistentEnPassant},
+		{"rnbqkbnr/pppppppp/8/8/8/4P3/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", ErrInconsistentEnPassant},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq d3 0 1", ErrInconsistentEnPassant},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1 w KQkq - 0 1", ErrInconsistentCastling},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQ1BNR w KQkq - 0 1", ErrMissingKing},
+		{"4k3/8/8/8/8/8/8/R3K3 w K - 0 1", ErrInconsistentCastling},
+		{"1nbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ErrInconsistentCastling},
+	}
+	for _, tc := range testCases {
+		_, err := ParseFEN(tc.fen)
+		if tc.err == nil {
+			if err != nil {
+				t.Errorf("ParseFEN(%q) failed: %v", tc.fen, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tc.err) {
+			t.Errorf("ParseFEN(%q): expected %v, got %v", tc.fen, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+	A Game wraps a Board and keeps track of everything a Board doesn't need to know about:
+	the moves that were played, the state needed to take them back, and how the game ended.
+	The GUI, the engine and any tooling should go through a Game instead of carrying
+	BoardStates around themselves.
+*/
+
+// Outcome is the result of a game from white's point of view
+type Outcome int
+
+const (
+	NoOutcome Outcome = iota
+	WhiteWon
+	BlackWon
+	Draw
+)
+
+// String returns the PGN result token for the outcome
+func (o Outcome) String() string {
+	switch o {
+	case WhiteWon:
+		return "1-0"
+	case BlackWon:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	default:
+		return "*"
+	}
+}
+
+// Method is the reason a game reached its outcome
+type Method int
+
+const (
+	NoMethod Method = iota
+	Checkmate
+	Stalemate
+	Resignation
+	Timeout
+	DrawAgreement
+	ThreefoldRepetition
+	FivefoldRepetition
+	FiftyMoveRule
+	SeventyFiveMoveRule
+	InsufficientMaterial
+)
+
+func (m Method) String() string {
+	switch m {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case Resignation:
+		return "resignation"
+	case Timeout:
+		return "timeout"
+	case DrawAgreement:
+		return "draw by agreement"
+	case ThreefoldRepetition:
+		return "threefold repetition"
+	case FivefoldRepetition:
+		return "fivefold repetition"
+	case FiftyMoveRule:
+		return "fifty move rule"
+	case SeventyFiveMoveRule:
+		return "seventy-five move rule"
+	case InsufficientMaterial:
+		return "insufficient material"
+	default:
+		return "none"
+	}
+}
+
+var (
+	ErrGameOver    = errors.New("game is already over")
+	ErrIllegalMove = errors.New("illegal move")
+	ErrNoDrawClaim = errors.New("no draw can be claimed")
+)
+
+// Game is a Board plus its move history and result
+type Game struct {
+	Board    *Board
+	StartFEN string
+
+	moves  []Move
+	states []BoardState
+	// Zobrist keys for the start position and after every move, used for repetitions
+	positions []uint64
+	// moves that were undone and can be replayed with Redo
+	redo []Move
+
+	outcome Outcome
+	method  Method
+}
+
+// NewGame creates a game starting from the given FEN, or the standard start position if empty.
+// The FEN has to be a legal position, see ParseFEN.
+func NewGame(fen string) (*Game, error) {
+	return newGame(fen, false)
+}
+
+// NewChess960Game creates a Chess960 game, castling moves are encoded as the king taking its own rook.
+// Standard positions work too, so the start position is just number 518.
+func NewChess960Game(fen string) (*Game, error) {
+	return newGame(fen, true)
+}
+
+func newGame(fen string, chess960 bool) (*Game, error) {
+	if fen == "" {
+		fen = START_FEN
+	}
+	board, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	if chess960 {
+		board.Chess960 = true
+	}
+	g := &Game{
+		Board:     board,
+		StartFEN:  fen,
+		positions: []uint64{board.Hash},
+	}
+	g.updateOutcome()
+	return g, nil
+}
+
+// MakeMove plays a legal move and updates the outcome of the game
+func (g *Game) MakeMove(move Move) error {
+	if g.outcome != NoOutcome {
+		return ErrGameOver
+	}
+	if !g.isLegal(move) {
+		return fmt.Errorf("%w: %s", ErrIllegalMove, move.String())
+	}
+	g.push(move)
+	g.redo = g.redo[:0]
+	g.updateOutcome()
+	return nil
+}
+
+// Undo takes back the last move, returns false if there is nothing to undo
+func (g *Game) Undo() bool {
+	if len(g.moves) == 0 {
+		return false
+	}
+	last := len(g.moves) - 1
+	move, state := g.moves[last], g.states[last]
+	g.moves = g.moves[:last]
+	g.states = g.states[:last]
+	g.positions = g.positions[:len(g.positions)-1]
+	g.Board.UnmakeMove(move, state)
+	g.redo = append(g.redo, move)
+	// whatever ended the game is no longer true once a move is taken back
+	g.outcome = NoOutcome
+	g.method = NoMethod
+	g.updateOutcome()
+	return true
+}
+
+// Redo replays the last undone move, returns false if there is nothing to redo
+func (g *Game) Redo() bool {
+	if len(g.redo) == 0 || g.outcome != NoOutcome {
+		return false
+	}
+	last := len(g.redo) - 1
+	move := g.redo[last]
+	g.redo = g.redo[:last]
+	g.push(move)
+	g.updateOutcome()
+	return true
+}
+
+// CanUndo returns true if there is a move to take back
+func (g *Game) CanUndo() bool {
+	return len(g.moves) > 0
+}
+
+// CanRedo returns true if there is an undone move to replay
+func (g *Game) CanRedo() bool {
+	return len(g.redo) > 0 && g.outcome == NoOutcome
+}
+
+// Moves returns the moves played so far
+func (g *Game) Moves() []Move {
+	moves := make([]Move, len(g.moves))
+	copy(moves, g.moves)
+	return moves
+}
+
+// LastMove returns the most recent move, or 0 if no moves were played
+func (g *Game) LastMove() Move {
+	if len(g.moves) == 0 {
+		return 0
+	}
+	return g.moves[len(g.moves)-1]
+}
+
+// Outcome returns the result of the game, NoOutcome while it is still going
+func (g *Game) Outcome() Outcome {
+	return g.outcome
+}
+
+// Method returns how the game ended, NoMethod while it is still going
+func (g *Game) Method() Method {
+	return g.method
+}
+
+// IsOver returns true once the game has an outcome
+func (g *Game) IsOver() bool {
+	return g.outcome != NoOutcome
+}
+
+// Resign ends the game with a loss for the given color
+func (g *Game) Resign(color byte) {
+	if g.outcome != NoOutcome {
+		return
+	}
+	g.outcome = winnerAgainst(color)
+	g.method = Resignation
+}
+
+// Timeout ends the game with a loss for the given color flagging
+func (g *Game) Timeout(color byte) {
+	if g.outcome != NoOutcome {
+		return
+	}
+	g.outcome = winnerAgainst(color)
+	g.method = Timeout
+}
+
+// AgreeDraw ends the game in a draw by agreement
+func (g *Game) AgreeDraw() {
+	if g.outcome != NoOutcome {
+		return
+	}
+	g.outcome = Draw
+	g.method = DrawAgreement
+}
+
+// RepetitionCount returns how many times the current position has occurred, including now
+func (g *Game) RepetitionCount() int {
+	current := g.positions[len(g.positions)-1]
+	count := 0
+	// positions before the last pawn move or capture can't repeat
+	oldest := max(len(g.positions)-1-g.Board.HalfMoves, 0)
+	for i := len(g.positions) - 1; i >= oldest; i-- {
+		if g.positions[i] == current {
+			count++
+		}
+	}
+	return count
+}
+
+// CanClaimDraw returns the rule a player could claim a draw by right now, if any
+func (g *Game) CanClaimDraw() (Method, bool) {
+	if g.outcome != NoOutcome {
+		return NoMethod, false
+	}
+	if g.RepetitionCount() >= 3 {
+		return ThreefoldRepetition, true
+	}
+	if g.Board.HalfMoves >= 100 {
+		return FiftyMoveRule, true
+	}
+	return NoMethod, false
+}
+
+// ClaimDraw ends the game in a draw if threefold repetition or the fifty move rule applies
+func (g *Game) ClaimDraw() error {
+	method, ok := g.CanClaimDraw()
+	if !ok {
+		return ErrNoDrawClaim
+	}
+	g.outcome = Draw
+	g.method = method
+	return nil
+}
+
+// makes the move on the board and records it
+func (g *Game) push(move Move) {
+	state := g.Board.MakeMove(move)
+	g.moves = append(g.moves, move)
+	g.states = append(g.states, state)
+	g.positions = append(g.positions, g.Board.Hash)
+}
+
+func (g *Game) isLegal(move Move) bool {
+	g.Board.GenerateLegalMoves()
+	for _, legalMove := range g.Board.LegalMoves {
+		if legalMove == move {
+			return true
+		}
+	}
+	return false
+}
+
+// checks for checkmate, stalemate and automatic draws, also leaves Board.LegalMoves up to date
+func (g *Game) updateOutcome() {
+	g.Board.GenerateLegalMoves()
+	if len(g.Board.LegalMoves) == 0 {
+		color := BLACK
+		if g.Board.WhiteToMove {
+			color = WHITE
+		}
+		if g.Board.IsInCheck(color) {
+			g.outcome = winnerAgainst(color)
+			g.method = Checkmate
+		} else {
+			g.outcome = Draw
+			g.method = Stalemate
+		}
+		return
+	}
+
+	// checkmate on the last move still wins, so these come after
+	switch {
+	case g.Board.IsInsufficientMaterial():
+		g.method = InsufficientMaterial
+	case g.RepetitionCount() >= 5:
+		g.method = FivefoldRepetition
+	case g.Board.HalfMoves >= 150:
+		g.method = SeventyFiveMoveRule
+	default:
+		return
+	}
+	g.outcome = Draw
+}
+
+// the outcome where the given color lost
+func winnerAgainst(color byte) Outcome {
+	if color == WHITE {
+		return BlackWon
+	}
+	return WhiteWon
+}
@@ -0,0 +1,284 @@
+package chess
+
+import (
+	"errors"
+	"testing"
+)
+
+// creates a game from a FEN the test knows is legal
+func mustNewGame(t *testing.T, fen string) *Game {
+	t.Helper()
+	g, err := NewGame(fen)
+	if err != nil {
+		t.Fatalf("NewGame(%q) failed: %v", fen, err)
+	}
+	return g
+}
+
+// plays a list of coordinate moves, matching them against the legal moves so flags are right
+func playMoves(t *testing.T, g *Game, moves ...string) {
+	t.Helper()
+	for _, moveStr := range moves {
+		source := StringToSquare(moveStr[:2])
+		target := StringToSquare(moveStr[2:4])
+		g.Board.GenerateLegalMoves()
+		found := false
+		for _, move := range g.Board.LegalMoves {
+			if move.Source() == source && move.Target() == target {
+				if err := g.MakeMove(move); err != nil {
+					t.Fatalf("MakeMove(%s) failed: %v", moveStr, err)
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("Move %s is not legal in %s", moveStr, g.Board.ExportFEN())
+		}
+	}
+}
+
+func TestNewGame(t *testing.T) {
+	g := mustNewGame(t, "")
+	if g.Board.ExportFEN() != START_FEN {
+		t.Errorf("Expected start position, got %s", g.Board.ExportFEN())
+	}
+	if g.IsOver() || g.Outcome() != NoOutcome || g.Method() != NoMethod {
+		t.Error("New game should not be over")
+	}
+	if len(g.Board.LegalMoves) != 20 {
+		t.Errorf("Expected 20 legal moves, got %d", len(g.Board.LegalMoves))
+	}
+}
+
+func TestNewGameInvalidFEN(t *testing.T) {
+	if _, err := NewGame("8/8/8/8/8/8/8/8 w - - 0 1"); !errors.Is(err, ErrMissingKing) {
+		t.Errorf("Expected ErrMissingKing, got %v", err)
+	}
+	if _, err := NewGame("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w"); !errors.Is(err, ErrFENFieldCount) {
+		t.Errorf("Expected ErrFENFieldCount, got %v", err)
+	}
+}
+
+func TestGameCheckmate(t *testing.T) {
+	g := mustNewGame(t, START_FEN)
+	// fool's mate
+	playMoves(t, g, "f2f3", "e7e5", "g2g4", "d8h4")
+
+	if g.Outcome() != BlackWon {
+		t.Errorf("Expected BlackWon, got %v", g.Outcome())
+	}
+	if g.Method() != Checkmate {
+		t.Errorf("Expected Checkmate, got %v", g.Method())
+	}
+	if g.Outcome().String() != "0-1" {
+		t.Errorf("Expected 0-1, got %s", g.Outcome().String())
+	}
+
+	// no more moves once the game is over
+	if err := g.MakeMove(NewMove(StringToSquare("e2"), StringToSquare("e3"), 0)); !errors.Is(err, ErrGameOver) {
+		t.Errorf("Expected ErrGameOver, got %v", err)
+	}
+}
+
+func TestGameStalemate(t *testing.T) {
+	g := mustNewGame(t, "k7/8/K7/8/8/8/8/1R6 w - - 0 1")
+	playMoves(t, g, "b1b7")
+
+	if g.Outcome() != Draw {
+		t.Errorf("Expected Draw, got %v", g.Outcome())
+	}
+	if g.Method() != Stalemate {
+		t.Errorf("Expected Stalemate, got %v", g.Method())
+	}
+}
+
+func TestGameIllegalMove(t *testing.T) {
+	g := mustNewGame(t, START_FEN)
+	err := g.MakeMove(NewMove(StringToSquare("e2"), StringToSquare("e5"), 0))
+	if !errors.Is(err, ErrIllegalMove) {
+		t.Errorf("Expected ErrIllegalMove, got %v", err)
+	}
+	if len(g.Moves()) != 0 {
+		t.Errorf("Illegal move should not be recorded")
+	}
+}
+
+func TestGameUndoRedo(t *testing.T) {
+	g := mustNewGame(t, START_FEN)
+	if g.Undo() {
+		t.Error("Undo should fail with no moves played")
+	}
+
+	playMoves(t, g, "e2e4")
+	afterOne := g.Board.ExportFEN()
+	playMoves(t, g, "e7e5", "g1f3")
+	afterThree := g.Board.ExportFEN()
+
+	if !g.Undo() {
+		t.Fatal("Undo should succeed")
+	}
+	if !g.Undo() {
+		t.Fatal("Undo should succeed")
+	}
+	if g.Board.ExportFEN() != afterOne {
+		t.Errorf("Expected %s after undo, got %s", afterOne, g.Board.ExportFEN())
+	}
+	if len(g.Moves()) != 1 {
+		t.Errorf("Expected 1 move after undo, got %d", len(g.Moves()))
+	}
+
+	if !g.Redo() || !g.Redo() {
+		t.Fatal("Redo should succeed")
+	}
+	if g.Board.ExportFEN() != afterThree {
+		t.Errorf("Expected %s after redo, got %s", afterThree, g.Board.ExportFEN())
+	}
+	if g.CanRedo() {
+		t.Error("Nothing should be left to redo")
+	}
+
+	// a new move throws away the redo history
+	g.Undo()
+	playMoves(t, g, "b1c3")
+	if g.CanRedo() {
+		t.Error("Making a move should clear the redo history")
+	}
+}
+
+func TestGameUndoCheckmate(t *testing.T) {
+	g := mustNewGame(t, START_FEN)
+	playMoves(t, g, "f2f3", "e7e5", "g2g4", "d8h4")
+	if !g.IsOver() {
+		t.Fatal("Game should be over")
+	}
+	g.Undo()
+	if g.IsOver() {
+		t.Error("Game should not be over after undoing the mating move")
+	}
+	if !g.Redo() {
+		t.Fatal("Redo should succeed")
+	}
+	if g.Method() != Checkmate {
+		t.Errorf("Expected Checkmate after redo, got %v", g.Method())
+	}
+}
+
+func TestGameResignTimeoutAgreement(t *testing.T) {
+	g := mustNewGame(t, START_FEN)
+	g.Resign(WHITE)
+	if g.Outcome() != BlackWon || g.Method() != Resignation {
+		t.Errorf("Expected black to win by resignation, got %v by %v", g.Outcome(), g.Method())
+	}
+
+	g = mustNewGame(t, START_FEN)
+	g.Timeout(BLACK)
+	if g.Outcome() != WhiteWon || g.Method() != Timeout {
+		t.Errorf("Expected white to win on time, got %v by %v", g.Outcome(), g.Method())
+	}
+
+	g = mustNewGame(t, START_FEN)
+	g.AgreeDraw()
+	if g.Outcome() != Draw || g.Method() != DrawAgreement {
+		t.Errorf("Expected draw by agreement, got %v by %v", g.Outcome(), g.Method())
+	}
+	// the first result sticks
+	g.Resign(WHITE)
+	if g.Method() != DrawAgreement {
+		t.Errorf("Result should not change once the game is over, got %v", g.Method())
+	}
+}
+
+func TestGameThreefoldRepetition(t *testing.T) {
+	g := mustNewGame(t, START_FEN)
+	if _, ok := g.CanClaimDraw(); ok {
+		t.Error("No draw should be claimable in the start position")
+	}
+	if err := g.ClaimDraw(); err != ErrNoDrawClaim {
+		t.Errorf("Expected ErrNoDrawClaim, got %v", err)
+	}
+
+	// shuffle the knights back and forth, the start position occurs for the third time
+	playMoves(t, g, "g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8")
+	if g.RepetitionCount() != 3 {
+		t.Errorf("Expected the start position to have occurred 3 times, got %d", g.RepetitionCount())
+	}
+	if g.IsOver() {
+		t.Error("Threefold repetition has to be claimed")
+	}
+	method, ok := g.CanClaimDraw()
+	if !ok || method != ThreefoldRepetition {
+		t.Errorf("Expected a threefold repetition claim, got %v", method)
+	}
+	if err := g.ClaimDraw(); err != nil {
+		t.Fatalf("ClaimDraw failed: %v", err)
+	}
+	if g.Outcome() != Draw || g.Method() != ThreefoldRepetition {
+		t.Errorf("Expected draw by threefold repetition, got %v by %v", g.Outcome(), g.Method())
+	}
+}
+
+func TestGameFivefoldRepetition(t *testing.T) {
+	g := mustNewGame(t, START_FEN)
+	for range 4 {
+		playMoves(t, g, "g1f3", "g8f6", "f3g1", "f6g8")
+	}
+	if g.RepetitionCount() != 5 {
+		t.Errorf("Expected the start position to have occurred 5 times, got %d", g.RepetitionCount())
+	}
+	if g.Outcome() != Draw || g.Method() != FivefoldRepetition {
+		t.Errorf("Expected draw by fivefold repetition, got %v by %v", g.Outcome(), g.Method())
+	}
+
+	// undoing the last move reopens the game
+	g.Undo()
+	if g.IsOver() {
+		t.Error("Game should not be over after undo")
+	}
+}
+
+func TestGameFiftyMoveRule(t *testing.T) {
+	g := mustNewGame(t, "4k3/8/8/8/8/8/8/R3K3 w - - 99 80")
+	if _, ok := g.CanClaimDraw(); ok {
+		t.Error("Fifty move rule should not apply yet")
+	}
+	playMoves(t, g, "a1a2")
+	method, ok := g.CanClaimDraw()
+	if !ok || method != FiftyMoveRule {
+		t.Errorf("Expected a fifty move rule claim, got %v", method)
+	}
+
+	// a pawn move resets the clock
+	g = mustNewGame(t, "4k3/8/8/8/8/8/4P3/R3K3 w - - 99 80")
+	playMoves(t, g, "e2e3")
+	if g.Board.HalfMoves != 0 {
+		t.Errorf("Expected the halfmove clock to reset, got %d", g.Board.HalfMoves)
+	}
+	if _, ok := g.CanClaimDraw(); ok {
+		t.Error("Fifty move rule should not apply after a pawn move")
+	}
+}
+
+func TestGameSeventyFiveMoveRule(t *testing.T) {
+	g := mustNewGame(t, "4k3/8/8/8/8/8/8/R3K3 w - - 149 120")
+	playMoves(t, g, "a1a2")
+	if g.Outcome() != Draw || g.Method() != SeventyFiveMoveRule {
+		t.Errorf("Expected draw by seventy-five move rule, got %v by %v", g.Outcome(), g.Method())
+	}
+
+	// checkmate on the last move takes priority
+	g = mustNewGame(t, "6k1/5ppp/8/8/8/8/8/R3K3 w - - 149 120")
+	playMoves(t, g, "a1a8")
+	if g.Outcome() != WhiteWon || g.Method() != Checkmate {
+		t.Errorf("Expected white to win by checkmate, got %v by %v", g.Outcome(), g.Method())
+	}
+}
+
+func TestGameInsufficientMaterial(t *testing.T) {
+	// white captures the last black piece
+	g := mustNewGame(t, "4k3/8/8/8/8/8/3r4/4K3 w - - 0 1")
+	playMoves(t, g, "e1d2")
+	if g.Outcome() != Draw || g.Method() != InsufficientMaterial {
+		t.Errorf("Expected draw by insufficient material, got %v by %v", g.Outcome(), g.Method())
+	}
+}
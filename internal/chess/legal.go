@@ -0,0 +1,159 @@
+package chess
+
+/*
+	Legal move filtering with check and pin masks.
+	https://www.chessprogramming.org/Checks_and_Pinned_Pieces_(Bitboards)
+	Playing every pseudo-legal move and looking for a check afterwards is simple but slow.
+	Instead we look at the king once per position and work out two things:
+	- the check mask: squares a piece can move to that deal with a check.
+	  With no check that's every square, with one checker it's the checker and the squares between it and the king,
+	  and with two checkers nothing but a king move helps.
+	- the pinned pieces: pieces that can only move along the line between the king and the enemy slider behind them.
+	Any move that isn't by the king or en passant is legal if it lands in the check mask and stays on its pin line.
+	King moves and en passant can expose the king in ways the masks don't catch, so those still get made and unmade.
+*/
+
+var (
+	// the squares strictly between two squares on the same rank, file or diagonal, empty otherwise
+	betweenMasks [64][64]Bitboard
+	// the whole line through two squares on the same rank, file or diagonal, empty otherwise
+	lineMasks [64][64]Bitboard
+	// the squares a pawn of each color attacks, indexed by color index
+	pawnAttackMasks [2][64]Bitboard
+)
+
+func init() {
+	for a := range 64 {
+		for b := range 64 {
+			if a == b {
+				continue
+			}
+			endpoints := Bitboard(1)<<a | Bitboard(1)<<b
+			if rookAttacks(a, 0).Occupied(b) {
+				betweenMasks[a][b] = rookAttacks(a, Bitboard(1)<<b) & rookAttacks(b, Bitboard(1)<<a)
+				lineMasks[a][b] = rookAttacks(a, 0)&rookAttacks(b, 0) | endpoints
+			} else if bishopAttacks(a, 0).Occupied(b) {
+				betweenMasks[a][b] = bishopAttacks(a, Bitboard(1)<<b) & bishopAttacks(b, Bitboard(1)<<a)
+				lineMasks[a][b] = bishopAttacks(a, 0)&bishopAttacks(b, 0) | endpoints
+			}
+		}
+	}
+	for square := range 64 {
+		pawn := Bitboard(1) << square
+		pawnAttackMasks[WHITE_INDEX][square] = (pawn<<7)&^FileH | (pawn<<9)&^FileA
+		pawnAttackMasks[BLACK_INDEX][square] = (pawn>>9)&^FileH | (pawn>>7)&^FileA
+	}
+}
+
+// squares a rook on the given square attacks with the given pieces in the way
+func rookAttacks(square int, occupancy Bitboard) Bitboard {
+	blockers := occupancy & RookMasks[square]
+	return RookAttacks[square][(blockers*RookMagics[square])>>(64-RookShifts[square])]
+}
+
+// squares a bishop on the given square attacks with the given pieces in the way
+func bishopAttacks(square int, occupancy Bitboard) Bitboard {
+	blockers := occupancy & BishopMasks[square]
+	return BishopAttacks[square][(blockers*BishopMagics[square])>>(64-BishopShifts[square])]
+}
+
+// everything legal move filtering needs to know about the king of the side to move
+type legalityMasks struct {
+	// -1 if the side to move has no king
+	kingSquare int
+	checkers   Bitboard
+	checkMask  Bitboard
+	pinned     Bitboard
+}
+
+// works out the checkers, check mask and pinned pieces for the side to move
+func (b *Board) legalityMasks() legalityMasks {
+	us, them := WHITE_INDEX, BLACK_INDEX
+	if !b.WhiteToMove {
+		us, them = BLACK_INDEX, WHITE_INDEX
+	}
+	masks := legalityMasks{kingSquare: -1, checkMask: ^Bitboard(0)}
+	if b.Pieces[us][KING] == 0 {
+		return masks
+	}
+	king := b.Pieces[us][KING].GetLSB()
+	masks.kingSquare = king
+
+	enemy := &b.Pieces[them]
+	occupancy := b.Occupancy()
+	rookLike := enemy[ROOK] | enemy[QUEEN]
+	bishopLike := enemy[BISHOP] | enemy[QUEEN]
+
+	// the king's own attack patterns find the pieces attacking it
+	masks.checkers = pawnAttackMasks[us][king]&enemy[PAWN] |
+		KnightMasks[king]&enemy[KNIGHT] |
+		rookAttacks(king, occupancy)&rookLike |
+		bishopAttacks(king, occupancy)&bishopLike
+	switch masks.checkers.Count() {
+	case 0:
+	case 1:
+		checker := masks.checkers.GetLSB()
+		masks.checkMask = masks.checkers | betweenMasks[king][checker]
+	default:
+		// double check, only the king can move
+		masks.checkMask = 0
+	}
+
+	masks.pinned = b.pinnedTo(king, us, them)
+	return masks
+}
+
+// the pieces of color index us that are pinned to the square by sliders of color index them
+func (b *Board) pinnedTo(king, us, them int) Bitboard {
+	enemy := &b.Pieces[them]
+	rookLike := enemy[ROOK] | enemy[QUEEN]
+	bishopLike := enemy[BISHOP] | enemy[QUEEN]
+	occupancy := b.Occupancy()
+	var pinned Bitboard
+	// sliders that would see the king if our own pieces weren't there
+	snipers := rookAttacks(king, b.Colors[them])&rookLike | bishopAttacks(king, b.Colors[them])&bishopLike
+	for snipers != 0 {
+		sniper := snipers.PopLSB()
+		between := betweenMasks[king][sniper] & occupancy
+		if between.Count() == 1 && between&b.Colors[us] != 0 {
+			pinned |= between
+		}
+	}
+	return pinned
+}
+
+// removes the moves that aren't legal from the list, keeping the order
+func (b *Board) filterLegalMoves(list *MoveList, masks *legalityMasks) {
+	count := 0
+	for i := 0; i < list.Count; i++ {
+		if b.isLegalMove(list.Moves[i], masks) {
+			list.Moves[count] = list.Moves[i]
+			count++
+		}
+	}
+	list.Count = count
+}
+
+// checks a pseudo-legal move against the masks, making it if the masks can't tell
+func (b *Board) isLegalMove(move Move, masks *legalityMasks) bool {
+	source, target := move.Source(), move.Target()
+	if source == masks.kingSquare || move.Flag() == EN_PASSANT_FLAG {
+		return b.leavesKingSafe(move)
+	}
+	if !masks.checkMask.Occupied(target) {
+		return false
+	}
+	return !masks.pinned.Occupied(source) || lineMasks[masks.kingSquare][source].Occupied(target)
+}
+
+// makes the move and checks that it doesn't leave the mover's king in check
+func (b *Board) leavesKingSafe(move Move) bool {
+	movingColor := BLACK
+	if b.WhiteToMove {
+		movingColor = WHITE
+	}
+	state := b.MakeMove(move)
+	safe := !b.IsInCheck(movingColor)
+	b.UnmakeMove(move, state)
+	return safe
+}
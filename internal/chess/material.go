@@ -0,0 +1,44 @@
+package chess
+
+import "strings"
+
+/*
+	A material key names the pieces in a position, like KRPvKB for king, rook and pawn against king and bishop.
+	Endgame table files are named by their material, so probing one starts with the key of the board.
+	https://www.chessprogramming.org/Material
+*/
+
+// MATERIAL_ORDER is the order a side's pieces are listed in a material key
+const MATERIAL_ORDER = "KQRBNP"
+
+// the piece types in MATERIAL_ORDER
+var materialTypes = [len(MATERIAL_ORDER)]byte{KING, QUEEN, ROOK, BISHOP, KNIGHT, PAWN}
+
+// MaterialKey returns each side's pieces as letters in MATERIAL_ORDER, like "KRP" and "KB"
+func (b *Board) MaterialKey() (white, black string) {
+	var sides [2]strings.Builder
+	for color := range sides {
+		for i, piece := range materialTypes {
+			sides[color].WriteString(strings.Repeat(MATERIAL_ORDER[i:i+1], b.Pieces[color][piece].Count()))
+		}
+	}
+	return sides[WHITE_INDEX].String(), sides[BLACK_INDEX].String()
+}
+
+// SortMaterial sorts one side's piece letters into MATERIAL_ORDER, so "KPR" becomes "KRP".
+// Letters that aren't pieces are left out.
+func SortMaterial(side string) string {
+	var key strings.Builder
+	for _, piece := range MATERIAL_ORDER {
+		key.WriteString(strings.Repeat(string(piece), strings.Count(side, string(piece))))
+	}
+	return key.String()
+}
+
+// MaterialPieceType returns the piece type a letter of MATERIAL_ORDER stands for, NONE for anything else
+func MaterialPieceType(letter rune) byte {
+	if i := strings.IndexRune(MATERIAL_ORDER, letter); i >= 0 {
+		return materialTypes[i]
+	}
+	return NONE
+}
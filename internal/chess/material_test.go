@@ -0,0 +1,45 @@
+package chess
+
+import "testing"
+
+func TestMaterialKey(t *testing.T) {
+	tests := []struct {
+		fen, white, black string
+	}{
+		{START_FEN, "KQRRBBNNPPPPPPPP", "KQRRBBNNPPPPPPPP"},
+		{"8/8/8/3k4/8/8/8/4K3 w - - 0 1", "K", "K"},
+		{"8/2b5/8/3k4/8/1P6/8/R3K3 b - - 0 1", "KRP", "KB"},
+	}
+	for _, tt := range tests {
+		board, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if white, black := board.MaterialKey(); white != tt.white || black != tt.black {
+			t.Errorf("%s: got %s and %s, want %s and %s", tt.fen, white, black, tt.white, tt.black)
+		}
+	}
+}
+
+func TestSortMaterial(t *testing.T) {
+	tests := map[string]string{
+		"KPR":   "KRP",
+		"KNBQ":  "KQBN",
+		"K":     "K",
+		"KPPxR": "KRPP",
+		"":      "",
+	}
+	for side, want := range tests {
+		if got := SortMaterial(side); got != want {
+			t.Errorf("SortMaterial(%q) = %q, want %q", side, got, want)
+		}
+	}
+	for i, letter := range MATERIAL_ORDER {
+		if got := MaterialPieceType(letter); got != materialTypes[i] {
+			t.Errorf("MaterialPieceType(%c) = %d, want %d", letter, got, materialTypes[i])
+		}
+	}
+	if got := MaterialPieceType('v'); got != NONE {
+		t.Errorf("MaterialPieceType(v) = %d, want NONE", got)
+	}
+}
@@ -1,5 +1,11 @@
 package chess
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type Move uint16
 
 // Move is a 16-bit integer with the following format:
@@ -24,9 +30,13 @@ func NewMove(source, target, flag int) Move {
 	return Move(uint16(source) | uint16(target<<6) | uint16(flag<<12))
 }
 
-// get the string representation of a move e.g. "e2e4"
+// get the string representation of a move in UCI notation e.g. "e2e4" or "a7a8q"
 func (m *Move) String() string {
-	return SquareToString(m.Source()) + SquareToString(m.Target())
+	s := SquareToString(m.Source()) + SquareToString(m.Target())
+	if promotion := m.PromotionPiece(); promotion != NONE {
+		s += strings.ToLower(sanPieceLetters[promotion])
+	}
+	return s
 }
 
 // get the index of a move's source square
@@ -44,6 +54,21 @@ func (m *Move) Flag() int {
 	return int((*m >> 12) & 7) // gets the leading 3 bits
 }
 
+// get the piece type a pawn promotes to, or NONE if the move isn't a promotion
+func (m *Move) PromotionPiece() byte {
+	switch m.Flag() {
+	case PROMOTE_KNIGHT_FLAG:
+		return KNIGHT
+	case PROMOTE_BISHOP_FLAG:
+		return BISHOP
+	case PROMOTE_ROOK_FLAG:
+		return ROOK
+	case PROMOTE_QUEEN_FLAG:
+		return QUEEN
+	}
+	return NONE
+}
+
 // TODO find a better file for this function
 // convert square index (0-63) to a chess coordinate (e.g., "e2")
 func SquareToString(square int) string {
@@ -60,3 +85,30 @@ func StringToSquare(s string) int {
 	rank := int(s[1] - '1')
 	return rank*8 + file
 }
+
+var ErrInvalidUCIMove = errors.New("invalid UCI move")
+
+// ParseUCIMove finds the legal move for a UCI coordinate move like "e2e4", "e1g1" or "a7a8q".
+// UCI moves have no flags, so they are matched against the legal moves to get castling, en passant and double pushes right.
+func (b *Board) ParseUCIMove(s string) (Move, error) {
+	if (len(s) != 4 && len(s) != 5) || !isSquare(s[:2]) || !isSquare(s[2:4]) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidUCIMove, s)
+	}
+	source, target := StringToSquare(s[:2]), StringToSquare(s[2:4])
+	promotion := NONE
+	if len(s) == 5 {
+		promotion = pieceTypeFromLetter(s[4])
+		if promotion == NONE || promotion == KING {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidUCIMove, s)
+		}
+	}
+
+	var moves MoveList
+	b.GenerateLegalMovesInto(&moves)
+	for _, move := range moves.Slice() {
+		if move.Source() == source && move.Target() == target && move.PromotionPiece() == promotion {
+			return move, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrIllegalMove, s)
+}
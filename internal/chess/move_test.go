@@ -1,6 +1,7 @@
 package chess
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -49,3 +50,70 @@ func TestSquareToString(t *testing.T) {
 		t.Errorf("Expected h8, got %s", str)
 	}
 }
+
+func TestMoveStringPromotion(t *testing.T) {
+	move := NewMove(StringToSquare("a7"), StringToSquare("a8"), PROMOTE_QUEEN_FLAG)
+	if move.String() != "a7a8q" {
+		t.Errorf("Expected a7a8q, got %s", move.String())
+	}
+	move = NewMove(StringToSquare("b2"), StringToSquare("a1"), PROMOTE_KNIGHT_FLAG)
+	if move.String() != "b2a1n" {
+		t.Errorf("Expected b2a1n, got %s", move.String())
+	}
+}
+
+func TestParseUCIMove(t *testing.T) {
+	testCases := []struct {
+		fen  string
+		move string
+		flag int
+	}{
+		{START_FEN, "e2e4", PAWN_DOUBLE_FLAG},
+		{START_FEN, "g1f3", NO_FLAG},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", CASTLE_FLAG},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", CASTLE_FLAG},
+		{"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 1", "e5d6", EN_PASSANT_FLAG},
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a7a8q", PROMOTE_QUEEN_FLAG},
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a7a8n", PROMOTE_KNIGHT_FLAG},
+	}
+	for _, tc := range testCases {
+		board := NewBoard()
+		board.LoadFEN(tc.fen)
+		move, err := board.ParseUCIMove(tc.move)
+		if err != nil {
+			t.Errorf("ParseUCIMove(%q) failed: %v", tc.move, err)
+			continue
+		}
+		if move.Flag() != tc.flag {
+			t.Errorf("ParseUCIMove(%q): expected flag %d, got %d", tc.move, tc.flag, move.Flag())
+		}
+		// the move has to round trip for bestmove output
+		if move.String() != tc.move {
+			t.Errorf("Expected %s, got %s", tc.move, move.String())
+		}
+	}
+}
+
+func TestParseUCIMoveErrors(t *testing.T) {
+	testCases := []struct {
+		fen      string
+		move     string
+		expected error
+	}{
+		{START_FEN, "e2e5", ErrIllegalMove},
+		{START_FEN, "e1g1", ErrIllegalMove},
+		{START_FEN, "e2", ErrInvalidUCIMove},
+		{START_FEN, "e2e4x", ErrInvalidUCIMove},
+		{START_FEN, "i2i4", ErrInvalidUCIMove},
+		// promotions need the piece
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a7a8", ErrIllegalMove},
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a7a8k", ErrInvalidUCIMove},
+	}
+	for _, tc := range testCases {
+		board := NewBoard()
+		board.LoadFEN(tc.fen)
+		if _, err := board.ParseUCIMove(tc.move); !errors.Is(err, tc.expected) {
+			t.Errorf("ParseUCIMove(%q): expected %v, got %v", tc.move, tc.expected, err)
+		}
+	}
+}
@@ -61,188 +61,287 @@ func (b *Board) GeneratePawnMoves() []Move {
 	return b.GenerateBlackPawnMoves()
 }
 
-// gets all white pawn moves
+// gets all legal white pawn moves
 func (b *Board) GenerateWhitePawnMoves() []Move {
-	wp := WHITE | PAWN
-	moves := make([]Move, 0)
-	enPassantSquare := NewBitboard()
+	return b.generateLegal(b.addWhitePawnMoves)
+}
+
+// gets all legal black pawn moves
+func (b *Board) GenerateBlackPawnMoves() []Move {
+	return b.generateLegal(b.addBlackPawnMoves)
+}
+
+// gets all legal knight moves for the current position
+func (b *Board) GenerateKnightMoves() []Move {
+	return b.generateLegal(b.addKnightMoves)
+}
+
+// gets all legal bishop moves for the current position
+func (b *Board) GenerateBishopMoves() []Move {
+	return b.generateLegal(b.addBishopMoves)
+}
+
+// gets all legal rook moves for the current position
+func (b *Board) GenerateRookMoves() []Move {
+	return b.generateLegal(b.addRookMoves)
+}
+
+// gets all legal queen moves for the current position
+func (b *Board) GenerateQueenMoves() []Move {
+	return b.generateLegal(b.addQueenMoves)
+}
+
+// gets all legal king moves for the current position
+func (b *Board) GenerateKingMoves() []Move {
+	return b.generateLegal(b.addKingMoves)
+}
+
+// runs one of the pseudo-legal generators and returns its legal moves in a new slice
+func (b *Board) generateLegal(add func(*MoveList, genType)) []Move {
+	var list MoveList
+	add(&list, genAll)
+	masks := b.legalityMasks()
+	b.filterLegalMoves(&list, &masks)
+	return list.ToSlice()
+}
+
+// which kind of moves the pseudo-legal generators add
+type genType int
+
+const (
+	genAll genType = iota
+	// captures, en passant and every promotion
+	genCaptures
+	// everything else, including castling
+	genQuiets
+)
+
+// the squares pieces other than pawns may move to for the given kind of moves
+func (b *Board) genTargets(gen genType) Bitboard {
+	us, them := WHITE_INDEX, BLACK_INDEX
+	if !b.WhiteToMove {
+		us, them = BLACK_INDEX, WHITE_INDEX
+	}
+	switch gen {
+	case genCaptures:
+		return b.Colors[them]
+	case genQuiets:
+		return ^b.Occupancy()
+	default:
+		return ^b.Colors[us]
+	}
+}
+
+// pseudo-legal pawn moves for the side to move
+func (b *Board) addPawnMoves(list *MoveList, gen genType) {
+	if b.WhiteToMove {
+		b.addWhitePawnMoves(list, gen)
+	} else {
+		b.addBlackPawnMoves(list, gen)
+	}
+}
+
+// pseudo-legal white pawn moves
+func (b *Board) addWhitePawnMoves(list *MoveList, gen genType) {
+	pawns := b.Pieces[WHITE_INDEX][PAWN]
+	var enPassantSquare Bitboard
 	if b.EnPassantSquare != -1 {
 		enPassantSquare.Set(b.EnPassantSquare)
 	}
 	// single push is when the square in front of the pawn is empty and not a promotion square
-	singlePushBoard := (*b.Bitboards[wp] << 8) & ^*b.Bitboards[WHITE] & ^*b.Bitboards[BLACK] & ^Rank8
+	singlePushBoard := (pawns << 8) & ^b.Colors[WHITE_INDEX] & ^b.Colors[BLACK_INDEX] & ^Rank8
 
 	// double push is another single push from legal single pushes from the pawns starting rank
-	doublePushBoard := (singlePushBoard & Rank3) << 8 & ^*b.Bitboards[WHITE] & ^*b.Bitboards[BLACK] & ^Rank8
+	doublePushBoard := (singlePushBoard & Rank3) << 8 & ^b.Colors[WHITE_INDEX] & ^b.Colors[BLACK_INDEX] & ^Rank8
 
 	// left capture is when there is an enemy piece on the left diagonal or the en passant square
-	leftCaptureBoard := ((*b.Bitboards[wp] << 7) & ^FileA & *b.Bitboards[BLACK])
+	leftCaptureBoard := ((pawns << 7) & ^FileH & b.Colors[BLACK_INDEX] & ^Rank8)
 
 	// right capture is when there is an enemy piece on the right diagonal or the en passant square
-	rightCaptureBoard := ((*b.Bitboards[wp] << 9) & ^FileH & *b.Bitboards[BLACK])
+	rightCaptureBoard := ((pawns << 9) & ^FileA & b.Colors[BLACK_INDEX] & ^Rank8)
 
 	// en passant
-	leftEnPassantBoard := ((*b.Bitboards[wp] << 7) & ^FileA & *enPassantSquare)
-	rightEnPassantBoard := ((*b.Bitboards[wp] << 9) & ^FileH & *enPassantSquare)
+	leftEnPassantBoard := ((pawns << 7) & ^FileH & enPassantSquare)
+	rightEnPassantBoard := ((pawns << 9) & ^FileA & enPassantSquare)
 
 	// promotion
-	promotionBoard := (*b.Bitboards[wp] << 8) & ^*b.Bitboards[WHITE] & ^*b.Bitboards[BLACK] & Rank8
-	promotionLeftCaptureBoard := (*b.Bitboards[wp] << 7) & ^FileA & *b.Bitboards[BLACK] & Rank8
-	promotionRightCaptureBoard := (*b.Bitboards[wp] << 9) & ^FileH & *b.Bitboards[BLACK] & Rank8
+	promotionBoard := (pawns << 8) & ^b.Colors[WHITE_INDEX] & ^b.Colors[BLACK_INDEX] & Rank8
+	promotionLeftCaptureBoard := (pawns << 7) & ^FileH & b.Colors[BLACK_INDEX] & Rank8
+	promotionRightCaptureBoard := (pawns << 9) & ^FileA & b.Colors[BLACK_INDEX] & Rank8
+
+	// only keep the boards for the kind of moves we want
+	if gen == genCaptures {
+		singlePushBoard, doublePushBoard = 0, 0
+	}
+	if gen == genQuiets {
+		leftCaptureBoard, rightCaptureBoard, leftEnPassantBoard, rightEnPassantBoard = 0, 0, 0, 0
+		promotionBoard, promotionLeftCaptureBoard, promotionRightCaptureBoard = 0, 0, 0
+	}
 
 	// turn the bitboards into moves
 	for singlePushBoard != 0 {
 		// pop the least significant bit
 		square := singlePushBoard.PopLSB()
-		// create a move and append it to the moves slice
-		moves = append(moves, NewMove(square-8, square, 0))
+		// create a move and add it to the list
+		list.Add(NewMove(square-8, square, 0))
 	}
 	for doublePushBoard != 0 {
 		square := doublePushBoard.PopLSB()
-		moves = append(moves, NewMove(square-16, square, PAWN_DOUBLE_FLAG))
+		list.Add(NewMove(square-16, square, PAWN_DOUBLE_FLAG))
 	}
 	for leftCaptureBoard != 0 {
 		square := leftCaptureBoard.PopLSB()
-		moves = append(moves, NewMove(square-7, square, 0))
+		list.Add(NewMove(square-7, square, 0))
 	}
 	for rightCaptureBoard != 0 {
 		square := rightCaptureBoard.PopLSB()
-		moves = append(moves, NewMove(square-9, square, 0))
+		list.Add(NewMove(square-9, square, 0))
 	}
 	for leftEnPassantBoard != 0 {
 		square := leftEnPassantBoard.PopLSB()
-		moves = append(moves, NewMove(square-7, square, EN_PASSANT_FLAG))
+		list.Add(NewMove(square-7, square, EN_PASSANT_FLAG))
 	}
 	for rightEnPassantBoard != 0 {
 		square := rightEnPassantBoard.PopLSB()
-		moves = append(moves, NewMove(square-9, square, EN_PASSANT_FLAG))
+		list.Add(NewMove(square-9, square, EN_PASSANT_FLAG))
 	}
 	for promotionBoard != 0 {
 		square := promotionBoard.PopLSB()
-		moves = append(moves, NewMove(square-8, square, PROMOTE_QUEEN_FLAG))
-		moves = append(moves, NewMove(square-8, square, PROMOTE_ROOK_FLAG))
-		moves = append(moves, NewMove(square-8, square, PROMOTE_BISHOP_FLAG))
-		moves = append(moves, NewMove(square-8, square, PROMOTE_KNIGHT_FLAG))
+		list.Add(NewMove(square-8, square, PROMOTE_QUEEN_FLAG))
+		list.Add(NewMove(square-8, square, PROMOTE_ROOK_FLAG))
+		list.Add(NewMove(square-8, square, PROMOTE_BISHOP_FLAG))
+		list.Add(NewMove(square-8, square, PROMOTE_KNIGHT_FLAG))
 	}
 	for promotionLeftCaptureBoard != 0 {
 		square := promotionLeftCaptureBoard.PopLSB()
-		moves = append(moves, NewMove(square-7, square, PROMOTE_QUEEN_FLAG))
-		moves = append(moves, NewMove(square-7, square, PROMOTE_ROOK_FLAG))
-		moves = append(moves, NewMove(square-7, square, PROMOTE_BISHOP_FLAG))
-		moves = append(moves, NewMove(square-7, square, PROMOTE_KNIGHT_FLAG))
+		list.Add(NewMove(square-7, square, PROMOTE_QUEEN_FLAG))
+		list.Add(NewMove(square-7, square, PROMOTE_ROOK_FLAG))
+		list.Add(NewMove(square-7, square, PROMOTE_BISHOP_FLAG))
+		list.Add(NewMove(square-7, square, PROMOTE_KNIGHT_FLAG))
 	}
 	for promotionRightCaptureBoard != 0 {
 		square := promotionRightCaptureBoard.PopLSB()
-		moves = append(moves, NewMove(square-9, square, PROMOTE_QUEEN_FLAG))
-		moves = append(moves, NewMove(square-9, square, PROMOTE_ROOK_FLAG))
-		moves = append(moves, NewMove(square-9, square, PROMOTE_BISHOP_FLAG))
-		moves = append(moves, NewMove(square-9, square, PROMOTE_KNIGHT_FLAG))
+		list.Add(NewMove(square-9, square, PROMOTE_QUEEN_FLAG))
+		list.Add(NewMove(square-9, square, PROMOTE_ROOK_FLAG))
+		list.Add(NewMove(square-9, square, PROMOTE_BISHOP_FLAG))
+		list.Add(NewMove(square-9, square, PROMOTE_KNIGHT_FLAG))
 	}
-	return b.FilterLegalMoves(moves)
 }
 
-// gets all black pawn moves
-func (b *Board) GenerateBlackPawnMoves() []Move {
-	bp := BLACK | PAWN
-	moves := make([]Move, 0)
-	enPassantSquare := NewBitboard()
+// pseudo-legal black pawn moves
+func (b *Board) addBlackPawnMoves(list *MoveList, gen genType) {
+	pawns := b.Pieces[BLACK_INDEX][PAWN]
+	var enPassantSquare Bitboard
 	if b.EnPassantSquare != -1 {
 		enPassantSquare.Set(b.EnPassantSquare)
 	}
 
 	// single push is when the square in front of the pawn is empty and not a promotion square
-	singlePushBoard := (*b.Bitboards[bp] >> 8) & ^*b.Bitboards[WHITE] & ^*b.Bitboards[BLACK] & ^Rank1
+	singlePushBoard := (pawns >> 8) & ^b.Colors[WHITE_INDEX] & ^b.Colors[BLACK_INDEX] & ^Rank1
 
 	// double push is another single push from legal single pushes from the pawns starting rank
-	doublePushBoard := (singlePushBoard & Rank6) >> 8 & ^*b.Bitboards[WHITE] & ^*b.Bitboards[BLACK] & ^Rank1
+	doublePushBoard := (singlePushBoard & Rank6) >> 8 & ^b.Colors[WHITE_INDEX] & ^b.Colors[BLACK_INDEX] & ^Rank1
 
 	// left capture is when there is an enemy piece on the left diagonal
-	leftCaptureBoard := (*b.Bitboards[bp] >> 9) & ^FileH & *b.Bitboards[WHITE]
+	leftCaptureBoard := (pawns >> 9) & ^FileH & b.Colors[WHITE_INDEX] & ^Rank1
 
 	// right capture is when there is an enemy piece on the right diagonal
-	rightCaptureBoard := (*b.Bitboards[bp] >> 7) & ^FileA & *b.Bitboards[WHITE]
+	rightCaptureBoard := (pawns >> 7) & ^FileA & b.Colors[WHITE_INDEX] & ^Rank1
 
 	// the en passant board is when the en passant square is the left or right diagonal of the pawn
-	leftEnPassantBoard := (*b.Bitboards[bp] >> 9) & ^FileH & *enPassantSquare
-	rightEnPassantBoard := (*b.Bitboards[bp] >> 7) & ^FileA & *enPassantSquare
+	leftEnPassantBoard := (pawns >> 9) & ^FileH & enPassantSquare
+	rightEnPassantBoard := (pawns >> 7) & ^FileA & enPassantSquare
 
 	// promotion
-	promotionBoard := (*b.Bitboards[bp] >> 8) & ^*b.Bitboards[WHITE] & ^*b.Bitboards[BLACK] & Rank1
-	promotionLeftCaptureBoard := (*b.Bitboards[bp] >> 9) & ^FileH & *b.Bitboards[WHITE] & Rank1
-	promotionRightCaptureBoard := (*b.Bitboards[bp] >> 7) & ^FileA & *b.Bitboards[WHITE] & Rank1
+	promotionBoard := (pawns >> 8) & ^b.Colors[WHITE_INDEX] & ^b.Colors[BLACK_INDEX] & Rank1
+	promotionLeftCaptureBoard := (pawns >> 9) & ^FileH & b.Colors[WHITE_INDEX] & Rank1
+	promotionRightCaptureBoard := (pawns >> 7) & ^FileA & b.Colors[WHITE_INDEX] & Rank1
+
+	// only keep the boards for the kind of moves we want
+	if gen == genCaptures {
+		singlePushBoard, doublePushBoard = 0, 0
+	}
+	if gen == genQuiets {
+		leftCaptureBoard, rightCaptureBoard, leftEnPassantBoard, rightEnPassantBoard = 0, 0, 0, 0
+		promotionBoard, promotionLeftCaptureBoard, promotionRightCaptureBoard = 0, 0, 0
+	}
 
 	for singlePushBoard != 0 {
 		square := singlePushBoard.PopLSB()
-		moves = append(moves, NewMove(square+8, square, 0))
+		list.Add(NewMove(square+8, square, 0))
 	}
 	for doublePushBoard != 0 {
 		square := doublePushBoard.PopLSB()
-		moves = append(moves, NewMove(square+16, square, PAWN_DOUBLE_FLAG))
+		list.Add(NewMove(square+16, square, PAWN_DOUBLE_FLAG))
 	}
 	for leftCaptureBoard != 0 {
 		square := leftCaptureBoard.PopLSB()
-		moves = append(moves, NewMove(square+9, square, 0))
+		list.Add(NewMove(square+9, square, 0))
 	}
 	for rightCaptureBoard != 0 {
 		square := rightCaptureBoard.PopLSB()
-		moves = append(moves, NewMove(square+7, square, 0))
+		list.Add(NewMove(square+7, square, 0))
 	}
 	for leftEnPassantBoard != 0 {
 		square := leftEnPassantBoard.PopLSB()
-		moves = append(moves, NewMove(square+9, square, EN_PASSANT_FLAG))
+		list.Add(NewMove(square+9, square, EN_PASSANT_FLAG))
 	}
 	for rightEnPassantBoard != 0 {
 		square := rightEnPassantBoard.PopLSB()
-		moves = append(moves, NewMove(square+7, square, EN_PASSANT_FLAG))
+		list.Add(NewMove(square+7, square, EN_PASSANT_FLAG))
 	}
 	for promotionBoard != 0 {
 		square := promotionBoard.PopLSB()
-		moves = append(moves, NewMove(square+8, square, PROMOTE_QUEEN_FLAG))
-		moves = append(moves, NewMove(square+8, square, PROMOTE_ROOK_FLAG))
-		moves = append(moves, NewMove(square+8, square, PROMOTE_BISHOP_FLAG))
-		moves = append(moves, NewMove(square+8, square, PROMOTE_KNIGHT_FLAG))
+		list.Add(NewMove(square+8, square, PROMOTE_QUEEN_FLAG))
+		list.Add(NewMove(square+8, square, PROMOTE_ROOK_FLAG))
+		list.Add(NewMove(square+8, square, PROMOTE_BISHOP_FLAG))
+		list.Add(NewMove(square+8, square, PROMOTE_KNIGHT_FLAG))
 	}
 	for promotionLeftCaptureBoard != 0 {
 		square := promotionLeftCaptureBoard.PopLSB()
-		moves = append(moves, NewMove(square+9, square, PROMOTE_QUEEN_FLAG))
-		moves = append(moves, NewMove(square+9, square, PROMOTE_ROOK_FLAG))
-		moves = append(moves, NewMove(square+9, square, PROMOTE_BISHOP_FLAG))
-		moves = append(moves, NewMove(square+9, square, PROMOTE_KNIGHT_FLAG))
+		list.Add(NewMove(square+9, square, PROMOTE_QUEEN_FLAG))
+		list.Add(NewMove(square+9, square, PROMOTE_ROOK_FLAG))
+		list.Add(NewMove(square+9, square, PROMOTE_BISHOP_FLAG))
+		list.Add(NewMove(square+9, square, PROMOTE_KNIGHT_FLAG))
 	}
 	for promotionRightCaptureBoard != 0 {
 		square := promotionRightCaptureBoard.PopLSB()
-		moves = append(moves, NewMove(square+7, square, PROMOTE_QUEEN_FLAG))
-		moves = append(moves, NewMove(square+7, square, PROMOTE_ROOK_FLAG))
-		moves = append(moves, NewMove(square+7, square, PROMOTE_BISHOP_FLAG))
-		moves = append(moves, NewMove(square+7, square, PROMOTE_KNIGHT_FLAG))
+		list.Add(NewMove(square+7, square, PROMOTE_QUEEN_FLAG))
+		list.Add(NewMove(square+7, square, PROMOTE_ROOK_FLAG))
+		list.Add(NewMove(square+7, square, PROMOTE_BISHOP_FLAG))
+		list.Add(NewMove(square+7, square, PROMOTE_KNIGHT_FLAG))
 	}
-
-	return b.FilterLegalMoves(moves)
 }
 
-// gets all knight moves for the current position
-func (b *Board) GenerateKnightMoves() []Move {
+// pseudo-legal knight moves
+func (b *Board) addKnightMoves(list *MoveList, gen genType) {
 	var colorToMove byte
 	if b.WhiteToMove {
 		colorToMove = WHITE
 	} else {
 		colorToMove = BLACK
 	}
-	knigthtBitboard := *b.Bitboards[colorToMove|KNIGHT]
-	moves := make([]Move, 0)
+	knigthtBitboard := b.Pieces[colorIndex(colorToMove)][KNIGHT]
+	targets := b.genTargets(gen)
 	for knigthtBitboard != 0 {
 		fromSquare := knigthtBitboard.PopLSB()
-		knightMoves := KnightMasks[fromSquare] & ^*b.Bitboards[colorToMove]
+		knightMoves := KnightMasks[fromSquare] & ^b.Colors[colorIndex(colorToMove)] & targets
 		for knightMoves != 0 {
 			toSquare := knightMoves.PopLSB()
-			moves = append(moves, NewMove(fromSquare, toSquare, 0))
+			list.Add(NewMove(fromSquare, toSquare, 0))
 		}
 	}
-	return b.FilterLegalMoves(moves)
 }
 
 // get all orthogonal moves for a piece at the given index
 func (b *Board) GenerateRookMovesAtPos(pos int) []Move {
+	var list MoveList
+	b.addRookMovesAtPos(&list, pos, ^Bitboard(0))
+	return list.ToSlice()
+}
+
+// adds the orthogonal moves for a piece at the given index to the list, only to the target squares
+func (b *Board) addRookMovesAtPos(list *MoveList, pos int, targets Bitboard) {
 	var colorToMove byte
 	if b.WhiteToMove {
 		colorToMove = WHITE
@@ -250,23 +349,28 @@ func (b *Board) GenerateRookMovesAtPos(pos int) []Move {
 		colorToMove = BLACK
 	}
 	// get the blockers for the rook mask at the given position
-	allPieces := *b.Bitboards[WHITE] | *b.Bitboards[BLACK]
+	allPieces := b.Occupancy()
 	occ := allPieces & RookMasks[pos]
 	occ *= RookMagics[pos]
 	occ >>= (64 - RookShifts[pos])
 	// get the legal moves for the rook at the given position
-	legalMoves := RookAttacks[pos][occ] & ^*b.Bitboards[colorToMove]
-	moves := make([]Move, 0)
+	legalMoves := RookAttacks[pos][occ] & ^b.Colors[colorIndex(colorToMove)] & targets
 	for legalMoves != 0 {
 		toSquare := legalMoves.PopLSB()
-		// add the move to the moves slice
-		moves = append(moves, NewMove(pos, toSquare, 0))
+		// add the move to the list
+		list.Add(NewMove(pos, toSquare, 0))
 	}
-	return moves
 }
 
 // gets all diagonal moves for a piece at the given index
 func (b *Board) GenerateBishopMovesAtPos(pos int) []Move {
+	var list MoveList
+	b.addBishopMovesAtPos(&list, pos, ^Bitboard(0))
+	return list.ToSlice()
+}
+
+// adds the diagonal moves for a piece at the given index to the list, only to the target squares
+func (b *Board) addBishopMovesAtPos(list *MoveList, pos int, targets Bitboard) {
 	var colorToMove byte
 	if b.WhiteToMove {
 		colorToMove = WHITE
@@ -274,23 +378,21 @@ func (b *Board) GenerateBishopMovesAtPos(pos int) []Move {
 		colorToMove = BLACK
 	}
 	// get the blockers for the rook mask at the given position
-	allPieces := *b.Bitboards[WHITE] | *b.Bitboards[BLACK]
+	allPieces := b.Occupancy()
 	blockers := allPieces & BishopMasks[pos]
 	// use magic to get the index
 	index := (blockers * BishopMagics[pos]) >> (64 - BishopShifts[pos])
 	// get the legal moves for the rook at the given position
-	legalMoves := BishopAttacks[pos][index] & ^*b.Bitboards[colorToMove]
-	moves := make([]Move, 0)
+	legalMoves := BishopAttacks[pos][index] & ^b.Colors[colorIndex(colorToMove)] & targets
 	for legalMoves != 0 {
 		toSquare := legalMoves.PopLSB()
-		// add the move to the moves slice
-		moves = append(moves, NewMove(pos, toSquare, 0))
+		// add the move to the list
+		list.Add(NewMove(pos, toSquare, 0))
 	}
-	return moves
 }
 
-// gets all bishop moves for the current position
-func (b *Board) GenerateBishopMoves() []Move {
+// pseudo-legal bishop moves
+func (b *Board) addBishopMoves(list *MoveList, gen genType) {
 	// get all diagonal moves for each bishop
 	var colorToMove byte
 	if b.WhiteToMove {
@@ -298,21 +400,19 @@ func (b *Board) GenerateBishopMoves() []Move {
 	} else {
 		colorToMove = BLACK
 	}
-	bishopBitboard := *b.Bitboards[colorToMove|BISHOP]
+	bishopBitboard := b.Pieces[colorIndex(colorToMove)][BISHOP]
 	if bishopBitboard == 0 {
-		return []Move{}
+		return
 	}
-	moves := make([]Move, 0)
+	targets := b.genTargets(gen)
 	for bishopBitboard != 0 {
 		fromSquare := bishopBitboard.PopLSB()
-		bishopMoves := b.GenerateBishopMovesAtPos(fromSquare)
-		moves = append(moves, bishopMoves...)
+		b.addBishopMovesAtPos(list, fromSquare, targets)
 	}
-	return b.FilterLegalMoves(moves)
 }
 
-// gets all rook moves for the current position
-func (b *Board) GenerateRookMoves() []Move {
+// pseudo-legal rook moves
+func (b *Board) addRookMoves(list *MoveList, gen genType) {
 	// get all sliding moves for each rook
 	var colorToMove byte
 	if b.WhiteToMove {
@@ -320,77 +420,96 @@ func (b *Board) GenerateRookMoves() []Move {
 	} else {
 		colorToMove = BLACK
 	}
-	rookBitboard := *b.Bitboards[colorToMove|ROOK]
+	rookBitboard := b.Pieces[colorIndex(colorToMove)][ROOK]
 	if rookBitboard == 0 {
-		return []Move{}
+		return
 	}
-	moves := make([]Move, 0)
+	targets := b.genTargets(gen)
 	for rookBitboard != 0 {
 		fromSquare := rookBitboard.PopLSB()
-		rookMoves := b.GenerateRookMovesAtPos(fromSquare)
-		moves = append(moves, rookMoves...)
+		b.addRookMovesAtPos(list, fromSquare, targets)
 	}
-	return b.FilterLegalMoves(moves)
 }
 
-// gets all queen moves for the current position
-func (b *Board) GenerateQueenMoves() []Move {
+// pseudo-legal queen moves
+func (b *Board) addQueenMoves(list *MoveList, gen genType) {
 	var queenBitboard Bitboard
 	if b.WhiteToMove {
-		queenBitboard = *b.Bitboards[WHITE|QUEEN]
+		queenBitboard = b.Pieces[WHITE_INDEX][QUEEN]
 	} else {
-		queenBitboard = *b.Bitboards[BLACK|QUEEN]
+		queenBitboard = b.Pieces[BLACK_INDEX][QUEEN]
 	}
 	if queenBitboard == 0 {
-		return []Move{}
+		return
+	}
+	targets := b.genTargets(gen)
+	// there can be more than one queen after a promotion
+	for queenBitboard != 0 {
+		queenPos := queenBitboard.PopLSB()
+		// queen moves are just the combination of bishop and rook moves
+		b.addRookMovesAtPos(list, queenPos, targets)
+		b.addBishopMovesAtPos(list, queenPos, targets)
 	}
-	queenPos := queenBitboard.PopLSB()
-	// queen moves are just the combination of bishop and rook moves
-	return b.FilterLegalMoves(append(b.GenerateRookMovesAtPos(queenPos), b.GenerateBishopMovesAtPos(queenPos)...))
 }
 
-// gets all king moves for the current position
-func (b *Board) GenerateKingMoves() []Move {
+// pseudo-legal king moves, including castling
+func (b *Board) addKingMoves(list *MoveList, gen genType) {
 	var colorToMove byte
 	if b.WhiteToMove {
 		colorToMove = WHITE
 	} else {
 		colorToMove = BLACK
 	}
-	kingBitboard := *b.Bitboards[colorToMove|KING]
+	kingBitboard := b.Pieces[colorIndex(colorToMove)][KING]
 	kingPos := kingBitboard.PopLSB()
-	kingMoves := KingMasks[kingPos] & ^*b.Bitboards[colorToMove]
-	moves := make([]Move, 0)
+	kingMoves := KingMasks[kingPos] & ^b.Colors[colorIndex(colorToMove)] & b.genTargets(gen)
 	for kingMoves != 0 {
 		toSquare := kingMoves.PopLSB()
-		moves = append(moves, NewMove(kingPos, toSquare, 0))
+		list.Add(NewMove(kingPos, toSquare, 0))
 	}
 	// castling
-	if b.WhiteToMove {
-		// white king side castle
-		K := strings.Contains(b.WhiteCastleRights, "K")
-		if K && !b.Bitboards[WHITE].Occupied(5) && !b.Bitboards[WHITE].Occupied(6) {
-			moves = append(moves, NewMove(4, 6, CASTLE_FLAG))
-		}
-		// white queen side castle
-		Q := strings.Contains(b.WhiteCastleRights, "Q")
-		if Q && !b.Bitboards[WHITE].Occupied(1) && !b.Bitboards[WHITE].Occupied(2) && !b.Bitboards[WHITE].Occupied(3) {
-			moves = append(moves, NewMove(4, 2, CASTLE_FLAG))
-		}
-	} else {
-		// black king side castle
-		k := strings.Contains(b.BlackCastleRights, "k")
-		if k && !b.Bitboards[BLACK].Occupied(61) && !b.Bitboards[BLACK].Occupied(62) {
-			moves = append(moves, NewMove(60, 62, CASTLE_FLAG))
+	if gen == genCaptures {
+		return
+	}
+	rights, kingRight, queenRight := b.WhiteCastleRights, byte('K'), byte('Q')
+	if !b.WhiteToMove {
+		rights, kingRight, queenRight = b.BlackCastleRights, 'k', 'q'
+	}
+	if strings.IndexByte(rights, kingRight) >= 0 && b.canCastle(colorToMove, kingPos, kingSide) {
+		list.Add(b.castleMove(kingPos, kingSide))
+	}
+	if strings.IndexByte(rights, queenRight) >= 0 && b.canCastle(colorToMove, kingPos, queenSide) {
+		list.Add(b.castleMove(kingPos, queenSide))
+	}
+}
+
+// checks the rules for castling, other than having the right to:
+// the king and rook are in place, the squares they pass over and land on are empty apart from each other,
+// and the king is not in check and doesn't pass through or land on an attacked square
+func (b *Board) canCastle(color byte, kingSquare, side int) bool {
+	if !b.castlingPiecesInPlace(color, kingSquare, side) {
+		return false
+	}
+	rookSquare := b.castlingRook(colorIndex(color), side)
+	kingTarget, rookTarget := castlingTargets(rookSquare&^7, side)
+	for _, path := range [2][2]int{{kingSquare, kingTarget}, {rookSquare, rookTarget}} {
+		for square := min(path[0], path[1]); square <= max(path[0], path[1]); square++ {
+			if square != kingSquare && square != rookSquare && !b.GetPieceAtIndex(square).IsNone() {
+				return false
+			}
 		}
-		// black queen side castle
-		q := strings.Contains(b.BlackCastleRights, "q")
-		if q && !b.Bitboards[BLACK].Occupied(57) && !b.Bitboards[BLACK].Occupied(58) && !b.Bitboards[BLACK].Occupied(59) {
-			moves = append(moves, NewMove(60, 58, CASTLE_FLAG))
+	}
+	// the king can't castle out of, through or into check
+	enemy := WHITE
+	if color == WHITE {
+		enemy = BLACK
+	}
+	for square := min(kingSquare, kingTarget); square <= max(kingSquare, kingTarget); square++ {
+		if b.IsSquareAttacked(square, enemy) {
+			return false
 		}
 	}
-
-	return b.FilterLegalMoves(moves)
+	return true
 }
 
 // IsSquareAttacked checks if a square is attacked by the given color
@@ -401,11 +520,11 @@ func (b *Board) IsSquareAttacked(square int, byColor byte) bool {
 		// So from the target square, we look diagonally downward for white pawns
 		if square >= 16 { // Make sure we don't go below rank 2
 			// Check diagonal down-left (from white pawn's perspective)
-			if square%8 != 0 && b.Bitboards[WHITE|PAWN].Occupied(square-9) {
+			if square%8 != 0 && b.Pieces[WHITE_INDEX][PAWN].Occupied(square-9) {
 				return true
 			}
 			// Check diagonal down-right (from white pawn's perspective)
-			if square%8 != 7 && b.Bitboards[WHITE|PAWN].Occupied(square-7) {
+			if square%8 != 7 && b.Pieces[WHITE_INDEX][PAWN].Occupied(square-7) {
 				return true
 			}
 		}
@@ -414,36 +533,38 @@ func (b *Board) IsSquareAttacked(square int, byColor byte) bool {
 		// So from the target square, we look diagonally upward for black pawns
 		if square < 48 { // Make sure we don't go above rank 7
 			// Check diagonal up-left (from black pawn's perspective)
-			if square%8 != 7 && b.Bitboards[BLACK|PAWN].Occupied(square+7) {
+			if square%8 != 0 && b.Pieces[BLACK_INDEX][PAWN].Occupied(square+7) {
 				return true
 			}
 			// Check diagonal up-right (from black pawn's perspective)
-			if square%8 != 0 && b.Bitboards[BLACK|PAWN].Occupied(square+9) {
+			if square%8 != 7 && b.Pieces[BLACK_INDEX][PAWN].Occupied(square+9) {
 				return true
 			}
 		}
 	}
 
+	attackers := &b.Pieces[colorIndex(byColor)]
+
 	// Check for knight attacks
 	knightMoves := KnightMasks[square]
-	if (knightMoves & *b.Bitboards[byColor|KNIGHT]) != 0 {
+	if (knightMoves & attackers[KNIGHT]) != 0 {
 		return true
 	}
 
 	// Check for king attacks
 	kingMoves := KingMasks[square]
-	if (kingMoves & *b.Bitboards[byColor|KING]) != 0 {
+	if (kingMoves & attackers[KING]) != 0 {
 		return true
 	}
 
 	// Check for sliding piece attacks (rook, bishop, queen)
-	allPieces := *b.Bitboards[WHITE] | *b.Bitboards[BLACK]
+	allPieces := b.Occupancy()
 
 	// Check for rook/queen attacks (orthogonal)
 	rookMask := RookMasks[square]
 	blockers := allPieces & rookMask
 	rookAttacks := RookAttacks[square][(blockers*RookMagics[square])>>(64-RookShifts[square])]
-	if (rookAttacks & (*b.Bitboards[byColor|ROOK] | *b.Bitboards[byColor|QUEEN])) != 0 {
+	if (rookAttacks & (attackers[ROOK] | attackers[QUEEN])) != 0 {
 		return true
 	}
 
@@ -451,13 +572,13 @@ func (b *Board) IsSquareAttacked(square int, byColor byte) bool {
 	bishopMask := BishopMasks[square]
 	blockers = allPieces & bishopMask
 	bishopAttacks := BishopAttacks[square][(blockers*BishopMagics[square])>>(64-BishopShifts[square])]
-	return (bishopAttacks & (*b.Bitboards[byColor|BISHOP] | *b.Bitboards[byColor|QUEEN])) != 0
+	return (bishopAttacks & (attackers[BISHOP] | attackers[QUEEN])) != 0
 }
 
 // IsInCheck returns true if the specified color's king is in check
 func (b *Board) IsInCheck(color byte) bool {
 	// Find the king position
-	kingBitboard := *b.Bitboards[color|KING]
+	kingBitboard := b.Pieces[colorIndex(color)][KING]
 	if kingBitboard == 0 {
 		return false // No king found
 	}
@@ -474,30 +595,14 @@ func (b *Board) IsInCheck(color byte) bool {
 	return b.IsSquareAttacked(kingPos, oppositeColor)
 }
 
+// FilterLegalMoves keeps the moves that don't leave the mover's king in check by making and unmaking each one.
+// Move generation uses the faster check and pin masks instead, this is the slow reference.
 func (b *Board) FilterLegalMoves(moves []Move) []Move {
 	legalMoves := make([]Move, 0)
-
-	// Determine which color is moving
-	var movingColor byte
-	if b.WhiteToMove {
-		movingColor = WHITE
-	} else {
-		movingColor = BLACK
-	}
-
 	for _, move := range moves {
-		// Make the move temporarily
-		state := b.MakeMove(move)
-
-		// Check if this move leaves our own king in check
-		// After making the move, it's the opponent's turn, so we check if our king is still safe
-		if !b.IsInCheck(movingColor) {
+		if b.leavesKingSafe(move) {
 			legalMoves = append(legalMoves, move)
 		}
-
-		// Unmake the move to restore the board state
-		b.UnmakeMove(move, state)
 	}
-
 	return legalMoves
 }
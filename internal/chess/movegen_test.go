@@ -385,8 +385,25 @@ func TestDiscoveredCheck(t *testing.T) {
 }
 
 func TestPinnedPieces(t *testing.T) {
-	t.Skip("Pinned pieces test not implemented yet")
-	// TODO: Implement a test for pinned pieces
+	board := NewBoard()
+
+	// knight pinned by the rook, f2 pawn pinned by the h4 bishop, the c3 and d2 pawns shield each other from the a5 bishop
+	board.LoadFEN("4k3/4r3/8/b7/7b/2P5/3PNP2/4K3 w - - 0 1")
+	expected := Bitboard(1)<<StringToSquare("e2") | Bitboard(1)<<StringToSquare("f2")
+	if pinned := board.Pinned(WHITE); pinned != expected {
+		t.Errorf("Expected e2 and f2 to be pinned, got %x", pinned)
+	}
+	if pinned := board.Pinned(BLACK); pinned != 0 {
+		t.Errorf("Expected no black pins, got %x", pinned)
+	}
+
+	// a pinned knight can't move at all
+	board.GenerateLegalMoves()
+	for _, move := range board.LegalMoves {
+		if move.Source() == StringToSquare("e2") {
+			t.Errorf("Pinned knight moved %s", move.String())
+		}
+	}
 }
 
 func TestDoubleCheck(t *testing.T) {
@@ -448,3 +465,255 @@ func TestStalemate(t *testing.T) {
 		t.Errorf("Should be stalemate (no legal moves), but found %d moves", len(board.LegalMoves))
 	}
 }
+
+// counts the castling moves in a list of moves
+func countCastles(moves []Move) int {
+	count := 0
+	for _, move := range moves {
+		if move.Flag() == CASTLE_FLAG {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCastlingLegality(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fen      string
+		expected int
+	}{
+		{"both sides free", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", 2},
+		{"out of check", "r3k2r/8/8/8/8/8/4r3/R3K2R w KQkq - 0 1", 0},
+		{"through an attacked square", "r3k2r/8/8/8/8/8/5r2/R3K2R w KQkq - 0 1", 1},
+		{"into an attacked square", "r3k2r/8/8/8/8/8/6r1/R3K2R w KQkq - 0 1", 1},
+		{"queen side b1 may be attacked", "r3k2r/8/8/8/8/8/1r6/R3K2R w KQkq - 0 1", 2},
+		{"over an enemy piece", "r3k2r/8/8/8/8/8/8/R2nK1bR w KQkq - 0 1", 0},
+		{"over a friendly piece", "r3k2r/8/8/8/8/8/8/RN2K1NR w KQkq - 0 1", 0},
+		{"rook missing", "r3k2r/8/8/8/8/8/8/4K2R w KQkq - 0 1", 1},
+		{"black out of check", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", 2},
+		{"black through an attacked square", "r3k2r/8/8/8/8/8/8/R2RK2R b KQkq - 0 1", 1},
+		{"black in check", "r3k2r/8/8/8/B7/8/8/R3K2R b KQkq - 0 1", 0},
+	}
+
+	for _, tc := range testCases {
+		board := NewBoard()
+		board.LoadFEN(tc.fen)
+		castles := countCastles(board.GenerateKingMoves())
+		if castles != tc.expected {
+			t.Errorf("%s: expected %d castling moves, got %d", tc.name, tc.expected, castles)
+		}
+	}
+}
+
+// walks the move tree checking the mask based filter against making and unmaking every move
+func checkLegalMovesMatch(t *testing.T, b *Board, depth int) {
+	t.Helper()
+	var pseudo MoveList
+	b.addPawnMoves(&pseudo, genAll)
+	b.addKnightMoves(&pseudo, genAll)
+	b.addBishopMoves(&pseudo, genAll)
+	b.addRookMoves(&pseudo, genAll)
+	b.addQueenMoves(&pseudo, genAll)
+	b.addKingMoves(&pseudo, genAll)
+	expected := b.FilterLegalMoves(pseudo.Slice())
+
+	b.GenerateLegalMoves()
+	moves := b.LegalMoves
+	if len(moves) != len(expected) {
+		t.Fatalf("%s: expected %d legal moves, got %d", b.ExportFEN(), len(expected), len(moves))
+	}
+	for i := range moves {
+		if moves[i] != expected[i] {
+			t.Fatalf("%s: move %d is %s, expected %s", b.ExportFEN(), i, moves[i].String(), expected[i].String())
+		}
+	}
+	if depth <= 1 {
+		return
+	}
+	for _, move := range moves {
+		state := b.MakeMove(move)
+		checkLegalMovesMatch(t, b, depth-1)
+		b.UnmakeMove(move, state)
+	}
+}
+
+func TestLegalMovesMatchMakeUnmake(t *testing.T) {
+	fens := []string{
+		// pinned pieces, checks along every line and en passant that exposes the king
+		"8/8/8/KPp4r/8/8/8/7k w - c6 0 1",
+		"4k3/8/8/8/1b6/8/3P4/4K3 w - - 0 1",
+		"4k3/4r3/8/8/8/8/4B3/4K3 w - - 0 1",
+		"4k3/8/8/8/8/5n2/8/r3K2R w K - 0 1",
+	}
+	for _, position := range PerftSuite {
+		fens = append(fens, position.FEN)
+	}
+	for _, fen := range fens {
+		board := NewBoard()
+		board.LoadFEN(fen)
+		checkLegalMovesMatch(t, board, 3)
+	}
+}
+
+// the directions each piece moves in as file and rank steps, sliders keep going until they hit something
+var referenceSteps = map[byte][][2]int{
+	KNIGHT: {{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}},
+	BISHOP: {{1, 1}, {1, -1}, {-1, -1}, {-1, 1}},
+	ROOK:   {{1, 0}, {-1, 0}, {0, 1}, {0, -1}},
+	QUEEN:  {{1, 1}, {1, -1}, {-1, -1}, {-1, 1}, {1, 0}, {-1, 0}, {0, 1}, {0, -1}},
+}
+
+// the legal moves of every piece of one type found the slow way, one square at a time from each piece
+func referenceMoves(b *Board, pieceType byte) map[Move]bool {
+	us, color := BLACK_INDEX, BLACK
+	if b.WhiteToMove {
+		us, color = WHITE_INDEX, WHITE
+	}
+	var pseudo []Move
+	for square := range 64 {
+		if b.Mailbox[square] != Piece(pieceType|color) {
+			continue
+		}
+		for _, step := range referenceSteps[pieceType] {
+			file, rank := square%8, square/8
+			for {
+				file, rank = file+step[0], rank+step[1]
+				if file < 0 || file > 7 || rank < 0 || rank > 7 || b.Colors[us].Occupied(rank*8+file) {
+					break
+				}
+				pseudo = append(pseudo, NewMove(square, rank*8+file, 0))
+				if pieceType == KNIGHT || !b.Mailbox[rank*8+file].IsNone() {
+					break
+				}
+			}
+		}
+	}
+	moves := make(map[Move]bool)
+	for _, move := range b.FilterLegalMoves(pseudo) {
+		moves[move] = true
+	}
+	return moves
+}
+
+// checks a piece type's generator finds the same moves as the reference, in any order
+func checkPieceMoves(t *testing.T, b *Board, pieceType byte) {
+	t.Helper()
+	generators := map[byte]func() []Move{
+		KNIGHT: b.GenerateKnightMoves,
+		BISHOP: b.GenerateBishopMoves,
+		ROOK:   b.GenerateRookMoves,
+		QUEEN:  b.GenerateQueenMoves,
+	}
+	expected := referenceMoves(b, pieceType)
+	moves := generators[pieceType]()
+	found := make(map[Move]bool)
+	for _, move := range moves {
+		if !expected[move] {
+			t.Errorf("%s: unexpected move %s", b.ExportFEN(), move.String())
+		}
+		found[move] = true
+	}
+	for move := range expected {
+		if !found[move] {
+			t.Errorf("%s: missing move %s", b.ExportFEN(), move.String())
+		}
+	}
+	if len(moves) != len(found) {
+		t.Errorf("%s: %d moves generated with duplicates, %d different", b.ExportFEN(), len(moves), len(found))
+	}
+}
+
+// every way to put two pieces of the same type on the board, for both colors, with a few blockers and an enemy to capture
+func TestGenerateMultiplePieces(t *testing.T) {
+	blockers := []struct {
+		square int
+		piece  Piece
+	}{
+		{StringToSquare("c3"), Piece(PAWN | WHITE)},
+		{StringToSquare("f6"), Piece(PAWN | BLACK)},
+		{StringToSquare("e4"), Piece(KNIGHT | BLACK)},
+		{StringToSquare("d5"), Piece(BISHOP | WHITE)},
+	}
+	for _, pieceType := range []byte{KNIGHT, BISHOP, ROOK, QUEEN} {
+		for _, color := range []byte{WHITE, BLACK} {
+			for first := range 64 {
+				for second := first + 1; second < 64; second++ {
+					board := NewBoard()
+					board.EnPassantSquare = -1
+					board.WhiteToMove = color == WHITE
+					for _, blocker := range blockers {
+						board.SetPieceAtIndex(blocker.piece, blocker.square)
+					}
+					if !board.Mailbox[first].IsNone() || !board.Mailbox[second].IsNone() {
+						continue
+					}
+					board.SetPieceAtIndex(Piece(pieceType|color), first)
+					board.SetPieceAtIndex(Piece(pieceType|color), second)
+					board.UpdateAttacks()
+					checkPieceMoves(t, board, pieceType)
+					if t.Failed() {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateThreeQueens(t *testing.T) {
+	board := NewBoard()
+	board.LoadFEN("4k3/8/8/3Q4/8/8/8/Q3K2Q w - - 0 1")
+	// the queens block each other: d5 has 26 moves, a1 has 17 and h1 has 12
+	queenMoves := board.GenerateQueenMoves()
+	if len(queenMoves) != 55 {
+		t.Errorf("Expected 55 queen moves, got %d", len(queenMoves))
+	}
+	checkPieceMoves(t, board, QUEEN)
+}
+
+// promotes four pawns one after another to each piece, the promoted pieces have to move like the real ones
+func TestPromotedPieceMoves(t *testing.T) {
+	promotions := []struct {
+		flag  int
+		piece byte
+	}{
+		{PROMOTE_QUEEN_FLAG, QUEEN},
+		{PROMOTE_ROOK_FLAG, ROOK},
+		{PROMOTE_BISHOP_FLAG, BISHOP},
+		{PROMOTE_KNIGHT_FLAG, KNIGHT},
+	}
+	starts := []struct {
+		fen      string
+		from, to int
+	}{
+		// white promotes on a8-d8, black promotes on e1-h1
+		{"8/PPPP4/8/8/8/7k/8/K7 w - - 0 1", StringToSquare("a7"), StringToSquare("a8")},
+		{"7k/8/K7/8/8/8/4pppp/8 b - - 0 1", StringToSquare("e2"), StringToSquare("e1")},
+	}
+	for _, start := range starts {
+		for _, promotion := range promotions {
+			board := NewBoard()
+			board.LoadFEN(start.fen)
+			for file := range 4 {
+				board.MakeMove(NewMove(start.from+file, start.to+file, promotion.flag))
+				// the other side just moves its king
+				board.GenerateLegalMoves()
+				if len(board.LegalMoves) == 0 {
+					t.Fatalf("%s: no reply to the promotion", board.ExportFEN())
+				}
+				board.MakeMove(board.LegalMoves[0])
+
+				us := WHITE_INDEX
+				if !board.WhiteToMove {
+					us = BLACK_INDEX
+				}
+				if count := board.Pieces[us][promotion.piece].Count(); count != file+1 {
+					t.Fatalf("%s: expected %d promoted pieces, got %d", board.ExportFEN(), file+1, count)
+				}
+				checkPieceMoves(t, board, promotion.piece)
+				checkLegalMovesMatch(t, board, 2)
+			}
+		}
+	}
+}
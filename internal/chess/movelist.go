@@ -0,0 +1,59 @@
+package chess
+
+/*
+	A MoveList is a fixed size array of moves with a count.
+	No legal chess position has more than 218 moves, so 256 is always enough.
+	Declared as a local variable it lives on the stack, which means move generation
+	can run millions of times in a search without creating any garbage for the GC.
+*/
+
+// MAX_MOVES is the capacity of a MoveList
+const MAX_MOVES = 256
+
+type MoveList struct {
+	Moves [MAX_MOVES]Move
+	Count int
+}
+
+// adds a move to the end of the list
+func (l *MoveList) Add(move Move) {
+	l.Moves[l.Count] = move
+	l.Count++
+}
+
+// removes every move from the list
+func (l *MoveList) Clear() {
+	l.Count = 0
+}
+
+// get the number of moves in the list
+func (l *MoveList) Len() int {
+	return l.Count
+}
+
+// get the move at index i
+func (l *MoveList) Get(i int) Move {
+	return l.Moves[i]
+}
+
+// checks if the list has the given move
+func (l *MoveList) Contains(move Move) bool {
+	for i := 0; i < l.Count; i++ {
+		if l.Moves[i] == move {
+			return true
+		}
+	}
+	return false
+}
+
+// get the moves as a slice that shares memory with the list, only valid until the list changes
+func (l *MoveList) Slice() []Move {
+	return l.Moves[:l.Count]
+}
+
+// copies the moves into a new slice
+func (l *MoveList) ToSlice() []Move {
+	moves := make([]Move, l.Count)
+	copy(moves, l.Moves[:l.Count])
+	return moves
+}
@@ -0,0 +1,68 @@
+package chess
+
+import "testing"
+
+func TestMoveList(t *testing.T) {
+	var list MoveList
+	e2e4 := NewMove(StringToSquare("e2"), StringToSquare("e4"), PAWN_DOUBLE_FLAG)
+	g1f3 := NewMove(StringToSquare("g1"), StringToSquare("f3"), NO_FLAG)
+	list.Add(e2e4)
+	list.Add(g1f3)
+	if list.Len() != 2 {
+		t.Fatalf("Expected 2 moves, got %d", list.Len())
+	}
+	if list.Get(1) != g1f3 {
+		t.Errorf("Expected g1f3 at index 1, got %s", list.Moves[1].String())
+	}
+	if !list.Contains(e2e4) || list.Contains(NewMove(0, 1, NO_FLAG)) {
+		t.Error("Contains returned the wrong answer")
+	}
+	moves := list.ToSlice()
+	list.Clear()
+	if list.Len() != 0 || len(list.Slice()) != 0 {
+		t.Error("Expected an empty list after Clear")
+	}
+	// ToSlice makes a copy that survives the list changing
+	if len(moves) != 2 || moves[0] != e2e4 {
+		t.Errorf("Expected the copied moves to be unchanged, got %v", moves)
+	}
+}
+
+func TestGenerateLegalMovesInto(t *testing.T) {
+	for _, position := range PerftSuite {
+		board := NewBoard()
+		board.LoadFEN(position.FEN)
+		var list MoveList
+		// whatever was in the list before is replaced
+		list.Add(NewMove(0, 1, NO_FLAG))
+		board.GenerateLegalMovesInto(&list)
+		board.GenerateLegalMoves()
+		if list.Len() != len(board.LegalMoves) {
+			t.Fatalf("%s: expected %d moves, got %d", position.Name, len(board.LegalMoves), list.Len())
+		}
+		for i, move := range board.LegalMoves {
+			if list.Get(i) != move {
+				t.Errorf("%s: move %d is %s, expected %s", position.Name, i, list.Moves[i].String(), move.String())
+			}
+		}
+	}
+}
+
+func TestMoveGenerationDoesNotAllocate(t *testing.T) {
+	board := NewBoard()
+	board.LoadFEN(PerftSuite[1].FEN)
+	var list MoveList
+	allocs := testing.AllocsPerRun(100, func() {
+		board.GenerateLegalMovesInto(&list)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected GenerateLegalMovesInto not to allocate, got %v allocations", allocs)
+	}
+	// perft makes and unmakes moves too, including castling and promotions
+	allocs = testing.AllocsPerRun(5, func() {
+		board.Perft(3)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected Perft not to allocate, got %v allocations", allocs)
+	}
+}
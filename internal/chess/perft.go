@@ -0,0 +1,128 @@
+package chess
+
+import (
+	"sync"
+)
+
+/*
+	Perft (performance test) walks the tree of legal moves and counts the leaf nodes at a given depth.
+	https://www.chessprogramming.org/Perft
+	The counts for a handful of tricky positions are well known, so comparing against them is
+	the best way to find bugs in move generation. Divide splits the count up by root move,
+	which makes it easy to narrow a mismatch down against another engine.
+*/
+
+// PerftPosition is a reference position with its published node counts
+type PerftPosition struct {
+	Name string
+	FEN  string
+	// Nodes[i] is the node count at depth i+1
+	Nodes []uint64
+}
+
+// PerftSuite holds the reference positions from https://www.chessprogramming.org/Perft_Results
+var PerftSuite = []PerftPosition{
+	{
+		Name:  "start position",
+		FEN:   START_FEN,
+		Nodes: []uint64{20, 400, 8902, 197281, 4865609, 119060324},
+	},
+	{
+		Name:  "kiwipete",
+		FEN:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		Nodes: []uint64{48, 2039, 97862, 4085603, 193690690},
+	},
+	{
+		Name:  "position 3",
+		FEN:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		Nodes: []uint64{14, 191, 2812, 43238, 674624, 11030083},
+	},
+	{
+		Name:  "position 4",
+		FEN:   "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		Nodes: []uint64{6, 264, 9467, 422333, 15833292},
+	},
+	{
+		Name:  "position 5",
+		FEN:   "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		Nodes: []uint64{44, 1486, 62379, 2103487, 89941194},
+	},
+	{
+		Name:  "position 6",
+		FEN:   "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		Nodes: []uint64{46, 2079, 89890, 3894594, 164075551},
+	},
+}
+
+// Perft counts the leaf nodes of the legal move tree at the given depth
+func (b *Board) Perft(depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+	var moves MoveList
+	b.GenerateLegalMovesInto(&moves)
+	// no need to make the moves at the last level, we only count them
+	if depth == 1 {
+		return uint64(moves.Count)
+	}
+	var nodes uint64
+	for _, move := range moves.Slice() {
+		state := b.MakeMove(move)
+		nodes += b.Perft(depth - 1)
+		b.UnmakeMove(move, state)
+	}
+	return nodes
+}
+
+// Divide returns the perft count below each legal move at the given depth
+func (b *Board) Divide(depth int) map[Move]uint64 {
+	counts := make(map[Move]uint64)
+	if depth <= 0 {
+		return counts
+	}
+	var moves MoveList
+	b.GenerateLegalMovesInto(&moves)
+	for _, move := range moves.Slice() {
+		state := b.MakeMove(move)
+		counts[move] = b.Perft(depth - 1)
+		b.UnmakeMove(move, state)
+	}
+	return counts
+}
+
+// PerftParallel is Perft with the root moves split up between the given number of goroutines
+func (b *Board) PerftParallel(depth, workers int) uint64 {
+	if depth <= 1 || workers <= 1 {
+		return b.Perft(depth)
+	}
+	var rootMoves MoveList
+	b.GenerateLegalMovesInto(&rootMoves)
+	moves := make(chan Move, rootMoves.Count)
+	for _, move := range rootMoves.Slice() {
+		moves <- move
+	}
+	close(moves)
+
+	var total uint64
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for range workers {
+		// every goroutine gets its own copy of the board
+		board := *b
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var nodes uint64
+			for move := range moves {
+				state := board.MakeMove(move)
+				nodes += board.Perft(depth - 1)
+				board.UnmakeMove(move, state)
+			}
+			mutex.Lock()
+			total += nodes
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+	return total
+}
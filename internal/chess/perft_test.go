@@ -0,0 +1,51 @@
+package chess
+
+import "testing"
+
+// every reference position to the same depth, depth 5 takes seconds per position and is left to cmd/perft
+const perftTestDepth = 4
+
+func TestPerftSuite(t *testing.T) {
+	for _, position := range PerftSuite {
+		board := NewBoard()
+		board.LoadFEN(position.FEN)
+		for depth := 1; depth <= perftTestDepth; depth++ {
+			nodes := board.Perft(depth)
+			if nodes != position.Nodes[depth-1] {
+				t.Errorf("%s depth %d: expected %d nodes, got %d", position.Name, depth, position.Nodes[depth-1], nodes)
+			}
+		}
+		// perft has to leave the board the way it found it
+		if board.ExportFEN() != position.FEN {
+			t.Errorf("%s: board changed to %s after perft", position.Name, board.ExportFEN())
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	board := NewBoard()
+	board.LoadFEN(START_FEN)
+	counts := board.Divide(2)
+	if len(counts) != 20 {
+		t.Fatalf("Expected 20 root moves, got %d", len(counts))
+	}
+	var total uint64
+	for move, nodes := range counts {
+		// black always has 20 replies to white's first move
+		if nodes != 20 {
+			t.Errorf("Expected 20 nodes after %s, got %d", move.String(), nodes)
+		}
+		total += nodes
+	}
+	if total != board.Perft(2) {
+		t.Errorf("Divide total %d doesn't match Perft %d", total, board.Perft(2))
+	}
+}
+
+func TestPerftParallel(t *testing.T) {
+	board := NewBoard()
+	board.LoadFEN(PerftSuite[1].FEN)
+	if nodes := board.PerftParallel(3, 4); nodes != 97862 {
+		t.Errorf("Expected 97862 nodes, got %d", nodes)
+	}
+}
@@ -0,0 +1,728 @@
+package chess
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	PGN (Portable Game Notation) is the standard format for chess games.
+	https://www.chessprogramming.org/Portable_Game_Notation
+	http://www.saremo.org/pgn/pgn_standard.txt
+	A game is a tag section of [Name "value"] pairs followed by movetext: SAN moves with move numbers,
+	{brace} and ;line comments, (recursive variations), numeric annotation glyphs like $1 and a result.
+	Games are read one at a time from a buffered stream, so files with millions of games never have to fit in memory.
+	Every move is played on a Board as it's read, so a game that comes back from the reader is legal.
+	The moves form a tree: each node's first child continues the line and the others are variations.
+*/
+
+// PGN_RESULTS are the game termination markers that end the movetext
+var PGN_RESULTS = []string{"1-0", "0-1", "1/2-1/2", "*"}
+
+// the seven tag roster, every PGN game has these tags in this order
+var pgnSevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// move suffix annotations and the NAGs they stand for
+var pgnSuffixNAGs = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+var ErrPGNSyntax = errors.New("PGN syntax error")
+
+// PGNError is an error in a PGN file, with where it happened
+type PGNError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *PGNError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *PGNError) Unwrap() error {
+	return e.Err
+}
+
+// PGNTag is a tag pair from the tag section
+type PGNTag struct {
+	Name  string
+	Value string
+}
+
+// PGNNode is a move in a game's move tree
+type PGNNode struct {
+	// zero for the root, which is the start position
+	Move   Move
+	Parent *PGNNode
+	// the first child continues the line, the rest are variations
+	Children []*PGNNode
+	// numeric annotation glyphs, $1 or ! is 1, $2 or ? is 2 and so on
+	NAGs []int
+	// comments after the move, for the root the comments before the first move
+	Comments []string
+	// comments before the move, only used for the first move of a variation
+	StartingComments []string
+	// commands embedded in the comments like [%clk 0:05:00], taken out of the comment text
+	Commands []PGNCommand
+}
+
+// PGNCommand is an embedded command like [%clk 0:05:00] or [%eval -1.25] in a comment
+type PGNCommand struct {
+	Name  string
+	Value string
+}
+
+// PGNEval is an evaluation from a [%eval] command, from white's point of view.
+// Mate is non-zero for a forced mate in that many moves, negative when black is mating.
+type PGNEval struct {
+	Centipawns int
+	Mate       int
+}
+
+// matches [%name value] commands inside comments
+var pgnCommandPattern = regexp.MustCompile(`\[%(\w+)\s*([^\]]*)\]`)
+
+// adds a comment after the move, moving any commands in it to Commands
+func (n *PGNNode) addComment(comment string) {
+	for _, match := range pgnCommandPattern.FindAllStringSubmatch(comment, -1) {
+		n.SetCommand(match[1], strings.TrimSpace(match[2]))
+	}
+	comment = strings.TrimSpace(pgnCommandPattern.ReplaceAllString(comment, ""))
+	if comment != "" {
+		n.Comments = append(n.Comments, comment)
+	}
+}
+
+// Command returns the value of an embedded command
+func (n *PGNNode) Command(name string) (string, bool) {
+	for _, command := range n.Commands {
+		if command.Name == name {
+			return command.Value, true
+		}
+	}
+	return "", false
+}
+
+// SetCommand changes an embedded command, or adds it
+func (n *PGNNode) SetCommand(name, value string) {
+	for i := range n.Commands {
+		if n.Commands[i].Name == name {
+			n.Commands[i].Value = value
+			return
+		}
+	}
+	n.Commands = append(n.Commands, PGNCommand{Name: name, Value: value})
+}
+
+// Clock returns the time left on the mover's clock from a [%clk h:mm:ss] command
+func (n *PGNNode) Clock() (time.Duration, bool) {
+	value, ok := n.Command("clk")
+	if !ok {
+		return 0, false
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	hours, errH := strconv.Atoi(parts[0])
+	minutes, errM := strconv.Atoi(parts[1])
+	seconds, errS := strconv.ParseFloat(parts[2], 64)
+	if errH != nil || errM != nil || errS != nil {
+		return 0, false
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second)), true
+}
+
+// SetClock sets the [%clk] command, tenths of a second are only written when there are some
+func (n *PGNNode) SetClock(clock time.Duration) {
+	tenths := clock.Round(100*time.Millisecond) / (100 * time.Millisecond)
+	value := fmt.Sprintf("%d:%02d:%02d", tenths/36000, tenths/600%60, tenths/10%60)
+	if tenths%10 != 0 {
+		value += fmt.Sprintf(".%d", tenths%10)
+	}
+	n.SetCommand("clk", value)
+}
+
+// Eval returns the evaluation from an [%eval 0.25] or [%eval #-3] command
+func (n *PGNNode) Eval() (PGNEval, bool) {
+	value, ok := n.Command("eval")
+	if !ok {
+		return PGNEval{}, false
+	}
+	// a depth can follow the score after a comma
+	value, _, _ = strings.Cut(value, ",")
+	if mate, found := strings.CutPrefix(value, "#"); found {
+		moves, err := strconv.Atoi(mate)
+		return PGNEval{Mate: moves}, err == nil && moves != 0
+	}
+	pawns, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return PGNEval{}, false
+	}
+	return PGNEval{Centipawns: int(math.Round(pawns * 100))}, true
+}
+
+// SetEval sets the [%eval] command
+func (n *PGNNode) SetEval(eval PGNEval) {
+	if eval.Mate != 0 {
+		n.SetCommand("eval", fmt.Sprintf("#%d", eval.Mate))
+		return
+	}
+	n.SetCommand("eval", fmt.Sprintf("%.2f", float64(eval.Centipawns)/100))
+}
+
+// AddChild adds a move after this one, as the main line if it's the first
+func (n *PGNNode) AddChild(move Move) *PGNNode {
+	child := &PGNNode{Move: move, Parent: n}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// PGNGame is one game from a PGN file
+type PGNGame struct {
+	// in the order they were read
+	Tags []PGNTag
+	// the position before the first move, the standard start position unless there's a FEN tag
+	Start *Board
+	// the start position, its children are the first moves
+	Root *PGNNode
+	// the termination marker at the end of the movetext: 1-0, 0-1, 1/2-1/2 or *
+	Result string
+}
+
+// NewPGNGame creates a game with the seven tag roster, starting from a FEN or the standard start position if empty
+func NewPGNGame(fen string) (*PGNGame, error) {
+	if fen == "" {
+		fen = START_FEN
+	}
+	start, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	return newPGNGame(start), nil
+}
+
+func newPGNGame(start *Board) *PGNGame {
+	g := &PGNGame{Start: start, Root: &PGNNode{}, Result: "*"}
+	for _, name := range pgnSevenTagRoster {
+		g.SetTag(name, "?")
+	}
+	g.SetTag("Date", "????.??.??")
+	g.SetTag("Result", "*")
+	if fen := start.ExportFEN(); fen != START_FEN {
+		g.SetTag("SetUp", "1")
+		g.SetTag("FEN", fen)
+	}
+	if start.Chess960 {
+		g.SetTag("Variant", "Chess960")
+	}
+	return g
+}
+
+// Tag returns the value of a tag, empty if the game doesn't have it
+func (g *PGNGame) Tag(name string) string {
+	for _, tag := range g.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// SetTag changes a tag, or adds it at the end
+func (g *PGNGame) SetTag(name, value string) {
+	for i := range g.Tags {
+		if g.Tags[i].Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, PGNTag{Name: name, Value: value})
+}
+
+// MainLine returns the moves of the game without the variations
+func (g *PGNGame) MainLine() []Move {
+	var moves []Move
+	for node := g.Root; len(node.Children) > 0; node = node.Children[0] {
+		moves = append(moves, node.Children[0].Move)
+	}
+	return moves
+}
+
+// Game replays the main line into a Game
+func (g *PGNGame) Game() (*Game, error) {
+	newGame := NewGame
+	if g.Start.Chess960 {
+		newGame = NewChess960Game
+	}
+	game, err := newGame(g.Start.ExportFEN())
+	if err != nil {
+		return nil, err
+	}
+	for _, move := range g.MainLine() {
+		if err := game.MakeMove(move); err != nil {
+			return nil, err
+		}
+	}
+	return game, nil
+}
+
+// PGNReader reads games one at a time from a PGN file
+type PGNReader struct {
+	r *bufio.Reader
+	// where the last byte read was, columns count bytes from 1
+	line, column int
+	// the column before the last newline, to go back over it
+	lastColumn int
+	// after an error the rest of the broken game is skipped
+	resync bool
+}
+
+func NewPGNReader(r io.Reader) *PGNReader {
+	return &PGNReader{r: bufio.NewReader(r), line: 1}
+}
+
+// ReadPGN reads every game, stopping at the first bad one
+func ReadPGN(r io.Reader) ([]*PGNGame, error) {
+	reader := NewPGNReader(r)
+	var games []*PGNGame
+	for {
+		game, err := reader.Read()
+		if err == io.EOF {
+			return games, nil
+		}
+		if err != nil {
+			return games, err
+		}
+		games = append(games, game)
+	}
+}
+
+// Read returns the next game, or io.EOF when there are no more.
+// After an error the next Read skips to the start of the following game, so one bad game doesn't end the file.
+func (p *PGNReader) Read() (*PGNGame, error) {
+	if p.resync {
+		p.resync = false
+		if err := p.skipToNextGame(); err != nil {
+			return nil, err
+		}
+	}
+	game, err := p.readGame()
+	if err != nil && err != io.EOF {
+		p.resync = true
+	}
+	return game, err
+}
+
+func (p *PGNReader) readByte() (byte, error) {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if c == '\n' {
+		p.line++
+		p.lastColumn = p.column
+		p.column = 0
+	} else {
+		p.column++
+	}
+	return c, nil
+}
+
+// puts back the last byte read, only one can be put back
+func (p *PGNReader) unreadByte(c byte) {
+	p.r.UnreadByte()
+	if c == '\n' {
+		p.line--
+		p.column = p.lastColumn
+	} else {
+		p.column--
+	}
+}
+
+func (p *PGNReader) errorAt(line, column int, format string, args ...any) error {
+	return &PGNError{Line: line, Column: column, Err: fmt.Errorf("%w: "+format, append([]any{ErrPGNSyntax}, args...)...)}
+}
+
+// skips whitespace and % escape lines, returning the next byte
+func (p *PGNReader) skipSpace() (byte, error) {
+	for {
+		c, err := p.readByte()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case c == '%' && p.column == 1:
+			if _, err := p.readLine(); err != nil {
+				return 0, err
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		default:
+			return c, nil
+		}
+	}
+}
+
+// reads to the end of the line, not including the newline
+func (p *PGNReader) readLine() (string, error) {
+	var sb strings.Builder
+	for {
+		c, err := p.readByte()
+		if err == io.EOF || c == '\n' {
+			return strings.TrimSuffix(sb.String(), "\r"), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(c)
+	}
+}
+
+// skips ahead to a [ at the start of a line after an empty line, where the next game's tags begin
+func (p *PGNReader) skipToNextGame() error {
+	afterEmptyLine, emptyLine := false, false
+	for {
+		c, err := p.readByte()
+		if err != nil {
+			return err
+		}
+		switch {
+		case c == '[' && p.column == 1 && afterEmptyLine:
+			p.unreadByte(c)
+			return nil
+		case c == '\n':
+			afterEmptyLine, emptyLine = emptyLine, true
+		case c == ' ' || c == '\t' || c == '\r':
+		default:
+			emptyLine = false
+		}
+	}
+}
+
+func (p *PGNReader) readGame() (*PGNGame, error) {
+	g := &PGNGame{Root: &PGNNode{}}
+
+	// tag section, ; comments between tags are dropped
+	fenLine, fenColumn := 0, 0
+	for {
+		c, err := p.skipSpace()
+		if err == io.EOF && len(g.Tags) == 0 {
+			return nil, io.EOF
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == ';' {
+			if _, err := p.readLine(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if c != '[' {
+			p.unreadByte(c)
+			break
+		}
+		line, column := p.line, p.column
+		tag, err := p.readTag()
+		if err != nil {
+			return nil, err
+		}
+		if tag.Name == "FEN" {
+			fenLine, fenColumn = line, column
+		}
+		g.Tags = append(g.Tags, tag)
+	}
+
+	// the start position
+	fen := START_FEN
+	if tag := g.Tag("FEN"); tag != "" {
+		fen = tag
+	}
+	start, err := ParseFEN(fen)
+	if err != nil {
+		return nil, &PGNError{Line: fenLine, Column: fenColumn, Err: err}
+	}
+	switch strings.ToLower(g.Tag("Variant")) {
+	case "chess960", "chess 960", "fischerandom", "fischer random":
+		start.Chess960 = true
+	}
+	g.Start = start
+
+	if err := p.readMovetext(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// reads a [Name "value"] tag pair, the [ has already been read
+func (p *PGNReader) readTag() (PGNTag, error) {
+	var tag PGNTag
+	c, err := p.skipSpace()
+	if err != nil {
+		return tag, p.unexpectedEOF(err)
+	}
+	var name strings.Builder
+	for isLetter(c) || (c >= '0' && c <= '9') || c == '_' {
+		name.WriteByte(c)
+		if c, err = p.readByte(); err != nil {
+			return tag, p.unexpectedEOF(err)
+		}
+	}
+	if name.Len() == 0 {
+		return tag, p.errorAt(p.line, p.column, "expected a tag name, got %q", c)
+	}
+	tag.Name = name.String()
+
+	p.unreadByte(c)
+	if c, err = p.skipSpace(); err != nil {
+		return tag, p.unexpectedEOF(err)
+	}
+	if c != '"' {
+		return tag, p.errorAt(p.line, p.column, "expected a quoted value for tag %s", tag.Name)
+	}
+	var value strings.Builder
+	for {
+		c, err := p.readByte()
+		if err != nil {
+			return tag, p.unexpectedEOF(err)
+		}
+		if c == '\n' {
+			return tag, p.errorAt(p.line-1, p.lastColumn+1, "unterminated value for tag %s", tag.Name)
+		}
+		if c == '"' {
+			break
+		}
+		// backslash escapes quotes and backslashes
+		if c == '\\' {
+			if c, err = p.readByte(); err != nil {
+				return tag, p.unexpectedEOF(err)
+			}
+		}
+		value.WriteByte(c)
+	}
+	tag.Value = value.String()
+
+	if c, err = p.skipSpace(); err != nil {
+		return tag, p.unexpectedEOF(err)
+	}
+	if c != ']' {
+		return tag, p.errorAt(p.line, p.column, "expected ] after tag %s", tag.Name)
+	}
+	return tag, nil
+}
+
+func (p *PGNReader) unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return p.errorAt(p.line, p.column, "unexpected end of file")
+	}
+	return err
+}
+
+// where a variation branches off from, restored when it ends
+type pgnVariation struct {
+	node        *PGNNode
+	board, prev Board
+}
+
+// reads moves, comments, variations and NAGs up to the result
+func (p *PGNReader) readMovetext(g *PGNGame) error {
+	board := *g.Start
+	// the position before the last move, variations start from it
+	var prev Board
+	node := g.Root
+	var variations []pgnVariation
+	// comments at the start of a variation wait for its first move
+	var startingComments []string
+	inVariation := false
+
+	addComment := func(comment string) {
+		if inVariation {
+			startingComments = append(startingComments, strings.TrimSpace(comment))
+		} else {
+			node.addComment(comment)
+		}
+	}
+
+	for {
+		c, err := p.skipSpace()
+		if err == io.EOF {
+			if len(variations) > 0 {
+				return p.errorAt(p.line, p.column, "unexpected end of file in a variation")
+			}
+			g.Result = g.resultTag()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, column := p.line, p.column
+
+		switch {
+		case c == '{':
+			comment, err := p.readComment()
+			if err != nil {
+				return err
+			}
+			addComment(comment)
+		case c == ';':
+			comment, err := p.readLine()
+			if err != nil {
+				return err
+			}
+			addComment(comment)
+		case c == '(':
+			if node == g.Root || inVariation {
+				return p.errorAt(line, column, "variation before any move")
+			}
+			variations = append(variations, pgnVariation{node: node, board: board, prev: prev})
+			node, board = node.Parent, prev
+			inVariation = true
+		case c == ')':
+			if len(variations) == 0 {
+				return p.errorAt(line, column, "unmatched )")
+			}
+			if inVariation {
+				return p.errorAt(line, column, "empty variation")
+			}
+			last := variations[len(variations)-1]
+			variations = variations[:len(variations)-1]
+			node, board, prev = last.node, last.board, last.prev
+		case c == '$':
+			token, err := p.readSymbol()
+			if err != nil {
+				return err
+			}
+			nag, err := strconv.Atoi(token)
+			if err != nil || nag < 0 || nag > 255 {
+				return p.errorAt(line, column, "bad NAG $%s", token)
+			}
+			if node == g.Root || inVariation {
+				return p.errorAt(line, column, "NAG before any move")
+			}
+			node.NAGs = append(node.NAGs, nag)
+		case c == '[':
+			// a new game without a result for this one
+			p.unreadByte(c)
+			if len(variations) > 0 {
+				return p.errorAt(line, column, "unterminated variation")
+			}
+			g.Result = g.resultTag()
+			return nil
+		case isSymbolStart(c):
+			p.unreadByte(c)
+			token, err := p.readSymbol()
+			if err != nil {
+				return err
+			}
+			if isPGNResult(token) {
+				if len(variations) > 0 {
+					return p.errorAt(line, column, "result %s inside a variation", token)
+				}
+				g.Result = token
+				return nil
+			}
+			token = stripMoveNumber(token)
+			if token == "" {
+				continue
+			}
+			san := strings.TrimRight(token, "!?")
+			suffix := token[len(san):]
+			if san != "" {
+				move, err := board.ParseSAN(san)
+				if err != nil {
+					return &PGNError{Line: line, Column: column, Err: err}
+				}
+				node = node.AddChild(move)
+				node.StartingComments, startingComments = startingComments, nil
+				inVariation = false
+				prev = board
+				board.MakeMove(move)
+			}
+			if suffix != "" {
+				nag, ok := pgnSuffixNAGs[suffix]
+				if !ok || node == g.Root || inVariation {
+					return p.errorAt(line, column, "bad annotation %s", suffix)
+				}
+				node.NAGs = append(node.NAGs, nag)
+			}
+		default:
+			return p.errorAt(line, column, "unexpected %q", c)
+		}
+	}
+}
+
+// the result from the tags, for movetext that ends without one
+func (g *PGNGame) resultTag() string {
+	if result := g.Tag("Result"); isPGNResult(result) {
+		return result
+	}
+	return "*"
+}
+
+// reads a {comment}, the { has already been read. Comments can span lines but don't nest.
+func (p *PGNReader) readComment() (string, error) {
+	var sb strings.Builder
+	for {
+		c, err := p.readByte()
+		if err != nil {
+			return "", p.unexpectedEOF(err)
+		}
+		if c == '}' {
+			return sb.String(), nil
+		}
+		if c != '\r' {
+			sb.WriteByte(c)
+		}
+	}
+}
+
+// reads a move, move number, result or NAG number up to the next delimiter
+func (p *PGNReader) readSymbol() (string, error) {
+	var sb strings.Builder
+	for {
+		c, err := p.readByte()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if !isSymbolStart(c) && !strings.ContainsRune("+#=:-/!?.", rune(c)) {
+			p.unreadByte(c)
+			return sb.String(), nil
+		}
+		sb.WriteByte(c)
+	}
+}
+
+// removes a move number like 12. or 12... from the start of a token, they're sometimes written without a space
+func stripMoveNumber(token string) string {
+	digits := len(token) - len(strings.TrimLeft(token, "0123456789"))
+	// castling with zeros isn't a move number
+	if digits < len(token) && token[digits] != '.' {
+		return token
+	}
+	return strings.TrimLeft(token[digits:], ".")
+}
+
+func isSymbolStart(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9') || c == '*' || c == '_' || c == '!' || c == '?'
+}
+
+func isPGNResult(s string) bool {
+	for _, result := range PGN_RESULTS {
+		if s == result {
+			return true
+		}
+	}
+	return false
+}
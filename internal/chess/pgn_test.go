@@ -0,0 +1,260 @@
+package chess
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testPGN = `[Event "F/S Return Match"]
+[Site "Belgrade, Serbia JUG"]
+[Date "1992.11.04"]
+[Round "29"]
+[White "Fischer, Robert J."]
+[Black "Spassky, Boris V."]
+[Result "1/2-1/2"]
+[Annotator "Someone \"quoted\""]
+
+{Game comment} 1. e4 e5 2. Nf3 Nc6 3. Bb5 {This opening is called the Ruy Lopez.} 3... a6
+4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 8. c3 O-O 9. h3 Nb8 10. d4 Nbd7
+11. c4 c6 12. cxb5 axb5 13. Nc3 Bb7 14. Bg5 b4 15. Nb1 h6 16. Bh4 c5 17. dxe5
+Nxe4 18. Bxe7 Qxe7 19. exd6 Qf6 20. Nbd2 Nxd6 21. Nc4 Nxc4 22. Bxc4 Nb6
+23. Ne5 Rae8 24. Bxf7+ Rxf7 25. Nxf7 Rxe1+ 26. Qxe1 Kxf7 27. Qe3 Qg5 28. Qxg5
+hxg5 29. b3 Ke6 30. a3 Kd6 31. axb4 cxb4 32. Ra5 Nd5 33. f3 Bc8 34. Kf2 Bf5
+35. Ra7 g6 36. Ra6+ Kc5 37. Ke1 Nf4 38. g3 Nxh3 39. Kd2 Kb5 40. Rd6 Kc5 41. Ra6
+Nf2 42. g4 Bd3 43. Re6 1/2-1/2
+
+% an escaped line that isn't part of any game
+[Event "Variations"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1-0"]
+
+1.e4 e5 (1...c5 {Sicilian} 2.Nf3 (2.c3 d5) 2...d6 $1) (1... e6!? ; French
+2. d4) 2. Nf3! Nc6 $2 3. Bc4 Bc5 4. 0-0 $14 1-0
+
+[Event "Setup"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 40"]
+[Result "*"]
+
+40. e4 Kd7 41. e5 *
+`
+
+func readTestGames(t *testing.T, pgn string) []*PGNGame {
+	t.Helper()
+	games, err := ReadPGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatalf("ReadPGN failed: %v", err)
+	}
+	return games
+}
+
+func TestReadPGN(t *testing.T) {
+	games := readTestGames(t, testPGN)
+	if len(games) != 3 {
+		t.Fatalf("Expected 3 games, got %d", len(games))
+	}
+
+	fischer := games[0]
+	if fischer.Tag("White") != "Fischer, Robert J." || fischer.Tag("Annotator") != `Someone "quoted"` || len(fischer.Tags) != 8 {
+		t.Errorf("Unexpected tags %v", fischer.Tags)
+	}
+	if fischer.Result != "1/2-1/2" {
+		t.Errorf("Expected result 1/2-1/2, got %s", fischer.Result)
+	}
+	if moves := fischer.MainLine(); len(moves) != 85 {
+		t.Errorf("Expected 85 moves, got %d", len(moves))
+	}
+	if len(fischer.Root.Comments) != 1 || fischer.Root.Comments[0] != "Game comment" {
+		t.Errorf("Unexpected game comment %v", fischer.Root.Comments)
+	}
+	bb5 := fischer.Root.Children[0].Children[0].Children[0].Children[0].Children[0]
+	if bb5.Move.String() != "f1b5" || len(bb5.Comments) != 1 || bb5.Comments[0] != "This opening is called the Ruy Lopez." {
+		t.Errorf("Unexpected comment on %s: %v", bb5.Move.String(), bb5.Comments)
+	}
+	game, err := fischer.Game()
+	if err != nil {
+		t.Fatalf("Game failed: %v", err)
+	}
+	if game.Board.ExportFEN() != "8/8/4R1p1/2k3p1/1p4P1/1P1b1P2/3K1n2/8 b - - 2 43" {
+		t.Errorf("Unexpected final position %s", game.Board.ExportFEN())
+	}
+}
+
+func TestReadPGNVariations(t *testing.T) {
+	g := readTestGames(t, testPGN)[1]
+	if g.Result != "1-0" {
+		t.Errorf("Expected result 1-0, got %s", g.Result)
+	}
+	// 1. e4 and black's three replies
+	e4 := g.Root.Children[0]
+	if len(e4.Children) != 3 {
+		t.Fatalf("Expected 3 replies to e4, got %d", len(e4.Children))
+	}
+	sicilian, french := e4.Children[1], e4.Children[2]
+	if sicilian.Move.String() != "c7c5" || len(sicilian.Comments) != 1 || sicilian.Comments[0] != "Sicilian" {
+		t.Errorf("Unexpected Sicilian %s %v", sicilian.Move.String(), sicilian.Comments)
+	}
+	// 2. Nf3 with 2. c3 as a nested variation, then 2... d6 $1
+	nf3 := sicilian.Children[0]
+	if len(nf3.Children) != 1 || len(sicilian.Children) != 2 || sicilian.Children[1].Move.String() != "c2c3" {
+		t.Fatalf("Unexpected Sicilian tree")
+	}
+	if d6 := nf3.Children[0]; d6.Move.String() != "d7d6" || len(d6.NAGs) != 1 || d6.NAGs[0] != 1 {
+		t.Errorf("Unexpected %s %v", d6.Move.String(), d6.NAGs)
+	}
+	if french.Move.String() != "e7e6" || len(french.NAGs) != 1 || french.NAGs[0] != 5 || french.Comments[0] != "French" {
+		t.Errorf("Unexpected French %s %v %v", french.Move.String(), french.NAGs, french.Comments)
+	}
+	if french.Children[0].Move.String() != "d2d4" {
+		t.Errorf("Expected 2. d4 in the French, got %s", french.Children[0].Move.String())
+	}
+	// back on the main line
+	main := g.MainLine()
+	if len(main) != 7 || main[6].String() != "e1g1" {
+		t.Fatalf("Unexpected main line %v", main)
+	}
+	nf3 = e4.Children[0].Children[0]
+	if len(nf3.NAGs) != 1 || nf3.NAGs[0] != 1 || nf3.Children[0].NAGs[0] != 2 {
+		t.Errorf("Unexpected NAGs %v %v", nf3.NAGs, nf3.Children[0].NAGs)
+	}
+	castle := nf3.Children[0].Children[0].Children[0].Children[0]
+	if len(castle.NAGs) != 1 || castle.NAGs[0] != 14 {
+		t.Errorf("Expected $14, got %v", castle.NAGs)
+	}
+}
+
+func TestReadPGNSetUp(t *testing.T) {
+	g := readTestGames(t, testPGN)[2]
+	if g.Start.ExportFEN() != "4k3/8/8/8/8/8/4P3/4K3 w - - 0 40" {
+		t.Errorf("Unexpected start position %s", g.Start.ExportFEN())
+	}
+	if g.Result != "*" || len(g.MainLine()) != 3 {
+		t.Errorf("Unexpected result %s or moves %v", g.Result, g.MainLine())
+	}
+	// the start position isn't changed by reading the moves
+	if g.Start.ExportFEN() != "4k3/8/8/8/8/8/4P3/4K3 w - - 0 40" {
+		t.Errorf("Start position changed to %s", g.Start.ExportFEN())
+	}
+
+	// Chess960 games castle by their X-FEN rights
+	g = readTestGames(t, `[Variant "Chess960"]
+[FEN "4k3/8/8/8/8/8/8/RK5R w KQ - 0 1"]
+
+1. O-O-O Ke7 2. Rh7+ *`)[0]
+	if !g.Start.Chess960 || len(g.MainLine()) != 3 {
+		t.Errorf("Unexpected Chess960 game %v", g.MainLine())
+	}
+}
+
+func TestReadPGNStartsWithoutTags(t *testing.T) {
+	games := readTestGames(t, "1. d4 d5 1-0\n\n1. c4 *")
+	if len(games) != 2 || games[0].Result != "1-0" || games[1].Result != "*" || games[1].MainLine()[0].String() != "c2c4" {
+		t.Errorf("Unexpected games %v", games)
+	}
+	// no result at the end of the file uses the tag
+	games = readTestGames(t, "[Result \"0-1\"]\n\n1. f3 e5 2. g4 Qh4#")
+	if len(games) != 1 || games[0].Result != "0-1" {
+		t.Errorf("Expected result 0-1, got %v", games)
+	}
+}
+
+func TestReadPGNErrors(t *testing.T) {
+	testCases := []struct {
+		pgn          string
+		line, column int
+		err          error
+	}{
+		{"1. e4 e5 2. Ke3 *", 1, 13, ErrIllegalMove},
+		{"[Event \"x\"]\n\n1. e4 e5\n2. Nf3 Nf3 *", 4, 8, ErrIllegalMove},
+		{"[Event \"unterminated]\n1. e4 *", 1, 22, ErrPGNSyntax},
+		{"[Event x]\n1. e4 *", 1, 8, ErrPGNSyntax},
+		{"1. e4 (e5) *", 1, 8, ErrIllegalMove},
+		{"( 1. e4 ) *", 1, 1, ErrPGNSyntax},
+		{"1. e4 ) *", 1, 7, ErrPGNSyntax},
+		{"1. e4 (1. d4 *", 1, 14, ErrPGNSyntax},
+		{"1. e4 {unterminated", 1, 19, ErrPGNSyntax},
+		{"1. e4 $x *", 1, 7, ErrPGNSyntax},
+		{"$1 1. e4 *", 1, 1, ErrPGNSyntax},
+		{"[FEN \"8/8/8/8/8/8/8/8 w - - 0 1\"]\n*", 1, 1, ErrMissingKing},
+	}
+	for _, tc := range testCases {
+		_, err := ReadPGN(strings.NewReader(tc.pgn))
+		var pgnErr *PGNError
+		if !errors.As(err, &pgnErr) || !errors.Is(err, tc.err) {
+			t.Errorf("ReadPGN(%q): expected %v, got %v", tc.pgn, tc.err, err)
+			continue
+		}
+		if pgnErr.Line != tc.line || pgnErr.Column != tc.column {
+			t.Errorf("ReadPGN(%q): expected the error at %d:%d, got %v", tc.pgn, tc.line, tc.column, err)
+		}
+	}
+}
+
+func TestPGNReaderSkipsBadGames(t *testing.T) {
+	input := "[Event \"1\"]\n\n1. e4 e5 *\n\n[Event \"2\"]\n\n1. e4 e4 2. d4 *\n\n[Event \"3\"]\n\n1. d4 *\n"
+	reader := NewPGNReader(strings.NewReader(input))
+	var events []string
+	errs := 0
+	for {
+		game, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs++
+			continue
+		}
+		events = append(events, game.Tag("Event"))
+	}
+	if errs != 1 || strings.Join(events, ",") != "1,3" {
+		t.Errorf("Expected games 1 and 3 and one error, got %v and %d errors", events, errs)
+	}
+}
+
+// a reader that makes up a long PGN file as it goes
+type repeatReader struct {
+	game  string
+	count int
+	pos   int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.count == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.game[r.pos:])
+	r.pos += n
+	if r.pos == len(r.game) {
+		r.pos = 0
+		r.count--
+	}
+	return n, nil
+}
+
+func TestPGNReaderStreams(t *testing.T) {
+	game := "[Event \"?\"]\n[Result \"1-0\"]\n\n1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0\n\n"
+	reader := NewPGNReader(&repeatReader{game: game, count: 5000})
+	count := 0
+	for {
+		g, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Game %d: %v", count+1, err)
+		}
+		if len(g.MainLine()) != 7 {
+			t.Fatalf("Game %d has %d moves", count+1, len(g.MainLine()))
+		}
+		count++
+	}
+	if count != 5000 {
+		t.Errorf("Expected 5000 games, got %d", count)
+	}
+}
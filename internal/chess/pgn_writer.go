@@ -0,0 +1,240 @@
+package chess
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+	Writing PGN follows the export format from the standard, so other software can read what we write.
+	http://www.saremo.org/pgn/pgn_standard.txt (section 8)
+	- the seven tag roster comes first in its fixed order, then every other tag sorted by name
+	- moves are written as SAN from the position, never as they were typed in
+	- white's moves get a move number, black's only at the start of a variation or after a comment or variation
+	- NAGs are written as $1, not !
+	- lines are wrapped at 80 columns, comments can wrap too
+*/
+
+// PGN_LINE_LENGTH is the longest line WritePGN writes, unless a single token is longer
+const PGN_LINE_LENGTH = 80
+
+// WritePGN writes games in PGN export format, with an empty line after each
+func WritePGN(w io.Writer, games ...*PGNGame) error {
+	for _, game := range games {
+		if _, err := io.WriteString(w, game.String()+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns the game in PGN export format: the tags, an empty line and the movetext
+func (g *PGNGame) String() string {
+	var sb strings.Builder
+	for _, tag := range g.exportTags() {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(tag.Value)
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", tag.Name, value)
+	}
+	sb.WriteByte('\n')
+
+	movetext := &pgnLineWriter{}
+	board := *g.Start
+	movetext.writeComments(g.Root.Comments, g.Root.Commands)
+	movetext.writeLine(g.Root, &board, true)
+	movetext.write(g.Result)
+	sb.WriteString(movetext.String())
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// the seven tag roster with ? for anything missing, then the rest by name
+func (g *PGNGame) exportTags() []PGNTag {
+	tags := make([]PGNTag, 0, len(g.Tags)+len(pgnSevenTagRoster))
+	for _, name := range pgnSevenTagRoster {
+		value := g.Tag(name)
+		switch {
+		case name == "Result":
+			value = g.Result
+		case value == "" && name == "Date":
+			value = "????.??.??"
+		case value == "":
+			value = "?"
+		}
+		tags = append(tags, PGNTag{Name: name, Value: value})
+	}
+	// a game that doesn't start from the standard position needs its FEN, always paired with SetUp
+	fen := g.Start.ExportFEN()
+	setUp := fen != START_FEN
+	var others []PGNTag
+	for _, tag := range g.Tags {
+		if slices.Contains(pgnSevenTagRoster, tag.Name) || setUp && (tag.Name == "SetUp" || tag.Name == "FEN") {
+			continue
+		}
+		others = append(others, tag)
+	}
+	if setUp {
+		others = append(others, PGNTag{Name: "SetUp", Value: "1"}, PGNTag{Name: "FEN", Value: fen})
+	}
+	slices.SortStableFunc(others, func(a, b PGNTag) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return append(tags, others...)
+}
+
+// collects movetext tokens into lines no longer than PGN_LINE_LENGTH
+type pgnLineWriter struct {
+	sb strings.Builder
+	// the tokens on the line being filled and its length with the spaces
+	line   []string
+	length int
+	// stuck to the front of the next token, for the ( starting a variation
+	prefix string
+}
+
+func (w *pgnLineWriter) write(token string) {
+	w.add(w.prefix + token)
+	w.prefix = ""
+}
+
+func (w *pgnLineWriter) add(token string) {
+	if len(w.line) > 0 && w.length+1+len(token) > PGN_LINE_LENGTH {
+		w.flush()
+	}
+	if len(w.line) > 0 {
+		w.length++
+	}
+	w.line = append(w.line, token)
+	w.length += len(token)
+}
+
+// starts a variation, the ( goes right before its first token
+func (w *pgnLineWriter) openVariation() {
+	w.prefix = "("
+}
+
+// ends a variation, the ) goes right after its last token
+func (w *pgnLineWriter) closeVariation() {
+	last := w.line[len(w.line)-1]
+	w.line = w.line[:len(w.line)-1]
+	w.length -= len(last)
+	if len(w.line) > 0 {
+		w.length--
+	}
+	w.add(last + ")")
+}
+
+func (w *pgnLineWriter) flush() {
+	w.sb.WriteString(strings.Join(w.line, " "))
+	w.sb.WriteByte('\n')
+	w.line = w.line[:0]
+	w.length = 0
+}
+
+func (w *pgnLineWriter) String() string {
+	return w.sb.String() + strings.Join(w.line, " ")
+}
+
+// writes the moves after node, main line first, with the variations after each move they replace.
+// board is the position after node and is left at the end of the line.
+func (w *pgnLineWriter) writeLine(node *PGNNode, board *Board, moveNumber bool) {
+	for len(node.Children) > 0 {
+		main := node.Children[0]
+		before := *board
+		moveNumber = w.writeMove(main, board, moveNumber)
+
+		for _, variation := range node.Children[1:] {
+			w.openVariation()
+			w.writeComments(variation.StartingComments, nil)
+			line := before
+			w.writeLine(variation, &line, w.writeMove(variation, &line, true))
+			w.closeVariation()
+			moveNumber = true
+		}
+		node = main
+	}
+}
+
+// writes a move with its number, NAGs and comments, then plays it.
+// Returns whether the next move needs its number written.
+func (w *pgnLineWriter) writeMove(node *PGNNode, board *Board, moveNumber bool) bool {
+	// the number stays on the same line as its move
+	san := board.MoveToSAN(node.Move)
+	if board.WhiteToMove {
+		san = strconv.Itoa(board.FullMoves) + ". " + san
+	} else if moveNumber {
+		san = strconv.Itoa(board.FullMoves) + "... " + san
+	}
+	w.write(san)
+	board.MakeMove(node.Move)
+	for _, nag := range node.NAGs {
+		w.write("$" + strconv.Itoa(nag))
+	}
+	w.writeComments(node.Comments, node.Commands)
+	return len(node.Comments) > 0 || len(node.Commands) > 0
+}
+
+// writes the comments in braces, commands go in the first one.
+// Comments are split at spaces so they can wrap like the moves do.
+func (w *pgnLineWriter) writeComments(comments []string, commands []PGNCommand) {
+	var first []string
+	for _, command := range commands {
+		first = append(first, "[%"+command.Name+" "+command.Value+"]")
+	}
+	if len(comments) > 0 {
+		first = append(first, commentWords(comments[0])...)
+		comments = comments[1:]
+	}
+	w.writeComment(first)
+	for _, comment := range comments {
+		w.writeComment(commentWords(comment))
+	}
+}
+
+// splits a comment into words, a } would end it early so it becomes a )
+func commentWords(comment string) []string {
+	return strings.Fields(strings.ReplaceAll(comment, "}", ")"))
+}
+
+func (w *pgnLineWriter) writeComment(words []string) {
+	if len(words) == 0 {
+		return
+	}
+	if len(words) == 1 {
+		w.write("{" + words[0] + "}")
+		return
+	}
+	w.write("{" + words[0])
+	for _, word := range words[1 : len(words)-1] {
+		w.write(word)
+	}
+	w.write(words[len(words)-1] + "}")
+}
+
+// PGN returns the game as a PGNGame so it can be written out, with the result and how it ended.
+// The error is from parsing StartFEN, which NewGame has already checked unless it was changed since.
+func (g *Game) PGN() (*PGNGame, error) {
+	start, err := ParseFEN(g.StartFEN)
+	if err != nil {
+		return nil, err
+	}
+	start.Chess960 = g.Board.Chess960
+	pgn := newPGNGame(start)
+	node := pgn.Root
+	for _, move := range g.Moves() {
+		node = node.AddChild(move)
+	}
+	pgn.Result = g.Outcome().String()
+	pgn.SetTag("Result", pgn.Result)
+	// the standard only has a few termination reasons, everything but losing on time is normal
+	switch g.Method() {
+	case NoMethod:
+	case Timeout:
+		pgn.SetTag("Termination", "time forfeit")
+	default:
+		pgn.SetTag("Termination", "normal")
+	}
+	return pgn, nil
+}
@@ -0,0 +1,157 @@
+package chess
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePGNVariations(t *testing.T) {
+	g := readTestGames(t, testPGN)[1]
+	expected := `[Event "Variations"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1-0"]
+
+1. e4 e5 (1... c5 {Sicilian} 2. Nf3 (2. c3 d5) 2... d6 $1) (1... e6 $5 {French}
+2. d4) 2. Nf3 $1 Nc6 $2 3. Bc4 Bc5 4. O-O $14 1-0
+`
+	if g.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, g.String())
+	}
+}
+
+func TestWritePGNLineLength(t *testing.T) {
+	out := readTestGames(t, testPGN)[0].String()
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > PGN_LINE_LENGTH {
+			t.Errorf("Line longer than %d: %q", PGN_LINE_LENGTH, line)
+		}
+		// a move number is never left at the end of a line
+		if strings.HasSuffix(line, ".") {
+			t.Errorf("Line ends with a move number: %q", line)
+		}
+	}
+	// comments wrap like the moves
+	if !strings.Contains(strings.ReplaceAll(out, "\n", " "), "3. Bb5 {This opening is called the Ruy Lopez.} 3... a6") {
+		t.Errorf("Black's move number missing after the comment:\n%s", out)
+	}
+}
+
+func TestWritePGNRoundTrip(t *testing.T) {
+	for _, g := range readTestGames(t, testPGN) {
+		first := g.String()
+		again := readTestGames(t, first)
+		if len(again) != 1 || again[0].String() != first {
+			t.Errorf("Round trip failed:\n%s", first)
+		}
+	}
+}
+
+func TestPGNTagOrder(t *testing.T) {
+	g, err := NewPGNGame(START_FEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetTag("WhiteElo", "2700")
+	g.SetTag("ECO", "C65")
+	g.SetTag("White", "Carlsen")
+	g.SetTag("Annotator", "me")
+	expected := "[Event \"?\"]\n[Site \"?\"]\n[Date \"????.??.??\"]\n[Round \"?\"]\n[White \"Carlsen\"]\n[Black \"?\"]\n[Result \"*\"]\n" +
+		"[Annotator \"me\"]\n[ECO \"C65\"]\n[WhiteElo \"2700\"]\n\n*\n"
+	if g.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, g.String())
+	}
+}
+
+// a FEN tag without SetUp is read fine, but written out with it
+func TestPGNSetUpTag(t *testing.T) {
+	fen := "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9"
+	g := readTestGames(t, "[Variant \"Chess960\"]\n[FEN \""+fen+"\"]\n\n9. g3 g6 *")[0]
+	out := g.String()
+	if !strings.Contains(out, "[SetUp \"1\"]\n") || strings.Count(out, "[FEN ") != 1 {
+		t.Errorf("Expected one FEN tag with SetUp \"1\":\n%s", out)
+	}
+	if again := readTestGames(t, out); len(again[0].MainLine()) != 2 || again[0].String() != out {
+		t.Errorf("Round trip failed:\n%s", out)
+	}
+}
+
+func TestPGNClockAndEval(t *testing.T) {
+	g := readTestGames(t, "1. e4 {[%clk 1:05:00] [%eval 0.34] good move} e5 {[%clk 0:04:58.5][%eval #-3]} *")[0]
+	e4 := g.Root.Children[0]
+	if len(e4.Comments) != 1 || e4.Comments[0] != "good move" {
+		t.Errorf("Expected the commands out of the comment, got %q", e4.Comments)
+	}
+	if clock, ok := e4.Clock(); !ok || clock != time.Hour+5*time.Minute {
+		t.Errorf("Expected 1h5m, got %v %v", clock, ok)
+	}
+	if eval, ok := e4.Eval(); !ok || eval.Centipawns != 34 || eval.Mate != 0 {
+		t.Errorf("Expected 34 centipawns, got %v %v", eval, ok)
+	}
+	e5 := e4.Children[0]
+	if clock, ok := e5.Clock(); !ok || clock != 4*time.Minute+58500*time.Millisecond {
+		t.Errorf("Expected 4m58.5s, got %v %v", clock, ok)
+	}
+	if eval, ok := e5.Eval(); !ok || eval.Mate != -3 {
+		t.Errorf("Expected mate in -3, got %v %v", eval, ok)
+	}
+	if _, ok := g.Root.Clock(); ok {
+		t.Error("Expected no clock before the first move")
+	}
+
+	e5.SetClock(3*time.Minute + 2*time.Second)
+	e5.SetEval(PGNEval{Centipawns: -150})
+	e4.SetEval(PGNEval{Mate: 2})
+	out := strings.ReplaceAll(g.String(), "\n", " ")
+	if !strings.Contains(out, "1. e4 {[%clk 1:05:00] [%eval #2] good move} 1... e5 {[%clk 0:03:02] [%eval -1.50]} *") {
+		t.Errorf("Unexpected commands in:\n%s", out)
+	}
+}
+
+func TestGamePGN(t *testing.T) {
+	game := mustNewGame(t, START_FEN)
+	for _, uci := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		move, err := game.Board.ParseUCIMove(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := game.MakeMove(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g, err := game.PGN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Result != "0-1" || g.Tag("Termination") != "normal" {
+		t.Errorf("Expected 0-1 normal, got %s %q", g.Result, g.Tag("Termination"))
+	}
+	var out bytes.Buffer
+	if err := WritePGN(&out, g, g); err != nil {
+		t.Fatal(err)
+	}
+	movetext := "1. f3 e5 2. g4 Qh4# 0-1\n"
+	if strings.Count(out.String(), movetext+"\n") != 2 {
+		t.Errorf("Expected two games each followed by an empty line:\n%s", out.String())
+	}
+	games := readTestGames(t, out.String())
+	if len(games) != 2 || len(games[1].MainLine()) != 4 || games[1].Result != "0-1" {
+		t.Errorf("Couldn't read the written games back: %v", games)
+	}
+
+	// a game started from a position keeps it
+	game = mustNewGame(t, "4k3/8/8/8/8/8/4P3/4K3 w - - 0 40")
+	game.Timeout(WHITE)
+	g, err = game.PGN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Tag("FEN") != "4k3/8/8/8/8/8/4P3/4K3 w - - 0 40" || g.Tag("SetUp") != "1" || g.Tag("Termination") != "time forfeit" {
+		t.Errorf("Unexpected tags %v", g.Tags)
+	}
+}
@@ -18,6 +18,20 @@ const (
 	ATTACK byte = 32 // 00100000
 )
 
+// indexes into the per color arrays on Board
+const (
+	WHITE_INDEX = 0
+	BLACK_INDEX = 1
+)
+
+// converts WHITE or BLACK to its index in the per color arrays on Board
+func colorIndex(color byte) int {
+	if color == WHITE {
+		return WHITE_INDEX
+	}
+	return BLACK_INDEX
+}
+
 func (p Piece) Color() byte {
 	return byte(p) & 24
 }
@@ -0,0 +1,227 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+	Standard Algebraic Notation is how humans (and PGN files) write moves: Nf3, exd5, O-O, e8=Q+.
+	https://www.chessprogramming.org/Algebraic_Chess_Notation#Standard_Algebraic_Notation_.28SAN.29
+	A SAN move only makes sense for a given position. It names the piece and the target square,
+	and only adds the source file and/or rank when another piece of the same type could also move there.
+	Writing SAN needs the legal moves for disambiguation and for the check and mate suffixes,
+	and reading it means finding the one legal move that matches.
+*/
+
+var (
+	ErrInvalidSAN   = errors.New("invalid SAN move")
+	ErrAmbiguousSAN = errors.New("ambiguous SAN move")
+)
+
+// the SAN letter for each piece type, pawns don't have one
+var sanPieceLetters = [7]string{"", "", "N", "B", "R", "Q", "K"}
+
+// MoveToSAN returns the move in Standard Algebraic Notation for the current position
+func (b *Board) MoveToSAN(move Move) string {
+	san := b.sanWithoutSuffix(move)
+
+	// check and mate suffixes
+	state := b.MakeMove(move)
+	opponent := BLACK
+	if b.WhiteToMove {
+		opponent = WHITE
+	}
+	if b.IsInCheck(opponent) {
+		var replies MoveList
+		b.GenerateLegalMovesInto(&replies)
+		if replies.Count == 0 {
+			san += "#"
+		} else {
+			san += "+"
+		}
+	}
+	b.UnmakeMove(move, state)
+	return san
+}
+
+// the SAN for a move without the + or # at the end
+func (b *Board) sanWithoutSuffix(move Move) string {
+	source, target := move.Source(), move.Target()
+	if move.Flag() == CASTLE_FLAG {
+		if target%8 > source%8 {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+
+	piece := b.GetPieceAtIndex(source)
+	capture := !b.GetPieceAtIndex(target).IsNone() || move.Flag() == EN_PASSANT_FLAG
+
+	var san strings.Builder
+	if piece.Type() == PAWN {
+		if capture {
+			san.WriteByte(SquareToString(source)[0])
+			san.WriteByte('x')
+		}
+		san.WriteString(SquareToString(target))
+		if promotion := move.PromotionPiece(); promotion != NONE {
+			san.WriteByte('=')
+			san.WriteString(sanPieceLetters[promotion])
+		}
+		return san.String()
+	}
+
+	san.WriteString(sanPieceLetters[piece.Type()])
+	san.WriteString(b.sanDisambiguation(move, piece))
+	if capture {
+		san.WriteByte('x')
+	}
+	san.WriteString(SquareToString(target))
+	return san.String()
+}
+
+// the source file, rank or square needed to tell the move apart from other moves of the same piece type to the same square
+func (b *Board) sanDisambiguation(move Move, piece Piece) string {
+	var moves MoveList
+	b.GenerateLegalMovesInto(&moves)
+	source, target := move.Source(), move.Target()
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range moves.Slice() {
+		if other.Target() != target || other.Source() == source || b.GetPieceAtIndex(other.Source()) != piece {
+			continue
+		}
+		ambiguous = true
+		if other.Source()%8 == source%8 {
+			sameFile = true
+		}
+		if other.Source()/8 == source/8 {
+			sameRank = true
+		}
+	}
+	square := SquareToString(source)
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return square[:1]
+	case !sameRank:
+		return square[1:]
+	default:
+		return square
+	}
+}
+
+// ParseSAN finds the legal move described by a SAN string in the current position.
+// Besides proper SAN it accepts common sloppy forms like Nbd2, ed5, e8Q, 0-0, long algebraic Ng1f3 and annotations like e4!?
+func (b *Board) ParseSAN(san string) (Move, error) {
+	text := strings.TrimSpace(san)
+	// check, mate and annotation symbols don't change the move
+	text = strings.TrimRight(text, "+#!?")
+	text = strings.TrimSpace(strings.TrimSuffix(text, "e.p."))
+
+	var moves MoveList
+	b.GenerateLegalMovesInto(&moves)
+
+	switch text {
+	case "O-O", "0-0", "o-o":
+		return b.findCastle(&moves, true, san)
+	case "O-O-O", "0-0-0", "o-o-o":
+		return b.findCastle(&moves, false, san)
+	}
+
+	// separators carry no information
+	text = strings.NewReplacer("x", "", "X", "", ":", "", "-", "", "=", "").Replace(text)
+	if len(text) < 2 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSAN, san)
+	}
+
+	pieceType := PAWN
+	if strings.ContainsRune("NBRQK", rune(text[0])) {
+		pieceType = pieceTypeFromLetter(text[0])
+		text = text[1:]
+	}
+
+	// a promotion piece comes after the target square
+	promotion := NONE
+	if last := text[len(text)-1]; strings.ContainsRune("NBRQnbrq", rune(last)) && pieceType == PAWN {
+		promotion = pieceTypeFromLetter(last)
+		text = text[:len(text)-1]
+	}
+
+	if len(text) < 2 || !isSquare(text[len(text)-2:]) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSAN, san)
+	}
+	target := StringToSquare(text[len(text)-2:])
+
+	// anything left is the source file, rank or both
+	fromFile, fromRank := -1, -1
+	for _, c := range text[:len(text)-2] {
+		switch {
+		case c >= 'a' && c <= 'h' && fromFile == -1:
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8' && fromRank == -1:
+			fromRank = int(c - '1')
+		default:
+			return 0, fmt.Errorf("%w: %q", ErrInvalidSAN, san)
+		}
+	}
+
+	found := Move(0)
+	matches := 0
+	for _, move := range moves.Slice() {
+		source := move.Source()
+		if move.Target() != target || b.GetPieceAtIndex(source).Type() != pieceType || move.Flag() == CASTLE_FLAG {
+			continue
+		}
+		if (fromFile != -1 && source%8 != fromFile) || (fromRank != -1 && source/8 != fromRank) {
+			continue
+		}
+		if move.PromotionPiece() != promotion {
+			continue
+		}
+		found = move
+		matches++
+	}
+	switch matches {
+	case 0:
+		return 0, fmt.Errorf("%w: %s", ErrIllegalMove, san)
+	case 1:
+		return found, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrAmbiguousSAN, san)
+	}
+}
+
+// finds the king or queen side castling move in the list of legal moves
+func (b *Board) findCastle(moves *MoveList, kingSide bool, san string) (Move, error) {
+	for _, move := range moves.Slice() {
+		if move.Flag() == CASTLE_FLAG && (move.Target()%8 > move.Source()%8) == kingSide {
+			return move, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrIllegalMove, san)
+}
+
+// converts a piece letter of either case to its type
+func pieceTypeFromLetter(letter byte) byte {
+	switch letter {
+	case 'N', 'n':
+		return KNIGHT
+	case 'B', 'b':
+		return BISHOP
+	case 'R', 'r':
+		return ROOK
+	case 'Q', 'q':
+		return QUEEN
+	case 'K', 'k':
+		return KING
+	}
+	return NONE
+}
+
+// checks if a string is a square name like e4
+func isSquare(s string) bool {
+	return len(s) == 2 && s[0] >= 'a' && s[0] <= 'h' && s[1] >= '1' && s[1] <= '8'
+}
@@ -0,0 +1,145 @@
+package chess
+
+import (
+	"errors"
+	"testing"
+)
+
+// finds the legal move with the given source and target, and promotion letter if there is one
+func findMove(t *testing.T, b *Board, coords string) Move {
+	t.Helper()
+	var moves MoveList
+	b.GenerateLegalMovesInto(&moves)
+	for _, move := range moves.Slice() {
+		if SquareToString(move.Source())+SquareToString(move.Target()) != coords[:4] {
+			continue
+		}
+		if len(coords) == 5 && move.PromotionPiece() != pieceTypeFromLetter(coords[4]) {
+			continue
+		}
+		return move
+	}
+	t.Fatalf("Move %s is not legal in %s", coords, b.ExportFEN())
+	return 0
+}
+
+func TestMoveToSAN(t *testing.T) {
+	testCases := []struct {
+		fen      string
+		move     string
+		expected string
+	}{
+		{START_FEN, "e2e4", "e4"},
+		{START_FEN, "g1f3", "Nf3"},
+		{PerftSuite[1].FEN, "e1g1", "O-O"},
+		{PerftSuite[1].FEN, "e1c1", "O-O-O"},
+		{PerftSuite[1].FEN, "e5f7", "Nxf7"},
+		{PerftSuite[1].FEN, "d5e6", "dxe6"},
+		// en passant
+		{"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 1", "e5d6", "exd6"},
+		// disambiguation by file, by rank and by both
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "b1d2", "Nbd2"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		{"4k3/8/8/2N5/8/2N3N1/8/4K3 w - - 0 1", "c3e4", "Nc3e4"},
+		// a pinned knight can't go there, so no disambiguation
+		{"4k3/8/8/8/4K3/5N2/8/1N5b w - - 0 1", "b1d2", "Nd2"},
+		// promotions, checks and mate
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a7a8q", "a8=Q+"},
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a7a8n", "a8=N"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2", "d8h4", "Qh4#"},
+	}
+	for _, tc := range testCases {
+		board := NewBoard()
+		board.LoadFEN(tc.fen)
+		san := board.MoveToSAN(findMove(t, board, tc.move))
+		if san != tc.expected {
+			t.Errorf("%s in %s: expected %s, got %s", tc.move, tc.fen, tc.expected, san)
+		}
+		if board.ExportFEN() != tc.fen {
+			t.Errorf("MoveToSAN changed the board to %s", board.ExportFEN())
+		}
+	}
+}
+
+func TestParseSAN(t *testing.T) {
+	testCases := []struct {
+		fen      string
+		san      string
+		expected string
+	}{
+		{START_FEN, "e4", "e2e4"},
+		{START_FEN, "Nf3", "g1f3"},
+		{START_FEN, "Ng1f3", "g1f3"},
+		{START_FEN, "e2-e4", "e2e4"},
+		{START_FEN, "e4!?", "e2e4"},
+		{PerftSuite[1].FEN, "O-O", "e1g1"},
+		{PerftSuite[1].FEN, "0-0", "e1g1"},
+		{PerftSuite[1].FEN, "O-O-O", "e1c1"},
+		{PerftSuite[1].FEN, "0-0-0", "e1c1"},
+		{PerftSuite[1].FEN, "Nxf7", "e5f7"},
+		// a file that isn't needed is fine
+		{"rnbqkbnr/ppp1pppp/8/3p4/3P4/8/PPP1PPPP/RNBQKBNR w KQkq - 0 2", "Nbd2", "b1d2"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "exd5", "e4d5"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "ed5", "e4d5"},
+		{"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 1", "exd6 e.p.", "e5d6"},
+		{"4k3/8/8/2N5/8/2N3N1/8/4K3 w - - 0 1", "Nc3e4", "c3e4"},
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a8=Q+", "a7a8q"},
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a8Q", "a7a8q"},
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a8=n", "a7a8n"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2", "Qh4#", "d8h4"},
+	}
+	for _, tc := range testCases {
+		board := NewBoard()
+		board.LoadFEN(tc.fen)
+		move, err := board.ParseSAN(tc.san)
+		if err != nil {
+			t.Errorf("ParseSAN(%q) in %s failed: %v", tc.san, tc.fen, err)
+			continue
+		}
+		if expected := findMove(t, board, tc.expected); move != expected {
+			t.Errorf("ParseSAN(%q): expected %s, got %s", tc.san, expected.String(), move.String())
+		}
+	}
+}
+
+func TestParseSANErrors(t *testing.T) {
+	testCases := []struct {
+		fen      string
+		san      string
+		expected error
+	}{
+		{START_FEN, "Nf6", ErrIllegalMove},
+		{START_FEN, "O-O", ErrIllegalMove},
+		{START_FEN, "Zz9", ErrInvalidSAN},
+		{START_FEN, "", ErrInvalidSAN},
+		{"4k3/8/8/2N5/8/2N3N1/8/4K3 w - - 0 1", "Ne4", ErrAmbiguousSAN},
+		{"4k3/8/8/2N5/8/2N3N1/8/4K3 w - - 0 1", "Nce4", ErrAmbiguousSAN},
+		// the promotion piece can't be left out
+		{"8/P7/8/8/8/8/8/k3K3 w - - 0 1", "a8", ErrIllegalMove},
+	}
+	for _, tc := range testCases {
+		board := NewBoard()
+		board.LoadFEN(tc.fen)
+		if _, err := board.ParseSAN(tc.san); !errors.Is(err, tc.expected) {
+			t.Errorf("ParseSAN(%q) in %s: expected %v, got %v", tc.san, tc.fen, tc.expected, err)
+		}
+	}
+}
+
+func TestSANRoundTrip(t *testing.T) {
+	for _, position := range PerftSuite {
+		board := NewBoard()
+		board.LoadFEN(position.FEN)
+		var moves MoveList
+		board.GenerateLegalMovesInto(&moves)
+		for _, move := range moves.Slice() {
+			san := board.MoveToSAN(move)
+			parsed, err := board.ParseSAN(san)
+			if err != nil {
+				t.Errorf("%s: ParseSAN(%q) failed: %v", position.Name, san, err)
+			} else if parsed != move {
+				t.Errorf("%s: %q parsed as %s, expected %s", position.Name, san, parsed.String(), move.String())
+			}
+		}
+	}
+}
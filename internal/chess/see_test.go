@@ -0,0 +1,75 @@
+package chess
+
+import "testing"
+
+func TestSEE(t *testing.T) {
+	testCases := []struct {
+		fen, san string
+		see      int
+	}{
+		// an undefended pawn
+		{"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "Rxe5", 100},
+		// both queens join in as x-rays behind the rook and bishop
+		{"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "Nxe5", -200},
+		{"3k4/3p4/8/8/8/8/3Q4/4K3 w - - 0 1", "Qxd7+", -800},
+		// the king can't take back a rook that's defended
+		{"3k4/3p4/8/8/8/8/3R4/3RK3 w - - 0 1", "Rxd7+", 100},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6", 100},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8=Q+", 800},
+		{"3rk3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8=Q", -100},
+		{"r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "bxa8=N", 700},
+		// quiet moves lose the piece if it can be taken
+		{"4k3/8/8/3p4/8/8/8/2R1K3 w - - 0 1", "Rc4", -500},
+		{"4k3/8/8/3p4/8/8/8/2R1K3 w - - 0 1", "Rc2", 0},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "O-O", 0},
+		// black to move
+		{"4k3/8/8/3p4/4P3/5P2/8/4K3 b - - 0 1", "dxe4", 0},
+	}
+	for _, tc := range testCases {
+		board, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		move, err := board.ParseSAN(tc.san)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.san, err)
+		}
+		if see := board.SEE(move); see != tc.see {
+			t.Errorf("%s in %s: expected %d, got %d", tc.san, tc.fen, tc.see, see)
+		}
+		if !board.SEEGreaterOrEqual(move, tc.see) || board.SEEGreaterOrEqual(move, tc.see+1) {
+			t.Errorf("%s in %s: SEEGreaterOrEqual doesn't agree with %d", tc.san, tc.fen, tc.see)
+		}
+		if board.ExportFEN() != tc.fen {
+			t.Errorf("%s: board changed to %s", tc.san, board.ExportFEN())
+		}
+	}
+}
+
+// SEEGreaterOrEqual stops early, but has to agree with SEE on every move
+func TestSEEGreaterOrEqual(t *testing.T) {
+	for _, position := range PerftSuite {
+		board, err := ParseFEN(position.FEN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the positions a couple of plies in have more captures to look at
+		var list, replies MoveList
+		board.GenerateLegalMovesInto(&list)
+		for i := 0; i < list.Count; i++ {
+			state := board.MakeMove(list.Moves[i])
+			board.GenerateLegalMovesInto(&replies)
+			for j := 0; j < replies.Count; j++ {
+				move := replies.Moves[j]
+				see := board.SEE(move)
+				for _, threshold := range []int{see - 100, see - 1, see, see + 1, see + 100} {
+					if board.SEEGreaterOrEqual(move, threshold) != (see >= threshold) {
+						t.Fatalf("%s in %s: SEE is %d but SEEGreaterOrEqual(%d) is %v",
+							move.String(), board.ExportFEN(), see, threshold, !(see >= threshold))
+					}
+				}
+			}
+			board.UnmakeMove(list.Moves[i], state)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package chess
+
+/*
+	Staged move generation splits the legal moves into groups so a search can look at them one group at a time.
+	https://www.chessprogramming.org/Move_Generation#Staged_Move_Generation
+	Captures are captures, en passant and every promotion. Quiets are everything else, including castling.
+	Together they are exactly the full list of legal moves.
+	Quiescence search only needs the captures, and sometimes the quiet moves that give check.
+*/
+
+// GenerateCaptures fills the list with the legal captures, en passant captures and promotions
+func (b *Board) GenerateCaptures(list *MoveList) {
+	b.generateLegalMovesInto(list, genCaptures)
+}
+
+// GenerateQuiets fills the list with the legal moves that aren't captures or promotions
+func (b *Board) GenerateQuiets(list *MoveList) {
+	b.generateLegalMovesInto(list, genQuiets)
+}
+
+// GenerateQuietChecks fills the list with the quiet moves that give check
+func (b *Board) GenerateQuietChecks(list *MoveList) {
+	b.generateLegalMovesInto(list, genQuiets)
+	count := 0
+	for i := 0; i < list.Count; i++ {
+		if b.GivesCheck(list.Moves[i]) {
+			list.Moves[count] = list.Moves[i]
+			count++
+		}
+	}
+	list.Count = count
+}
+
+// GivesCheck returns whether a legal move puts the opponent in check, directly or by moving out of the way of a slider
+func (b *Board) GivesCheck(move Move) bool {
+	// castling, en passant and promotions move more than one piece, just try them
+	if move.Flag() != NO_FLAG && move.Flag() != PAWN_DOUBLE_FLAG {
+		return b.givesCheckByMaking(move)
+	}
+	us, them := WHITE_INDEX, BLACK_INDEX
+	if !b.WhiteToMove {
+		us, them = BLACK_INDEX, WHITE_INDEX
+	}
+	if b.Pieces[them][KING] == 0 {
+		return false
+	}
+	king := b.Pieces[them][KING].GetLSB()
+	source, target := move.Source(), move.Target()
+	piece := b.Mailbox[source]
+
+	switch piece.Type() {
+	case PAWN:
+		if pawnAttackMasks[us][target].Occupied(king) {
+			return true
+		}
+	case KNIGHT:
+		if KnightMasks[target].Occupied(king) {
+			return true
+		}
+	}
+
+	// our sliders after the move, seen from the enemy king with the board as it will be
+	occupancy := b.Occupancy()&^(Bitboard(1)<<source) | Bitboard(1)<<target
+	rookLike := (b.Pieces[us][ROOK] | b.Pieces[us][QUEEN]) &^ (Bitboard(1) << source)
+	bishopLike := (b.Pieces[us][BISHOP] | b.Pieces[us][QUEEN]) &^ (Bitboard(1) << source)
+	switch piece.Type() {
+	case ROOK:
+		rookLike |= Bitboard(1) << target
+	case BISHOP:
+		bishopLike |= Bitboard(1) << target
+	case QUEEN:
+		rookLike |= Bitboard(1) << target
+		bishopLike |= Bitboard(1) << target
+	}
+	return rookAttacks(king, occupancy)&rookLike != 0 || bishopAttacks(king, occupancy)&bishopLike != 0
+}
+
+// makes the move and checks if the opponent is in check
+func (b *Board) givesCheckByMaking(move Move) bool {
+	opponent := WHITE
+	if b.WhiteToMove {
+		opponent = BLACK
+	}
+	state := b.MakeMove(move)
+	check := b.IsInCheck(opponent)
+	b.UnmakeMove(move, state)
+	return check
+}
@@ -0,0 +1,70 @@
+package chess
+
+import "testing"
+
+// walks the move tree checking the staged generators against the full legal move list
+func checkStagedMoves(t *testing.T, b *Board, depth int) {
+	t.Helper()
+	var all, captures, quiets, checks MoveList
+	b.GenerateLegalMovesInto(&all)
+	b.GenerateCaptures(&captures)
+	b.GenerateQuiets(&quiets)
+	b.GenerateQuietChecks(&checks)
+
+	if captures.Len()+quiets.Len() != all.Len() {
+		t.Fatalf("%s: %d captures and %d quiets don't add up to %d legal moves", b.ExportFEN(), captures.Len(), quiets.Len(), all.Len())
+	}
+	for _, move := range all.Slice() {
+		isCapture := !b.GetPieceAtIndex(move.Target()).IsNone() || move.Flag() == EN_PASSANT_FLAG || move.Flag() >= PROMOTE_KNIGHT_FLAG
+		if isCapture && !captures.Contains(move) {
+			t.Fatalf("%s: capture %s is missing from GenerateCaptures", b.ExportFEN(), move.String())
+		}
+		if !isCapture && !quiets.Contains(move) {
+			t.Fatalf("%s: quiet move %s is missing from GenerateQuiets", b.ExportFEN(), move.String())
+		}
+		if !isCapture && b.givesCheckByMaking(move) != checks.Contains(move) {
+			t.Fatalf("%s: GenerateQuietChecks is wrong about %s", b.ExportFEN(), move.String())
+		}
+	}
+	if depth <= 1 {
+		return
+	}
+	for _, move := range all.Slice() {
+		state := b.MakeMove(move)
+		checkStagedMoves(t, b, depth-1)
+		b.UnmakeMove(move, state)
+	}
+}
+
+func TestStagedMoveGeneration(t *testing.T) {
+	fens := []string{
+		// discovered checks, a castling check and quiet promotions
+		"4k3/8/8/8/8/8/4N3/4R1K1 w - - 0 1",
+		"5k2/8/8/8/8/8/8/4K2R w K - 0 1",
+		"8/3P4/8/8/8/8/8/k3K3 w - - 0 1",
+	}
+	for _, position := range PerftSuite {
+		fens = append(fens, position.FEN)
+	}
+	for _, fen := range fens {
+		board := NewBoard()
+		board.LoadFEN(fen)
+		checkStagedMoves(t, board, 3)
+	}
+}
+
+func TestGenerateQuietChecks(t *testing.T) {
+	board := NewBoard()
+	// every knight move uncovers the rook on the e-file, the rook and king moves don't check
+	board.LoadFEN("4k3/8/8/8/8/8/4N3/4R1K1 w - - 0 1")
+	var checks MoveList
+	board.GenerateQuietChecks(&checks)
+	for _, move := range checks.Slice() {
+		if move.Source() != StringToSquare("e2") {
+			t.Errorf("Unexpected checking move %s", move.String())
+		}
+	}
+	if checks.Len() != 5 {
+		t.Errorf("Expected 5 discovered checks, got %d", checks.Len())
+	}
+}
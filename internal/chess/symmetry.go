@@ -0,0 +1,68 @@
+package chess
+
+import "strings"
+
+/*
+	Mirroring and flipping a position gives one that should play and evaluate the same.
+	https://www.chessprogramming.org/Flipping_Mirroring_and_Rotating
+	FlipColors turns the board upside down and swaps the colors, so white's pieces become black's on the other side,
+	the side to move changes too and the position is the same game with the players swapped.
+	Mirror swaps the a and h files. Castling isn't the same on both wings, the king ends up on the g or c file
+	whichever side it started nearer, so a mirrored position has no castling rights.
+	Both return a new board and leave the original alone.
+*/
+
+// Mirror returns a copy of the board with the a and h files swapped and no castling rights
+func (b *Board) Mirror() *Board {
+	m := *b
+	m.LegalMoves = nil
+	for color := range b.Pieces {
+		for piece := range b.Pieces[color] {
+			m.Pieces[color][piece] = b.Pieces[color][piece].MirrorHorizontal()
+		}
+		m.Colors[color] = b.Colors[color].MirrorHorizontal()
+		m.Attacks[color] = b.Attacks[color].MirrorHorizontal()
+	}
+	for square, piece := range b.Mailbox {
+		m.Mailbox[square^7] = piece
+	}
+	if b.EnPassantSquare != -1 {
+		m.EnPassantSquare = b.EnPassantSquare ^ 7
+	}
+	m.WhiteCastleRights, m.BlackCastleRights = "", ""
+	m.CastlingRooks = standardCastlingRooks
+	m.Hash = m.ComputeHash()
+	return &m
+}
+
+// FlipColors returns a copy of the board turned upside down with the colors swapped,
+// along with the side to move, the castling rights and the en passant square
+func (b *Board) FlipColors() *Board {
+	f := *b
+	f.LegalMoves = nil
+	for color := range b.Pieces {
+		other := color ^ 1
+		for piece := range b.Pieces[color] {
+			f.Pieces[other][piece] = b.Pieces[color][piece].FlipVertical()
+		}
+		f.Colors[other] = b.Colors[color].FlipVertical()
+		f.Attacks[other] = b.Attacks[color].FlipVertical()
+		for side, rook := range b.CastlingRooks[color] {
+			f.CastlingRooks[other][side] = rook ^ 56
+		}
+	}
+	for square, piece := range b.Mailbox {
+		if !piece.IsNone() {
+			piece ^= Piece(WHITE | BLACK)
+		}
+		f.Mailbox[square^56] = piece
+	}
+	if b.EnPassantSquare != -1 {
+		f.EnPassantSquare = b.EnPassantSquare ^ 56
+	}
+	f.WhiteToMove = !b.WhiteToMove
+	f.WhiteCastleRights = strings.ToUpper(b.BlackCastleRights)
+	f.BlackCastleRights = strings.ToLower(b.WhiteCastleRights)
+	f.Hash = f.ComputeHash()
+	return &f
+}
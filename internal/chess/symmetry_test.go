@@ -0,0 +1,100 @@
+package chess
+
+import "testing"
+
+func TestMirror(t *testing.T) {
+	tests := []struct {
+		fen, mirrored string
+	}{
+		{START_FEN, "rnbkqbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBKQBNR w - - 0 1"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "rnbkqbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBKQBNR b - d3 0 1"},
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", "8/5p2/4p3/r5PK/k1p3R1/8/1P1P4/8 w - - 0 1"},
+	}
+	for _, tt := range tests {
+		board, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mirrored := board.Mirror()
+		if got := mirrored.ExportFEN(); got != tt.mirrored {
+			t.Errorf("Mirror(%s) = %s, want %s", tt.fen, got, tt.mirrored)
+		}
+		if board.ExportFEN() != tt.fen {
+			t.Errorf("Mirror changed the board to %s", board.ExportFEN())
+		}
+		if mirrored.Hash != mirrored.ComputeHash() {
+			t.Errorf("Mirror(%s) has the wrong hash", tt.fen)
+		}
+		checkAttacks(t, mirrored, 1)
+		if back := mirrored.Mirror(); tt.mirrored == tt.fen && back.ExportFEN() != tt.fen {
+			t.Errorf("mirroring %s twice gives %s", tt.fen, back.ExportFEN())
+		}
+	}
+}
+
+func TestFlipColors(t *testing.T) {
+	tests := []struct {
+		fen, flipped string
+	}{
+		{START_FEN, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "rnbqkbnr/pppp1ppp/8/4p3/8/8/PPPPPPPP/RNBQKBNR w KQkq e6 0 1"},
+		{"r3k2r/8/8/8/8/8/8/4K2R w Kq - 3 20", "4k2r/8/8/8/8/8/8/R3K2R b Qk - 3 20"},
+		// Chess960 rights keep their rook files
+		{"1r2k1r1/8/8/8/8/8/8/1R2K1R1 w KQk - 0 1", "1r2k1r1/8/8/8/8/8/8/1R2K1R1 b Kkq - 0 1"},
+	}
+	for _, tt := range tests {
+		board, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		flipped := board.FlipColors()
+		if got := flipped.ExportFEN(); got != tt.flipped {
+			t.Errorf("FlipColors(%s) = %s, want %s", tt.fen, got, tt.flipped)
+		}
+		if board.ExportFEN() != tt.fen {
+			t.Errorf("FlipColors changed the board to %s", board.ExportFEN())
+		}
+		if flipped.Hash != flipped.ComputeHash() {
+			t.Errorf("FlipColors(%s) has the wrong hash", tt.fen)
+		}
+		checkAttacks(t, flipped, 1)
+		if back := flipped.FlipColors(); back.ExportFEN() != tt.fen || back.Hash != board.Hash {
+			t.Errorf("flipping %s twice gives %s", tt.fen, back.ExportFEN())
+		}
+	}
+}
+
+// the flipped and mirrored positions have the same move trees as the originals
+func TestSymmetricPerft(t *testing.T) {
+	for _, position := range PerftSuite {
+		board, err := ParseFEN(position.FEN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := position.Nodes[2]
+		if nodes := board.FlipColors().Perft(3); nodes != want {
+			t.Errorf("%s flipped: expected %d nodes, got %d", position.Name, want, nodes)
+		}
+		// castling doesn't survive mirroring
+		if board.WhiteCastleRights == "" && board.BlackCastleRights == "" {
+			if nodes := board.Mirror().Perft(3); nodes != want {
+				t.Errorf("%s mirrored: expected %d nodes, got %d", position.Name, want, nodes)
+			}
+		}
+	}
+
+	for _, index := range []int{0, 100, 959} {
+		fen, err := Chess960FEN(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		board, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		board.Chess960 = true
+		if want, got := board.Perft(3), board.FlipColors().Perft(3); got != want {
+			t.Errorf("Chess960 position %d flipped: expected %d nodes, got %d", index, want, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package chess
+
+import "strings"
+
+/*
+	Zobrist hashing gives every position a 64-bit key.
+	https://www.chessprogramming.org/Zobrist_Hashing
+	Every piece on every square, every castling right combination, every en passant file and the
+	side to move get a random number. The key of a position is all of the numbers that apply XORed together.
+	Because XOR undoes itself, moving a piece only needs two XORs instead of rehashing the whole board,
+	so Board keeps the key up to date in MakeMove and restores it in UnmakeMove.
+*/
+
+var (
+	// random numbers for each piece on each square, indexed by the piece byte
+	zobristPieces [23][64]uint64
+	// random numbers for each combination of castling rights
+	zobristCastling [16]uint64
+	// random numbers for each en passant file
+	zobristEnPassant [8]uint64
+	// toggled when it's black to move
+	zobristBlackToMove uint64
+)
+
+func init() {
+	// a fixed seed keeps keys the same between runs, so they can be stored
+	rng := xorshift(0x9E3779B97F4A7C15)
+	for _, color := range []byte{WHITE, BLACK} {
+		for pieceType := PAWN; pieceType <= KING; pieceType++ {
+			for square := range 64 {
+				zobristPieces[color|pieceType][square] = rng.next()
+			}
+		}
+	}
+	for i := range zobristCastling {
+		zobristCastling[i] = rng.next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = rng.next()
+	}
+	zobristBlackToMove = rng.next()
+}
+
+// xorshift64* pseudo random number generator, good enough for hash keys
+type xorshift uint64
+
+func (x *xorshift) next() uint64 {
+	*x ^= *x >> 12
+	*x ^= *x << 25
+	*x ^= *x >> 27
+	return uint64(*x) * 0x2545F4914F6CDD1D
+}
+
+// ComputeHash calculates the Zobrist key of the position from scratch
+func (b *Board) ComputeHash() uint64 {
+	var hash uint64
+	for square, piece := range b.Mailbox {
+		if !piece.IsNone() {
+			hash ^= zobristPieces[piece][square]
+		}
+	}
+	return hash ^ b.stateHash()
+}
+
+// the part of the key that isn't pieces: castling rights, en passant and side to move
+func (b *Board) stateHash() uint64 {
+	hash := zobristCastling[b.castlingIndex()]
+	// the en passant square only matters if a pawn can actually capture there
+	if b.canCaptureEnPassant() {
+		hash ^= zobristEnPassant[b.EnPassantSquare%8]
+	}
+	if !b.WhiteToMove {
+		hash ^= zobristBlackToMove
+	}
+	return hash
+}
+
+// castling rights as a 4-bit number, KQkq from lowest to highest bit
+func (b *Board) castlingIndex() int {
+	index := 0
+	if strings.Contains(b.WhiteCastleRights, "K") {
+		index |= 1
+	}
+	if strings.Contains(b.WhiteCastleRights, "Q") {
+		index |= 2
+	}
+	if strings.Contains(b.BlackCastleRights, "k") {
+		index |= 4
+	}
+	if strings.Contains(b.BlackCastleRights, "q") {
+		index |= 8
+	}
+	return index
+}
+
+// checks if the side to move has a pawn next to the en passant square
+func (b *Board) canCaptureEnPassant() bool {
+	if b.EnPassantSquare == -1 {
+		return false
+	}
+	square := b.EnPassantSquare
+	var pawns Bitboard
+	var left, right int
+	if b.WhiteToMove {
+		pawns = b.Pieces[WHITE_INDEX][PAWN]
+		left, right = square-9, square-7
+	} else {
+		pawns = b.Pieces[BLACK_INDEX][PAWN]
+		left, right = square+7, square+9
+	}
+	if square%8 != 0 && left >= 0 && left < 64 && pawns.Occupied(left) {
+		return true
+	}
+	return square%8 != 7 && right >= 0 && right < 64 && pawns.Occupied(right)
+}
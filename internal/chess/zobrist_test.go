@@ -0,0 +1,93 @@
+package chess
+
+import (
+	"testing"
+)
+
+func TestHashMatchesComputeHash(t *testing.T) {
+	fens := []string{
+		START_FEN,
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+		"8/8/8/8/8/8/8/8 w - - 0 1",
+	}
+	for _, fen := range fens {
+		board := NewBoard()
+		board.LoadFEN(fen)
+		if board.Hash != board.ComputeHash() {
+			t.Errorf("Hash after LoadFEN(%s) does not match ComputeHash", fen)
+		}
+	}
+}
+
+func TestHashIncrementalUpdates(t *testing.T) {
+	fens := []string{
+		START_FEN,
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+		"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 1",
+		"8/P7/8/8/8/8/8/k6K w - - 0 1",
+	}
+	for _, fen := range fens {
+		board := NewBoard()
+		board.LoadFEN(fen)
+		original := board.Hash
+
+		board.GenerateLegalMoves()
+		for _, move := range board.LegalMoves {
+			state := board.MakeMove(move)
+			if board.Hash != board.ComputeHash() {
+				t.Errorf("Incremental hash is wrong after %s in %s", move.String(), fen)
+			}
+			board.UnmakeMove(move, state)
+			if board.Hash != original {
+				t.Errorf("Hash was not restored after unmaking %s in %s", move.String(), fen)
+			}
+		}
+	}
+}
+
+func TestHashTranspositions(t *testing.T) {
+	// 1.Nf3 Nf6 2.Nc3 and 1.Nc3 Nf6 2.Nf3 reach the same position
+	first := mustNewGame(t, START_FEN)
+	playMoves(t, first, "g1f3", "g8f6", "b1c3")
+	second := mustNewGame(t, START_FEN)
+	playMoves(t, second, "b1c3", "g8f6", "g1f3")
+	if first.Board.Hash != second.Board.Hash {
+		t.Error("Transposed positions should have the same hash")
+	}
+
+	// the same pieces with the other side to move are a different position
+	board := NewBoard()
+	board.LoadFEN(START_FEN)
+	white := board.Hash
+	board.LoadFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1")
+	if board.Hash == white {
+		t.Error("Side to move should change the hash")
+	}
+
+	// and so are different castling rights
+	board.LoadFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w Kkq - 0 1")
+	if board.Hash == white {
+		t.Error("Castling rights should change the hash")
+	}
+}
+
+func TestHashIgnoresUselessEnPassant(t *testing.T) {
+	board := NewBoard()
+
+	// after 1.e4 no black pawn can capture on e3
+	board.LoadFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	withEP := board.Hash
+	board.LoadFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if withEP != board.Hash {
+		t.Error("Expected en passant square to be ignored when no capture is possible")
+	}
+
+	// a black pawn on d4 can capture on e3, so it matters
+	board.LoadFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	withEP = board.Hash
+	board.LoadFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if withEP == board.Hash {
+		t.Error("Expected en passant square to be part of the hash when a capture is possible")
+	}
+}
@@ -0,0 +1,146 @@
+package endgame
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+// EXTENSION is the file extension of bitbase files
+const EXTENSION = ".bitbase"
+
+// ErrNoMoves is returned by BestMove for a position that is already over
+var ErrNoMoves = errors.New("no legal moves")
+
+// Bitbases is a set of tables, one per material.
+// Probing looks positions up in whichever table has their material, drawn materials like KNK need no table.
+type Bitbases struct {
+	// by material, with the stronger side first and with it second
+	tables map[string]*Table
+}
+
+// NewBitbases returns an empty set, to generate tables into
+func NewBitbases() *Bitbases {
+	return &Bitbases{tables: make(map[string]*Table)}
+}
+
+// Open loads every bitbase file in a list of directories, separated like PATH is
+func Open(path string) (*Bitbases, error) {
+	bb := NewBitbases()
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != EXTENSION {
+				continue
+			}
+			t, err := Load(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			bb.Add(t)
+		}
+	}
+	if len(bb.tables) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoTables, path)
+	}
+	return bb, nil
+}
+
+// Add adds a table to the set, replacing any with the same material
+func (bb *Bitbases) Add(t *Table) {
+	bb.tables[t.material] = t
+	bb.tables[swapSides(t.material)] = t
+}
+
+// Table returns the table for a material, or nil if the set doesn't have it
+func (bb *Bitbases) Table(material string) *Table {
+	white, black, err := parseMaterial(material)
+	if err != nil {
+		return nil
+	}
+	return bb.tables[white+black]
+}
+
+// Materials returns the materials of the tables in the set, sorted
+func (bb *Bitbases) Materials() []string {
+	var materials []string
+	for material, t := range bb.tables {
+		if material == t.material {
+			materials = append(materials, material)
+		}
+	}
+	slices.Sort(materials)
+	return materials
+}
+
+// Probe returns the result of a position for the side to move
+func (bb *Bitbases) Probe(board *chess.Board) (Result, error) {
+	if pieces := board.Occupancy().Count(); pieces > MAX_PIECES {
+		return Result{}, fmt.Errorf("%w: %d pieces, bitbases have up to %d", ErrTooManyPieces, pieces, MAX_PIECES)
+	}
+	if board.IsInsufficientMaterial() {
+		return Result{}, nil
+	}
+	key := boardKey(board)
+	t := bb.tables[key]
+	if t == nil {
+		return Result{}, fmt.Errorf("%w: %s", ErrMissingTable, key)
+	}
+	return t.Probe(board)
+}
+
+// BestMove returns the move that mates quickest, or failing that draws, or failing that loses slowest,
+// with the result of the position before it
+func (bb *Bitbases) BestMove(board *chess.Board) (chess.Move, Result, error) {
+	if board.WhiteCastleRights != "" || board.BlackCastleRights != "" {
+		return 0, Result{}, ErrCastling
+	}
+	b := *board
+	b.LegalMoves = nil
+	var list chess.MoveList
+	b.GenerateLegalMovesInto(&list)
+	if list.Len() == 0 {
+		return 0, Result{}, ErrNoMoves
+	}
+
+	var best chess.Move
+	var bestResult Result
+	bestScore := 0
+	for i, move := range list.Slice() {
+		state := b.MakeMove(move)
+		after, err := bb.Probe(&b)
+		b.UnmakeMove(move, state)
+		if err != nil {
+			return 0, Result{}, err
+		}
+		result := Result{WDL: -after.WDL}
+		if result.WDL != DRAW {
+			result.DTM = after.DTM + 1
+		}
+		if score := score(result); i == 0 || score > bestScore {
+			best, bestResult, bestScore = move, result, score
+		}
+	}
+	return best, bestResult, nil
+}
+
+// orders results from the side to move's point of view, quick mates highest and quick losses lowest
+func score(r Result) int {
+	switch r.WDL {
+	case WIN:
+		return 1000 - r.DTM
+	case LOSS:
+		return -1000 + r.DTM
+	}
+	return 0
+}
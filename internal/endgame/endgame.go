@@ -0,0 +1,260 @@
+// Package endgame generates exact results for small endgames by retrograde analysis and stores them as bitbases.
+package endgame
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+/*
+	Retrograde analysis works backwards from the end of the game.
+	https://www.chessprogramming.org/Retrograde_Analysis
+	Every position of a material is listed, the checkmates are found with the normal move generator,
+	then un-moves walk back from each decided position to the ones that lead to it:
+	a position with a move into a loss is a win one ply further away,
+	and a position whose moves all lead into wins is a loss once the last of them is decided.
+	Whatever is left when nothing changes any more is a draw.
+	Captures and promotions leave the material, their results come from the smaller tables, which are made first.
+
+	The results are distance to mate, in plies, with the 50 move rule ignored.
+	Positions are indexed with the white king mirrored into the a1-d1-d4 triangle, or onto files a-d when there are pawns,
+	so a 4 piece table has 10*64*64*64*2 positions and one byte each.
+	Tables have the stronger side as white, positions with the colors the other way round are flipped to fit.
+	En passant can't be stored, so only one side may have pawns, and castling rights aren't allowed.
+*/
+
+// MAX_PIECES is the most pieces, kings included, a table can have
+const MAX_PIECES = 4
+
+// WDL is a win/draw/loss result for the side to move
+type WDL int8
+
+const (
+	LOSS WDL = -1
+	DRAW WDL = 0
+	WIN  WDL = 1
+)
+
+func (w WDL) String() string {
+	switch w {
+	case LOSS:
+		return "loss"
+	case DRAW:
+		return "draw"
+	case WIN:
+		return "win"
+	}
+	return fmt.Sprintf("WDL(%d)", int8(w))
+}
+
+// Result is the exact result of a position for the side to move
+type Result struct {
+	WDL WDL
+	// plies to mate with best play, odd for wins and even for losses, 0 when mated or drawn
+	DTM int
+}
+
+var (
+	ErrMaterial        = errors.New("invalid material")
+	ErrTooManyPieces   = errors.New("too many pieces for a bitbase")
+	ErrPawns           = errors.New("bitbases can't have pawns on both sides")
+	ErrMissingTable    = errors.New("bitbase missing")
+	ErrNoTables        = errors.New("no bitbase files found")
+	ErrCastling        = errors.New("bitbases don't have positions with castling rights")
+	ErrIllegalPosition = errors.New("illegal position")
+	ErrCorrupt         = errors.New("bitbase file is corrupt")
+)
+
+// Table has the result of every position of one material
+type Table struct {
+	// like KQKR, white's pieces then black's
+	material string
+	// white king, black king, then white's other pieces and black's in chess.MATERIAL_ORDER, the order positions are indexed in
+	pieces []chess.Piece
+	pawns  bool
+	// one byte per index, see the value constants
+	values []byte
+}
+
+const (
+	// a draw, also what isn't known yet during generation
+	drawValue = 0
+	// an index that isn't a legal position, or that a symmetry maps to another index
+	illegalValue = 0xff
+	// everything else is the DTM plus one
+	maxDTM = illegalValue - 2
+)
+
+// newTable checks a material and sets up an empty table for it
+func newTable(material string) (*Table, error) {
+	white, black, err := parseMaterial(material)
+	if err != nil {
+		return nil, err
+	}
+	if white+black != material {
+		return nil, fmt.Errorf("%w: %s is written %s", ErrMaterial, material, white+black)
+	}
+	t := &Table{
+		material: material,
+		pieces:   []chess.Piece{chess.Piece(chess.KING | chess.WHITE), chess.Piece(chess.KING | chess.BLACK)},
+	}
+	for _, side := range []struct {
+		pieces string
+		color  byte
+	}{{white, chess.WHITE}, {black, chess.BLACK}} {
+		for _, c := range side.pieces[1:] {
+			piece := chess.MaterialPieceType(c)
+			t.pieces = append(t.pieces, chess.Piece(piece|side.color))
+			t.pawns = t.pawns || piece == chess.PAWN
+		}
+	}
+	return t, nil
+}
+
+// Material returns the table's material, like KQKR, with the stronger side first
+func (t *Table) Material() string {
+	return t.material
+}
+
+// Size returns how many positions the table has, illegal ones included
+func (t *Table) Size() int {
+	return len(t.values)
+}
+
+// Stats counts a table's legal positions by result, for white to move and black to move
+type Stats struct {
+	Wins, Draws, Losses [2]int
+	// the most plies to mate, from a won position
+	Longest int
+}
+
+// Stats counts the positions of the table by result
+func (t *Table) Stats() Stats {
+	var s Stats
+	for idx, value := range t.values {
+		side := idx & 1
+		switch result := decodeValue(value); {
+		case value == illegalValue:
+		case result.WDL == WIN:
+			s.Wins[side]++
+			s.Longest = max(s.Longest, result.DTM)
+		case result.WDL == LOSS:
+			s.Losses[side]++
+		default:
+			s.Draws[side]++
+		}
+	}
+	return s
+}
+
+// Probe returns the result of a position with the table's material, or with its colors swapped
+func (t *Table) Probe(board *chess.Board) (Result, error) {
+	if board.WhiteCastleRights != "" || board.BlackCastleRights != "" {
+		return Result{}, ErrCastling
+	}
+	key := boardKey(board)
+	flip := key != t.material
+	if flip && swapSides(key) != t.material {
+		return Result{}, fmt.Errorf("%w: %s position in the %s table", ErrMaterial, key, t.material)
+	}
+	squares, side := t.boardSquares(board, flip)
+	value := t.values[t.index(squares, side)]
+	if value == illegalValue {
+		return Result{}, ErrIllegalPosition
+	}
+	return decodeValue(value), nil
+}
+
+func decodeValue(value byte) Result {
+	if value == drawValue || value == illegalValue {
+		return Result{}
+	}
+	dtm := int(value) - 1
+	if dtm%2 == 1 {
+		return Result{WDL: WIN, DTM: dtm}
+	}
+	return Result{WDL: LOSS, DTM: dtm}
+}
+
+func encodeResult(r Result) byte {
+	if r.WDL == DRAW {
+		return drawValue
+	}
+	return byte(r.DTM + 1)
+}
+
+// values used to decide which side is stronger, in chess.MATERIAL_ORDER
+var pieceValues = []int{0, 9, 5, 3, 3, 1}
+
+// splits a material like KQKR into its sides, each sorted into chess.MATERIAL_ORDER, with the stronger side first
+func parseMaterial(material string) (string, string, error) {
+	material = strings.ToUpper(material)
+	second := strings.LastIndex(material, "K")
+	if !strings.HasPrefix(material, "K") || second <= 0 || strings.Count(material, "K") != 2 {
+		return "", "", fmt.Errorf("%w: %q needs a king on each side, like KQK", ErrMaterial, material)
+	}
+	for _, c := range material {
+		if !strings.ContainsRune(chess.MATERIAL_ORDER, c) {
+			return "", "", fmt.Errorf("%w: %q has a piece that isn't one of %s", ErrMaterial, material, chess.MATERIAL_ORDER)
+		}
+	}
+	if len(material) > MAX_PIECES {
+		return "", "", fmt.Errorf("%w: %s has %d pieces, bitbases have up to %d", ErrTooManyPieces, material, len(material), MAX_PIECES)
+	}
+	white, black := chess.SortMaterial(material[:second]), chess.SortMaterial(material[second:])
+	if strings.Contains(white, "P") && strings.Contains(black, "P") {
+		return "", "", fmt.Errorf("%w: %s", ErrPawns, material)
+	}
+	if stronger(black, white) {
+		white, black = black, white
+	}
+	return white, black, nil
+}
+
+// whether one side's pieces beat another's, by count, then value, then the first different piece
+func stronger(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	if value(a) != value(b) {
+		return value(a) > value(b)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return strings.IndexByte(chess.MATERIAL_ORDER, a[i]) < strings.IndexByte(chess.MATERIAL_ORDER, b[i])
+		}
+	}
+	return false
+}
+
+func value(side string) int {
+	total := 0
+	for _, c := range side {
+		total += pieceValues[strings.IndexRune(chess.MATERIAL_ORDER, c)]
+	}
+	return total
+}
+
+// the material with the other side first, KQKR becomes KRKQ
+func swapSides(material string) string {
+	second := strings.LastIndex(material, "K")
+	if second < 0 {
+		return material
+	}
+	return material[second:] + material[:second]
+}
+
+// the material of a position, white's pieces first
+func boardKey(board *chess.Board) string {
+	white, black := board.MaterialKey()
+	return white + black
+}
+
+// whether nobody can mate with this material, so it needs no table
+func drawnMaterial(material string) bool {
+	minors := strings.Count(material, "B") + strings.Count(material, "N")
+	return !strings.ContainsAny(material, "QRP") && minors <= 1
+}
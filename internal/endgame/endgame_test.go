@@ -0,0 +1,362 @@
+package endgame
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+var (
+	smallOnce sync.Once
+	small     *Bitbases
+	smallErr  error
+)
+
+// the 3 piece tables, made once for all the tests
+func smallTables(t *testing.T) *Bitbases {
+	t.Helper()
+	smallOnce.Do(func() {
+		small = NewBitbases()
+		for _, material := range []string{"KQK", "KRK", "KPK"} {
+			if _, smallErr = small.Generate(material); smallErr != nil {
+				return
+			}
+		}
+	})
+	if smallErr != nil {
+		t.Fatal(smallErr)
+	}
+	return small
+}
+
+func mustParse(t *testing.T, fen string) *chess.Board {
+	t.Helper()
+	board, err := chess.ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("ParseFEN(%q): %v", fen, err)
+	}
+	return board
+}
+
+func TestParseMaterial(t *testing.T) {
+	tests := []struct {
+		material     string
+		white, black string
+		err          error
+	}{
+		{"KQK", "KQ", "K", nil},
+		{"kqkr", "KQ", "KR", nil},
+		{"KRKQ", "KQ", "KR", nil},
+		{"KKQ", "KQ", "K", nil},
+		{"KNKB", "KB", "KN", nil},
+		{"KNBK", "KBN", "K", nil},
+		{"KPKR", "KR", "KP", nil},
+		{"QK", "", "", ErrMaterial},
+		{"KQKK", "", "", ErrMaterial},
+		{"KXK", "", "", ErrMaterial},
+		{"KQRBK", "", "", ErrTooManyPieces},
+		{"KPKP", "", "", ErrPawns},
+	}
+	for _, tt := range tests {
+		white, black, err := parseMaterial(tt.material)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parseMaterial(%q) error = %v, want %v", tt.material, err, tt.err)
+			continue
+		}
+		if white != tt.white || black != tt.black {
+			t.Errorf("parseMaterial(%q) = %s, %s, want %s, %s", tt.material, white, black, tt.white, tt.black)
+		}
+	}
+}
+
+func TestIndexSymmetry(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, material := range []string{"KQK", "KPK", "KBNK", "KRKP"} {
+		table, err := newTable(material)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 2000 {
+			var squares [MAX_PIECES]int
+			for i := range table.pieces {
+				squares[i] = rng.Intn(64)
+			}
+			if !table.placeable(squares) {
+				continue
+			}
+			side := rng.Intn(2)
+			idx := table.index(squares, side)
+			if idx < 0 || idx >= table.size() {
+				t.Fatalf("%s: index %d out of range for %v", material, idx, squares)
+			}
+			// every symmetry of the position has the same index
+			for _, s := range table.symmetries() {
+				var turned [MAX_PIECES]int
+				for i := range table.pieces {
+					turned[i] = s.apply(squares[i])
+				}
+				if got := table.index(turned, side); got != idx {
+					t.Errorf("%s: %v turned by %v has index %d, want %d", material, squares, s, got, idx)
+				}
+			}
+			// and the index gives back one of them
+			decoded, decodedSide := table.decode(idx)
+			if decodedSide != side || table.index(decoded, side) != idx {
+				t.Errorf("%s: index %d decodes to %v, which has index %d", material, idx, decoded, table.index(decoded, side))
+			}
+		}
+	}
+}
+
+func TestLongestMates(t *testing.T) {
+	bb := smallTables(t)
+	tests := []struct {
+		material string
+		longest  int
+	}{
+		{"KQK", 19},
+		{"KRK", 31},
+		{"KPK", 55},
+	}
+	for _, tt := range tests {
+		if got := bb.Table(tt.material).Stats().Longest; got != tt.longest {
+			t.Errorf("%s longest mate = %d plies, want %d", tt.material, got, tt.longest)
+		}
+	}
+
+	// KPK has a lot of draws, but black can never win it
+	stats := bb.Table("KPK").Stats()
+	if stats.Wins[1] != 0 || stats.Draws[0] == 0 || stats.Losses[0] != 0 {
+		t.Errorf("KPK stats %+v: black can't win and white can't lose", stats)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	bb := smallTables(t)
+	tests := []struct {
+		name string
+		fen  string
+		want Result
+	}{
+		{"queen mates in one", "k7/8/1K6/8/8/8/7Q/8 w - - 0 1", Result{WIN, 1}},
+		{"black queen mates in one", "8/8/8/8/8/1k6/7q/K7 b - - 0 1", Result{WIN, 1}},
+		{"mated by the rook", "R3k3/8/4K3/8/8/8/8/8 b - - 0 1", Result{LOSS, 0}},
+		{"stalemate", "k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", Result{}},
+		{"opposition, white to move draws", "8/4k3/8/4K3/4P3/8/8/8 w - - 0 1", Result{}},
+		{"opposition, black to move loses", "8/4k3/8/4K3/4P3/8/8/8 b - - 0 1", Result{LOSS, 28}},
+		{"black pawn", "8/8/8/4p3/4k3/8/4K3/8 w - - 0 1", Result{LOSS, 28}},
+		{"king on the 6th wins either way", "4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", Result{WIN, 21}},
+		{"rook pawn draws", "k7/8/8/8/8/8/P7/K7 w - - 0 1", Result{}},
+		{"bare kings", "k7/8/8/8/8/8/8/K7 w - - 0 1", Result{}},
+		{"knight can't mate", "k7/8/8/8/8/8/8/KN6 w - - 0 1", Result{}},
+	}
+	for _, tt := range tests {
+		got, err := bb.Probe(mustParse(t, tt.fen))
+		if err != nil {
+			t.Errorf("%s: Probe: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Probe = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	errorTests := []struct {
+		fen string
+		err error
+	}{
+		{"r3k3/8/8/8/8/8/8/4K3 b q - 0 1", ErrCastling},
+		{"k7/8/8/8/8/8/8/KBN5 w - - 0 1", ErrMissingTable},
+		{"k7/8/8/8/8/8/8/KBNR4 w - - 0 1", ErrTooManyPieces},
+	}
+	for _, tt := range errorTests {
+		if _, err := bb.Probe(mustParse(t, tt.fen)); !errors.Is(err, tt.err) {
+			t.Errorf("Probe(%q) error = %v, want %v", tt.fen, err, tt.err)
+		}
+	}
+}
+
+// checks every result agrees with the results after each move, which only holds if generation is exact
+func checkConsistent(t *testing.T, bb *Bitbases, material string, step int) {
+	t.Helper()
+	table := bb.Table(material)
+	var board chess.Board
+	var list chess.MoveList
+	for idx := 0; idx < table.Size(); idx += step {
+		if table.values[idx] == illegalValue {
+			continue
+		}
+		squares, side := table.decode(idx)
+		table.place(&board, squares, side)
+		want, err := table.Probe(&board)
+		if err != nil {
+			t.Fatalf("%s: Probe(%s): %v", material, board.ExportFEN(), err)
+		}
+		board.GenerateLegalMovesInto(&list)
+		if list.Len() == 0 {
+			mated := board.Checkers() != 0
+			if (mated && want != Result{LOSS, 0}) || (!mated && want != Result{}) {
+				t.Errorf("%s: %s has no moves but is %+v", material, board.ExportFEN(), want)
+			}
+			continue
+		}
+		_, got, err := bb.BestMove(&board)
+		if err != nil {
+			t.Fatalf("%s: BestMove(%s): %v", material, board.ExportFEN(), err)
+		}
+		if got != want {
+			t.Errorf("%s: %s is %+v, but its best move gives %+v", material, board.ExportFEN(), want, got)
+		}
+	}
+}
+
+func TestConsistent(t *testing.T) {
+	bb := smallTables(t)
+	for _, material := range bb.Materials() {
+		checkConsistent(t, bb, material, 3)
+	}
+}
+
+func TestFourPieces(t *testing.T) {
+	if testing.Short() {
+		t.Skip("4 piece tables take a while to make")
+	}
+	bb := NewBitbases()
+	table, err := bb.Generate("KRKQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Material() != "KQKR" {
+		t.Errorf("Material() = %s, want KQKR", table.Material())
+	}
+	// the tables for the captures are made too
+	if got := bb.Materials(); !slices.Equal(got, []string{"KQK", "KQKR", "KRK"}) {
+		t.Errorf("Materials() = %v, want KQK, KQKR and KRK", got)
+	}
+	if got := table.Stats().Longest; got != 69 {
+		t.Errorf("KQKR longest mate = %d plies, want 69", got)
+	}
+
+	// the rook skewers the king and queen, then the rook ending is lost for white
+	board := mustParse(t, "8/8/8/8/8/2K5/8/r1Q1k3 b - - 0 1")
+	move, result, err := bb.BestMove(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move.String() != "a1c1" || result.WDL != WIN {
+		t.Errorf("BestMove = %s %+v, want a1c1 to win", move.String(), result)
+	}
+	checkConsistent(t, bb, "KQKR", 101)
+}
+
+func TestBestMove(t *testing.T) {
+	bb := smallTables(t)
+	move, result, err := bb.BestMove(mustParse(t, "k7/8/1K6/8/8/8/7Q/8 w - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move.String() != "h2h8" || result != (Result{WIN, 1}) {
+		t.Errorf("BestMove = %s %+v, want h2h8 mating", move.String(), result)
+	}
+
+	// the only way to keep the pawn is to lose slowest
+	// black can't get the opposition back, every move draws
+	_, result, err = bb.BestMove(mustParse(t, "8/8/8/4p3/4k3/8/4K3/8 b - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (Result{}) {
+		t.Errorf("BestMove result = %+v, want a draw", result)
+	}
+
+	// the loser plays the longest defence
+	move, result, err = bb.BestMove(mustParse(t, "8/4k3/8/4K3/4P3/8/8/8 b - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (Result{LOSS, 28}) {
+		t.Errorf("BestMove = %s %+v, want a loss in 28 plies", move.String(), result)
+	}
+
+	if _, _, err := bb.BestMove(mustParse(t, "R3k3/8/4K3/8/8/8/8/8 b - - 0 1")); !errors.Is(err, ErrNoMoves) {
+		t.Errorf("BestMove when mated error = %v, want ErrNoMoves", err)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	bb := smallTables(t)
+	table := bb.Table("KPK")
+	var buf bytes.Buffer
+	if err := table.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= table.Size()/4 {
+		t.Errorf("%d positions written in %d bytes, should be compressed", table.Size(), buf.Len())
+	}
+	data := buf.Bytes()
+
+	read, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Material() != "KPK" || !bytes.Equal(read.values, table.values) {
+		t.Errorf("read back %s with different values", read.Material())
+	}
+
+	corrupt := map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("XXXX"), data[4:]...),
+		"version":   append(append([]byte(magic), 9), data[5:]...),
+		"material":  bytes.Replace(data, []byte("KPK"), []byte("KQK"), 1),
+		"truncated": data[:len(data)/2],
+	}
+	for name, data := range corrupt {
+		if _, err := Read(bytes.NewReader(data)); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: Read error = %v, want ErrCorrupt", name, err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	bb := smallTables(t)
+	dir := t.TempDir()
+	for _, material := range bb.Materials() {
+		if _, err := bb.Table(material).Save(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := opened.Materials(); !slices.Equal(got, bb.Materials()) {
+		t.Errorf("Materials() = %v, want %v", got, bb.Materials())
+	}
+	result, err := opened.Probe(mustParse(t, "8/4k3/8/4K3/4P3/8/8/8 b - - 0 1"))
+	if err != nil || result != (Result{LOSS, 28}) {
+		t.Errorf("Probe = %+v, %v, want a loss in 28 plies", result, err)
+	}
+
+	if _, err := Open(t.TempDir()); !errors.Is(err, ErrNoTables) {
+		t.Errorf("Open of an empty directory error = %v, want ErrNoTables", err)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	bb := NewBitbases()
+	for material, want := range map[string]error{
+		"KNK":   ErrMaterial,
+		"KK":    ErrMaterial,
+		"KPKP":  ErrPawns,
+		"KQRKR": ErrTooManyPieces,
+	} {
+		if _, err := bb.Generate(material); !errors.Is(err, want) {
+			t.Errorf("Generate(%s) error = %v, want %v", material, err, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package endgame
+
+import (
+	"bufio"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+/*
+	A bitbase file is a short header and then the table's values, one byte per index, compressed with DEFLATE.
+	The header is the magic "GCBB", a version byte, the length of the material and the material itself,
+	then the number of values as a little endian uint32, so a file can be checked against its material before it's read.
+	Most of a table is draws, illegal indexes and a few common distances, so it compresses to a small part of its size.
+*/
+
+const (
+	magic   = "GCBB"
+	version = 1
+)
+
+// Load reads a table from a bitbase file
+func Load(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Read reads a table in the bitbase format
+func Read(r io.Reader) (*Table, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, len(magic)+2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, fmt.Errorf("%w: not a bitbase file", ErrCorrupt)
+	}
+	if header[len(magic)] != version {
+		return nil, fmt.Errorf("%w: version %d, only %d is known", ErrCorrupt, header[len(magic)], version)
+	}
+	material := make([]byte, header[len(magic)+1])
+	var count uint32
+	if _, err := io.ReadFull(reader, material); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	t, err := newTable(string(material))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if int(count) != t.size() {
+		return nil, fmt.Errorf("%w: %d values, %s has %d", ErrCorrupt, count, t.material, t.size())
+	}
+	t.values = make([]byte, count)
+	values := flate.NewReader(reader)
+	defer values.Close()
+	if _, err := io.ReadFull(values, t.values); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	for _, value := range t.values {
+		if value > maxDTM+1 && value != illegalValue {
+			return nil, fmt.Errorf("%w: value %d", ErrCorrupt, value)
+		}
+	}
+	return t, nil
+}
+
+// Write writes the table in the bitbase format
+func (t *Table) Write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	out.WriteString(magic)
+	out.WriteByte(version)
+	out.WriteByte(byte(len(t.material)))
+	out.WriteString(t.material)
+	binary.Write(out, binary.LittleEndian, uint32(len(t.values)))
+	values, err := flate.NewWriter(out, flate.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := values.Write(t.values); err != nil {
+		return err
+	}
+	if err := values.Close(); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// Save writes the table to a file named after its material in a directory, like KQKR.bitbase
+func (t *Table) Save(dir string) (string, error) {
+	path := filepath.Join(dir, t.material+EXTENSION)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := t.Write(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
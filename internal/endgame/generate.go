@@ -0,0 +1,307 @@
+package endgame
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+/*
+	Generation goes ply by ply, so every result is found at its shortest distance.
+	First every position is looked at with the move generator: mates and stalemates are decided straight away,
+	captures and promotions are looked up in the smaller tables, and the other moves are counted.
+	A move counts once per position it leads to, because un-moves only find each parent once.
+	Then the positions decided at each ply are un-moved: the parents of a loss are wins at the next ply,
+	and each parent of a win has its count taken down, reaching 0 makes it a loss unless a capture or promotion saves it.
+	A capture or promotion that wins decides its position at that ply if nothing quicker has by then.
+*/
+
+// position states during generation
+const (
+	unknown byte = iota
+	illegal
+	drawn
+	won
+	lost
+	// set once a decided position's parents have been updated
+	retracted byte = 0x80
+)
+
+// Generate makes the table for a material, after the tables its captures and promotions lead to if the set doesn't have them.
+// Every table made is added to the set.
+func (bb *Bitbases) Generate(material string) (*Table, error) {
+	white, black, err := parseMaterial(material)
+	if err != nil {
+		return nil, err
+	}
+	material = white + black
+	if t := bb.tables[material]; t != nil {
+		return t, nil
+	}
+	if drawnMaterial(material) {
+		return nil, fmt.Errorf("%w: %s is always a draw", ErrMaterial, material)
+	}
+	for _, smaller := range smallerMaterials(white, black) {
+		if drawnMaterial(smaller) {
+			continue
+		}
+		if _, err := bb.Generate(smaller); err != nil {
+			return nil, err
+		}
+	}
+
+	t, err := newTable(material)
+	if err != nil {
+		return nil, err
+	}
+	g := newGenerator(t, bb)
+	if err := g.scan(); err != nil {
+		return nil, err
+	}
+	if err := g.retract(); err != nil {
+		return nil, err
+	}
+	g.store()
+	bb.Add(t)
+	return t, nil
+}
+
+// the materials one capture or promotion away
+func smallerMaterials(white, black string) []string {
+	var materials []string
+	for _, sides := range [][2]string{{white, black}, {black, white}} {
+		us, them := sides[0], sides[1]
+		// one of our pieces is captured
+		for i := 1; i < len(us); i++ {
+			materials = append(materials, us[:i]+us[i+1:]+them)
+		}
+		i := strings.IndexByte(us, 'P')
+		if i < 0 {
+			continue
+		}
+		for _, promoted := range "QRBN" {
+			us := us[:i] + string(promoted) + us[i+1:]
+			materials = append(materials, us+them)
+			// and with a capture
+			for j := 1; j < len(them); j++ {
+				materials = append(materials, us+them[:j]+them[j+1:])
+			}
+		}
+	}
+	return materials
+}
+
+type generator struct {
+	t  *Table
+	bb *Bitbases
+	// a position state and, once decided, its DTM
+	states, dtm []byte
+	// how many of the positions the moves that stay in the material lead to aren't known to be won
+	counts []byte
+	// the quickest win and slowest loss by a capture or promotion, 0 when there's none
+	captureWin, captureLoss []byte
+	// whether a capture or promotion draws
+	captureDraw []bool
+	// the positions decided at each ply, waiting to be retracted
+	plies [][]int32
+}
+
+func newGenerator(t *Table, bb *Bitbases) *generator {
+	size := t.size()
+	return &generator{
+		t:           t,
+		bb:          bb,
+		states:      make([]byte, size),
+		dtm:         make([]byte, size),
+		counts:      make([]byte, size),
+		captureWin:  make([]byte, size),
+		captureLoss: make([]byte, size),
+		captureDraw: make([]bool, size),
+		plies:       make([][]int32, maxDTM+1),
+	}
+}
+
+// looks at the moves of every position, split between as many goroutines as there are CPUs
+func (g *generator) scan() error {
+	size := len(g.states)
+	workers := runtime.GOMAXPROCS(0)
+	chunk := (size + workers - 1) / workers
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[w] = g.scanRange(w*chunk, min((w+1)*chunk, size))
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	for idx := range g.states {
+		switch {
+		case g.states[idx] == lost:
+			// mated
+			g.decided(idx, 0)
+		case g.states[idx] != unknown:
+		case g.captureWin[idx] != 0:
+			g.decided(idx, int(g.captureWin[idx]))
+		case g.counts[idx] == 0 && g.captureDraw[idx]:
+			g.states[idx] = drawn
+		case g.counts[idx] == 0:
+			// every move is a capture or promotion that loses
+			g.lose(idx, int(g.captureLoss[idx]))
+		}
+	}
+	return nil
+}
+
+func (g *generator) scanRange(from, to int) error {
+	t := g.t
+	var board chess.Board
+	var list chess.MoveList
+	var children []int
+	for idx := from; idx < to; idx++ {
+		squares, side := t.decode(idx)
+		if !t.placeable(squares) || t.index(squares, side) != idx {
+			g.states[idx] = illegal
+			continue
+		}
+		t.place(&board, squares, side)
+		// the side that just moved can't still be in check
+		waiting := chess.BLACK
+		if side == 1 {
+			waiting = chess.WHITE
+		}
+		if board.IsInCheck(waiting) {
+			g.states[idx] = illegal
+			continue
+		}
+
+		board.GenerateLegalMovesInto(&list)
+		if list.Len() == 0 {
+			g.states[idx] = drawn
+			if board.Checkers() != 0 {
+				g.states[idx] = lost
+			}
+			continue
+		}
+		children = children[:0]
+		for _, move := range list.Slice() {
+			if move.PromotionPiece() == chess.NONE && board.Mailbox[move.Target()].IsNone() {
+				child := squares
+				child[slices.Index(squares[:len(t.pieces)], move.Source())] = move.Target()
+				if childIdx := t.index(child, side^1); !slices.Contains(children, childIdx) {
+					children = append(children, childIdx)
+				}
+				continue
+			}
+			state := board.MakeMove(move)
+			result, err := g.bb.Probe(&board)
+			board.UnmakeMove(move, state)
+			if err != nil {
+				return fmt.Errorf("after %s in %s: %w", move.String(), t.material, err)
+			}
+			dtm := byte(result.DTM + 1)
+			switch result.WDL {
+			case LOSS:
+				if g.captureWin[idx] == 0 || dtm < g.captureWin[idx] {
+					g.captureWin[idx] = dtm
+				}
+			case WIN:
+				g.captureLoss[idx] = max(g.captureLoss[idx], dtm)
+			default:
+				g.captureDraw[idx] = true
+			}
+		}
+		g.counts[idx] = byte(len(children))
+	}
+	return nil
+}
+
+// queues a position to be retracted at a ply
+func (g *generator) decided(idx, ply int) {
+	g.plies[ply] = append(g.plies[ply], int32(idx))
+}
+
+func (g *generator) lose(idx, ply int) {
+	g.states[idx] = lost
+	g.dtm[idx] = byte(ply)
+	g.decided(idx, ply)
+}
+
+// goes through the plies in order, un-moving from every position decided at each one
+func (g *generator) retract() error {
+	t := g.t
+	var board chess.Board
+	var parents []int
+	for ply := range g.plies {
+		for i := 0; i < len(g.plies[ply]); i++ {
+			idx := int(g.plies[ply][i])
+			if g.states[idx] == unknown {
+				// nothing was quicker than the winning capture or promotion
+				g.states[idx] = won
+				g.dtm[idx] = byte(ply)
+			}
+			if g.states[idx]&retracted != 0 || int(g.dtm[idx]) != ply {
+				continue
+			}
+			if ply == maxDTM {
+				return fmt.Errorf("%s has mates longer than %d plies", t.material, maxDTM)
+			}
+			g.states[idx] |= retracted
+
+			squares, side := t.decode(idx)
+			t.place(&board, squares, side)
+			parents = parents[:0]
+			t.unmoves(&board, squares, side, func(before [MAX_PIECES]int, beforeSide int) {
+				if parent := t.index(before, beforeSide); !slices.Contains(parents, parent) {
+					parents = append(parents, parent)
+				}
+			})
+			wins := g.states[idx]&^retracted == won
+			for _, parent := range parents {
+				if g.states[parent] != unknown {
+					continue
+				}
+				if !wins {
+					g.states[parent] = won
+					g.dtm[parent] = byte(ply + 1)
+					g.decided(parent, ply+1)
+					continue
+				}
+				g.counts[parent]--
+				if g.counts[parent] == 0 && g.captureWin[parent] == 0 && !g.captureDraw[parent] {
+					g.lose(parent, max(ply+1, int(g.captureLoss[parent])))
+				}
+			}
+		}
+		g.plies[ply] = nil
+	}
+	return nil
+}
+
+// moves the results into the table, anything still unknown is a draw
+func (g *generator) store() {
+	g.t.values = make([]byte, len(g.states))
+	for idx, state := range g.states {
+		switch state &^ retracted {
+		case illegal:
+			g.t.values[idx] = illegalValue
+		case won:
+			g.t.values[idx] = encodeResult(Result{WDL: WIN, DTM: int(g.dtm[idx])})
+		case lost:
+			g.t.values[idx] = encodeResult(Result{WDL: LOSS, DTM: int(g.dtm[idx])})
+		default:
+			g.t.values[idx] = drawValue
+		}
+	}
+}
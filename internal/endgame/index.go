@@ -0,0 +1,177 @@
+package endgame
+
+import "github.com/jgerontis/go-chess/internal/chess"
+
+/*
+	An index is the squares of the pieces in the table's order and the side to move, packed as
+	((kingSlot*64 + square1)*64 + square2 ...)*2 + side, where kingSlot is where the white king is in kingSquares.
+	Mirroring and turning the board doesn't change a position without pawns, with pawns only mirroring the files is allowed,
+	so each position is stored once at the smallest index any of its symmetries gives.
+	Pieces of the same kind are sorted by square for the same reason.
+	The other indexes are marked illegal, generation has to see exactly one index per position or the move counts go wrong.
+*/
+
+// a turn or flip of the board, the square is transposed across the a1-h8 diagonal first if asked and then xor'd
+type symmetry struct {
+	transpose bool
+	flip      int
+}
+
+// the first two keep the pawns moving the right way
+var symmetries = [8]symmetry{
+	{false, 0}, {false, 7}, {false, 56}, {false, 63},
+	{true, 0}, {true, 7}, {true, 56}, {true, 63},
+}
+
+var (
+	// where the white king can be, without pawns and with them
+	kingSquares [2][]int
+	// the position of each square in kingSquares, -1 if it isn't in it
+	kingSlots [2][64]int
+)
+
+func init() {
+	for pawns := range 2 {
+		for square := range 64 {
+			kingSlots[pawns][square] = -1
+			file, rank := square%8, square/8
+			if file > 3 || (pawns == 0 && rank > file) {
+				continue
+			}
+			kingSlots[pawns][square] = len(kingSquares[pawns])
+			kingSquares[pawns] = append(kingSquares[pawns], square)
+		}
+	}
+}
+
+func (s symmetry) apply(square int) int {
+	if s.transpose {
+		square = square>>3 | (square&7)<<3
+	}
+	return square ^ s.flip
+}
+
+// the symmetries a table allows
+func (t *Table) symmetries() []symmetry {
+	if t.pawns {
+		return symmetries[:2]
+	}
+	return symmetries[:]
+}
+
+func (t *Table) kingSquares() []int {
+	if t.pawns {
+		return kingSquares[1]
+	}
+	return kingSquares[0]
+}
+
+// how many indexes the table has
+func (t *Table) size() int {
+	size := len(t.kingSquares()) * 2
+	for range t.pieces[1:] {
+		size *= 64
+	}
+	return size
+}
+
+// the index of a position, the smallest of its symmetries
+func (t *Table) index(squares [MAX_PIECES]int, side int) int {
+	slots := &kingSlots[0]
+	if t.pawns {
+		slots = &kingSlots[1]
+	}
+	best := -1
+	for _, s := range t.symmetries() {
+		slot := slots[s.apply(squares[0])]
+		if slot < 0 {
+			continue
+		}
+		var turned [MAX_PIECES]int
+		for i := range t.pieces {
+			turned[i] = s.apply(squares[i])
+		}
+		t.sortSamePieces(&turned)
+		idx := slot
+		for i := 1; i < len(t.pieces); i++ {
+			idx = idx*64 + turned[i]
+		}
+		idx = idx*2 + side
+		if best < 0 || idx < best {
+			best = idx
+		}
+	}
+	return best
+}
+
+// puts the squares of pieces of the same kind in order, they come next to each other in t.pieces
+func (t *Table) sortSamePieces(squares *[MAX_PIECES]int) {
+	for i := 3; i < len(t.pieces); i++ {
+		for j := i; j > 2 && t.pieces[j] == t.pieces[j-1] && squares[j] < squares[j-1]; j-- {
+			squares[j], squares[j-1] = squares[j-1], squares[j]
+		}
+	}
+}
+
+// the squares and side to move of an index, which might not be a legal position
+func (t *Table) decode(idx int) ([MAX_PIECES]int, int) {
+	var squares [MAX_PIECES]int
+	side := idx & 1
+	idx >>= 1
+	for i := len(t.pieces) - 1; i > 0; i-- {
+		squares[i] = idx & 63
+		idx >>= 6
+	}
+	squares[0] = t.kingSquares()[idx]
+	return squares, side
+}
+
+// whether the squares could be a position: no two pieces on a square and no pawns on the first or last rank.
+// Checks are left to the board.
+func (t *Table) placeable(squares [MAX_PIECES]int) bool {
+	var taken chess.Bitboard
+	for i, piece := range t.pieces {
+		square := squares[i]
+		if taken.Occupied(square) {
+			return false
+		}
+		taken.Set(square)
+		if piece.Type() == chess.PAWN && (square < 8 || square >= 56) {
+			return false
+		}
+	}
+	return true
+}
+
+// sets the board up with the position, which has no castling rights or en passant square
+func (t *Table) place(board *chess.Board, squares [MAX_PIECES]int, side int) {
+	*board = chess.Board{EnPassantSquare: -1, FullMoves: 1, WhiteToMove: side == 0}
+	for i, piece := range t.pieces {
+		board.SetPieceAtIndex(piece, squares[i])
+	}
+	board.UpdateAttacks()
+}
+
+// the squares of the board's pieces in the table's order, with the board flipped top to bottom and the colors swapped if asked
+func (t *Table) boardSquares(board *chess.Board, flip bool) ([MAX_PIECES]int, int) {
+	var squares [MAX_PIECES]int
+	pieces := board.Pieces
+	flipSquares := 0
+	side := 1
+	if board.WhiteToMove {
+		side = 0
+	}
+	if flip {
+		pieces[chess.WHITE_INDEX], pieces[chess.BLACK_INDEX] = pieces[chess.BLACK_INDEX], pieces[chess.WHITE_INDEX]
+		flipSquares = 56
+		side ^= 1
+	}
+	for i, piece := range t.pieces {
+		color := chess.WHITE_INDEX
+		if piece.Color() == chess.BLACK {
+			color = chess.BLACK_INDEX
+		}
+		squares[i] = pieces[color][piece.Type()].PopLSB() ^ flipSquares
+	}
+	return squares, side
+}
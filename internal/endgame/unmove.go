@@ -0,0 +1,93 @@
+package endgame
+
+import "github.com/jgerontis/go-chess/internal/chess"
+
+/*
+	An un-move takes back the last move: a piece of the side that isn't to move goes back to a square it could have come from.
+	Every piece moves back the way it moves forward, over empty squares, except pawns, which step back towards their own side.
+	Un-captures and un-promotions would bring back pieces from another material, so they are never made,
+	the positions before a capture or promotion get their results from the smaller tables instead.
+*/
+
+// calls visit with the squares and side to move of each position one un-move before the board's position.
+// The board has to be set up with the same squares and isn't changed.
+func (t *Table) unmoves(board *chess.Board, squares [MAX_PIECES]int, side int, visit func([MAX_PIECES]int, int)) {
+	// the side that just moved
+	moved, waiting := chess.WHITE_INDEX, chess.BLACK_INDEX
+	color := chess.WHITE
+	if side == 0 {
+		moved, waiting = chess.BLACK_INDEX, chess.WHITE_INDEX
+		color = chess.BLACK
+	}
+	occupancy := board.Occupancy()
+	king := board.Pieces[waiting][chess.KING].GetLSB()
+
+	for i, piece := range t.pieces {
+		if piece.Color() != color {
+			continue
+		}
+		to := squares[i]
+		var from chess.Bitboard
+		switch piece.Type() {
+		case chess.KING:
+			from = chess.KingMasks[to]
+		case chess.KNIGHT:
+			from = chess.KnightMasks[to]
+		case chess.BISHOP:
+			from = bishopAttacks(to, occupancy)
+		case chess.ROOK:
+			from = rookAttacks(to, occupancy)
+		case chess.QUEEN:
+			from = bishopAttacks(to, occupancy) | rookAttacks(to, occupancy)
+		case chess.PAWN:
+			from = pawnOrigins(to, color, occupancy)
+		}
+		from &^= occupancy
+
+		for from != 0 {
+			square := from.PopLSB()
+			// the side to move now can't have been left in check
+			board.ClearPieceAtIndex(piece, to)
+			board.SetPieceAtIndex(piece, square)
+			attacked := board.AttackersTo(king, board.Occupancy())&board.Colors[moved] != 0
+			board.ClearPieceAtIndex(piece, square)
+			board.SetPieceAtIndex(piece, to)
+			if attacked {
+				continue
+			}
+			before := squares
+			before[i] = square
+			visit(before, side^1)
+		}
+	}
+}
+
+// the squares a pawn could have moved to the square from, one step back or two from its fourth rank
+func pawnOrigins(to int, color byte, occupancy chess.Bitboard) chess.Bitboard {
+	step, fourthRank := -8, 3
+	if color == chess.BLACK {
+		step, fourthRank = 8, 4
+	}
+	var from chess.Bitboard
+	back := to + step
+	// a pawn is never on its first rank
+	if back < 8 || back >= 56 || occupancy.Occupied(back) {
+		return from
+	}
+	from.Set(back)
+	if to/8 == fourthRank {
+		from.Set(back + step)
+	}
+	return from
+}
+
+// the same magic bitboard lookups the move generator uses
+func rookAttacks(square int, occupancy chess.Bitboard) chess.Bitboard {
+	blockers := occupancy & chess.RookMasks[square]
+	return chess.RookAttacks[square][(blockers*chess.RookMagics[square])>>(64-chess.RookShifts[square])]
+}
+
+func bishopAttacks(square int, occupancy chess.Bitboard) chess.Bitboard {
+	blockers := occupancy & chess.BishopMasks[square]
+	return chess.BishopAttacks[square][(blockers*chess.BishopMagics[square])>>(64-chess.BishopShifts[square])]
+}
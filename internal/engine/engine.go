@@ -2,8 +2,14 @@ package engine
 
 import (
 	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
 
 	"github.com/jgerontis/go-chess/internal/chess"
+	"github.com/jgerontis/go-chess/internal/endgame"
+	"github.com/jgerontis/go-chess/internal/polyglot"
+	"github.com/jgerontis/go-chess/internal/syzygy"
 	"github.com/jgerontis/go-chess/internal/uci"
 )
 
@@ -16,17 +22,31 @@ import (
 
 // GoChessEngine represents our chess engine implementation
 type GoChessEngine struct {
+	game       *chess.Game
 	board      *chess.Board
 	stopChan   chan struct{}
 	searching  bool
 	currentBest chess.Move
+	// UCI_Chess960, castling moves are sent and received as the king taking its rook
+	chess960   bool
+	// OwnBook, play moves from the Polyglot book in BookFile while there are any
+	ownBook bool
+	book    *polyglot.Book
+	rng     *rand.Rand
+	// SyzygyPath and SyzygyProbeLimit, positions with few enough pieces are played from the tablebases
+	tablebase  *syzygy.Tablebase
+	probeLimit int
+	// BitbasePath, the endings generated by cmd/bitbase are played by distance to mate
+	bitbases *endgame.Bitbases
 }
 
 // NewGoChessEngine creates a new instance of our chess engine
 func NewGoChessEngine() *GoChessEngine {
 	return &GoChessEngine{
-		board:    chess.NewBoard(),
-		stopChan: make(chan struct{}),
+		board:      chess.NewBoard(),
+		stopChan:   make(chan struct{}),
+		rng:        rand.New(rand.NewSource(rand.Int63())),
+		probeLimit: syzygy.MAX_PIECES,
 	}
 }
 
@@ -38,6 +58,82 @@ func (e *GoChessEngine) GetInfo() uci.EngineInfo {
 	}
 }
 
+// Options returns the UCI options the engine supports
+func (e *GoChessEngine) Options() []uci.Option {
+	return []uci.Option{
+		{Name: "UCI_Chess960", Type: uci.OptionCheck, Default: "false"},
+		{Name: "OwnBook", Type: uci.OptionCheck, Default: "false"},
+		{Name: "BookFile", Type: uci.OptionString, Default: "<empty>"},
+		{Name: "SyzygyPath", Type: uci.OptionString, Default: "<empty>"},
+		{Name: "SyzygyProbeLimit", Type: uci.OptionSpin, Default: strconv.Itoa(syzygy.MAX_PIECES), Min: 0, Max: syzygy.MAX_PIECES},
+		{Name: "BitbasePath", Type: uci.OptionString, Default: "<empty>"},
+	}
+}
+
+// SetOption changes one of the engine's UCI options
+func (e *GoChessEngine) SetOption(name, value string) error {
+	switch strings.ToLower(name) {
+	case "uci_chess960":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s", value, name)
+		}
+		e.chess960 = enabled
+		return nil
+	case "ownbook":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s", value, name)
+		}
+		e.ownBook = enabled
+		return nil
+	case "bookfile":
+		if value == "" || value == "<empty>" {
+			e.book = nil
+			return nil
+		}
+		book, err := polyglot.Open(value)
+		if err != nil {
+			return fmt.Errorf("couldn't load the book: %w", err)
+		}
+		e.book = book
+		return nil
+	case "syzygypath":
+		if e.tablebase != nil {
+			e.tablebase.Close()
+			e.tablebase = nil
+		}
+		if value == "" || value == "<empty>" {
+			return nil
+		}
+		tablebase, err := syzygy.Open(value)
+		if err != nil {
+			return fmt.Errorf("couldn't load the tablebases: %w", err)
+		}
+		e.tablebase = tablebase
+		return nil
+	case "syzygyprobelimit":
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 || limit > syzygy.MAX_PIECES {
+			return fmt.Errorf("invalid value %q for %s", value, name)
+		}
+		e.probeLimit = limit
+		return nil
+	case "bitbasepath":
+		e.bitbases = nil
+		if value == "" || value == "<empty>" {
+			return nil
+		}
+		bitbases, err := endgame.Open(value)
+		if err != nil {
+			return fmt.Errorf("couldn't load the bitbases: %w", err)
+		}
+		e.bitbases = bitbases
+		return nil
+	}
+	return fmt.Errorf("unknown option %s", name)
+}
+
 // SetPosition sets the current board position
 func (e *GoChessEngine) SetPosition(fen string, moves []string) error {
 	// Load the position
@@ -45,33 +141,30 @@ func (e *GoChessEngine) SetPosition(fen string, moves []string) error {
 		fen = chess.START_FEN
 	}
 
-	e.board.LoadFEN(fen)
+	newGame := chess.NewGame
+	if e.chess960 {
+		newGame = chess.NewChess960Game
+	}
+	game, err := newGame(fen)
+	if err != nil {
+		return err
+	}
 
-	// Apply moves if provided
+	// Apply moves if provided, the board resolves them against its legal moves
 	for _, moveStr := range moves {
-		move, err := e.parseMove(moveStr)
+		move, err := game.Board.ParseUCIMove(moveStr)
 		if err != nil {
-			return fmt.Errorf("invalid move %s: %v", moveStr, err)
+			return fmt.Errorf("invalid move %s: %w", moveStr, err)
 		}
 
-		// Generate legal moves to validate the move
-		e.board.GenerateLegalMoves()
-		isLegal := false
-		for _, legalMove := range e.board.LegalMoves {
-			if legalMove == move {
-				isLegal = true
-				break
-			}
-		}
-
-		if !isLegal {
+		if err := game.MakeMove(move); err != nil {
 			return fmt.Errorf("illegal move: %s", moveStr)
 		}
-
-		// Make the move
-		_ = e.board.MakeMove(move)
 	}
 
+	e.game = game
+	e.board = game.Board
+
 	return nil
 }
 
@@ -87,8 +180,30 @@ func (e *GoChessEngine) Search(params uci.SearchParams) (string, error) {
 		return "(none)", fmt.Errorf("no legal moves available")
 	}
 	
-	// Start with the first legal move as a fallback
-	e.currentBest = e.board.LegalMoves[0]
+	// play straight from the book while the position is in it
+	if e.ownBook && e.book != nil {
+		if move, err := e.book.WeightedMove(e.board, e.rng); err == nil {
+			e.searching = false
+			return move.String(), nil
+		}
+	}
+
+	// with few enough pieces the tablebases know the best move, or at least which moves keep the result
+	rootMoves, best := e.tablebaseMoves()
+	if best {
+		e.searching = false
+		return rootMoves[0].String(), nil
+	}
+	if move, ok := e.bitbaseMove(); ok {
+		e.searching = false
+		return move.String(), nil
+	}
+	if rootMoves == nil {
+		rootMoves = e.board.LegalMoves
+	}
+
+	// Start with the first root move as a fallback
+	e.currentBest = rootMoves[0]
 	
 	// Create a new stop channel for this search
 	e.stopChan = make(chan struct{})
@@ -96,10 +211,12 @@ func (e *GoChessEngine) Search(params uci.SearchParams) (string, error) {
 	// Run search in goroutine and wait for result or stop signal
 	resultChan := make(chan chess.Move, 1)
 	
+	// the search makes moves on its own copy, so a stopped search can't touch the next position
+	board := *e.board
+	board.LegalMoves = nil
+	options := SearchOptions{Depth: params.Depth, Probe: e.tablebaseScore, Stop: e.stopChan}
 	go func() {
-		// Your actual search logic would go here
-		// For now, simulate with the simple search
-		bestMove := FindBestMove(e.board)
+		bestMove := FindBestMove(&board, rootMoves, options)
 		
 		select {
 		case resultChan <- bestMove:
@@ -129,6 +246,60 @@ func (e *GoChessEngine) Search(params uci.SearchParams) (string, error) {
 	return bestMove.String(), nil
 }
 
+// tablebaseMoves returns the moves that keep the tablebase result when the position is in the tablebases.
+// With the DTZ tables that's just the move that wins quickest, or loses slowest, and best is true.
+// With only the WDL tables it's every move with the best result, for the search to choose between.
+func (e *GoChessEngine) tablebaseMoves() (moves []chess.Move, best bool) {
+	if e.tablebase == nil || e.board.Occupancy().Count() > e.probeLimit {
+		return nil, false
+	}
+	if ranked, err := e.tablebase.RootMoves(e.board); err == nil && len(ranked) > 0 {
+		return []chess.Move{ranked[0].Move}, true
+	}
+	ranked, err := e.tablebase.RootMovesWDL(e.board)
+	if err != nil || len(ranked) == 0 {
+		return nil, false
+	}
+	for _, move := range ranked {
+		if move.Rank == ranked[0].Rank {
+			moves = append(moves, move.Move)
+		}
+	}
+	return moves, false
+}
+
+// tablebaseScore is the search's probe. Positions with no more than SyzygyProbeLimit pieces that a capture
+// or pawn move has just reached, with the halfmove clock at 0, are scored from the WDL tables.
+// Cursed wins and blessed losses are as good as draws once the 50 move rule is counted.
+func (e *GoChessEngine) tablebaseScore(board *chess.Board) (int, bool) {
+	if e.tablebase == nil || board.HalfMoves != 0 || board.Occupancy().Count() > e.probeLimit {
+		return 0, false
+	}
+	wdl, err := e.tablebase.ProbeWDL(board)
+	if err != nil {
+		return 0, false
+	}
+	switch wdl {
+	case syzygy.WIN:
+		return TABLEBASE_WIN, true
+	case syzygy.LOSS:
+		return -TABLEBASE_WIN, true
+	}
+	return 0, true
+}
+
+// bitbaseMove returns the move that mates quickest, or loses slowest, when the position's ending has a bitbase
+func (e *GoChessEngine) bitbaseMove() (chess.Move, bool) {
+	if e.bitbases == nil || e.board.Occupancy().Count() > endgame.MAX_PIECES {
+		return 0, false
+	}
+	move, _, err := e.bitbases.BestMove(e.board)
+	if err != nil {
+		return 0, false
+	}
+	return move, true
+}
+
 // IsReady returns true if the engine is ready to receive commands
 func (e *GoChessEngine) IsReady() bool {
 	return true
@@ -141,37 +312,6 @@ func (e *GoChessEngine) Stop() {
 	}
 }
 
-// parseMove converts a UCI move string to a chess.Move
-func (e *GoChessEngine) parseMove(moveStr string) (chess.Move, error) {
-	if len(moveStr) < 4 {
-		return 0, fmt.Errorf("move string too short: %s", moveStr)
-	}
-
-	fromSquare := chess.StringToSquare(moveStr[:2])
-	toSquare := chess.StringToSquare(moveStr[2:4])
-
-	if fromSquare < 0 || fromSquare >= 64 || toSquare < 0 || toSquare >= 64 {
-		return 0, fmt.Errorf("invalid squares in move: %s", moveStr)
-	}
-
-	// Handle promotion
-	flags := chess.NO_FLAG
-	if len(moveStr) == 5 {
-		switch moveStr[4] {
-		case 'q':
-			flags = chess.PROMOTE_QUEEN_FLAG
-		case 'r':
-			flags = chess.PROMOTE_ROOK_FLAG
-		case 'b':
-			flags = chess.PROMOTE_BISHOP_FLAG
-		case 'n':
-			flags = chess.PROMOTE_KNIGHT_FLAG
-		}
-	}
-
-	return chess.NewMove(fromSquare, toSquare, flags), nil
-}
-
 // StartEngine runs the UCI engine loop using the new infrastructure
 func StartEngine() {
 	engine := NewGoChessEngine()
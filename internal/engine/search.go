@@ -1,7 +1,134 @@
 package engine
 
-import "github.com/jgerontis/go-chess/internal/chess"
+import (
+	"github.com/jgerontis/go-chess/internal/chess"
+)
 
-func FindBestMove(board *chess.Board) chess.Move {
-	return chess.NewMove(chess.StringToSquare("e2"), chess.StringToSquare("e4"), 0)
+/*
+	A plain alpha-beta search over material, enough for the engine to play legal, greedy moves.
+	https://www.chessprogramming.org/Alpha-Beta
+	Below the root every position is offered to SearchOptions.Probe first, which is how the tablebases
+	cut the search short once a capture or pawn move reaches an ending they cover.
+*/
+
+const (
+	// MATE_SCORE is the score for giving mate at the root, mates further away score a little less
+	MATE_SCORE = 100000
+	// TABLEBASE_WIN is the score for a position the tablebases say is won, below any mate the search finds itself
+	TABLEBASE_WIN = MATE_SCORE - 1000
+	// DEFAULT_DEPTH is how many plies FindBestMove searches when no depth is given
+	DEFAULT_DEPTH = 3
+)
+
+// values of the pieces in centipawns, by piece type
+var pieceValues = [7]int{chess.PAWN: 100, chess.KNIGHT: 300, chess.BISHOP: 300, chess.ROOK: 500, chess.QUEEN: 900}
+
+// SearchOptions controls FindBestMove
+type SearchOptions struct {
+	// plies to search, DEFAULT_DEPTH when 0
+	Depth int
+	// called for every position below the root, a true result is used as the position's score without searching it.
+	// Scores are for the side to move, like TABLEBASE_WIN for a won position.
+	Probe func(board *chess.Board) (int, bool)
+	// closing it stops the search, the best move found so far is returned
+	Stop <-chan struct{}
+}
+
+type searcher struct {
+	board   *chess.Board
+	options SearchOptions
+	stopped bool
+}
+
+// FindBestMove searches the root moves and returns the best one.
+// The root moves are fewer than the legal moves when the tablebases have ruled some out, the board is left as it was.
+func FindBestMove(board *chess.Board, rootMoves []chess.Move, options SearchOptions) chess.Move {
+	depth := options.Depth
+	if depth <= 0 {
+		depth = DEFAULT_DEPTH
+	}
+	s := &searcher{board: board, options: options}
+	best, bestScore := rootMoves[0], -MATE_SCORE-1
+	for _, move := range rootMoves {
+		state := board.MakeMove(move)
+		score := -s.search(depth-1, 1, -MATE_SCORE-1, -bestScore)
+		board.UnmakeMove(move, state)
+		if s.stopped {
+			break
+		}
+		if score > bestScore {
+			best, bestScore = move, score
+		}
+	}
+	return best
+}
+
+// negamax with alpha-beta, the score is for the side to move
+func (s *searcher) search(depth, ply, alpha, beta int) int {
+	if s.stopping() {
+		return 0
+	}
+	if s.options.Probe != nil {
+		if score, ok := s.options.Probe(s.board); ok {
+			// like mates, a tablebase win reached sooner is better
+			switch score {
+			case TABLEBASE_WIN:
+				score -= ply
+			case -TABLEBASE_WIN:
+				score += ply
+			}
+			return score
+		}
+	}
+
+	var list chess.MoveList
+	s.board.GenerateLegalMovesInto(&list)
+	if list.Len() == 0 {
+		if s.board.Checkers() != 0 {
+			return -MATE_SCORE + ply
+		}
+		return 0
+	}
+	if depth <= 0 {
+		return s.evaluate()
+	}
+	for _, move := range list.Slice() {
+		state := s.board.MakeMove(move)
+		score := -s.search(depth-1, ply+1, -beta, -alpha)
+		s.board.UnmakeMove(move, state)
+		if s.stopped {
+			return 0
+		}
+		if score >= beta {
+			return beta
+		}
+		alpha = max(alpha, score)
+	}
+	return alpha
+}
+
+// the material balance for the side to move
+func (s *searcher) evaluate() int {
+	us, them := chess.WHITE_INDEX, chess.BLACK_INDEX
+	if !s.board.WhiteToMove {
+		us, them = them, us
+	}
+	score := 0
+	for piece, value := range pieceValues {
+		score += value * (s.board.Pieces[us][piece].Count() - s.board.Pieces[them][piece].Count())
+	}
+	return score
+}
+
+// whether Stop has been closed, checked at every node
+func (s *searcher) stopping() bool {
+	if s.stopped || s.options.Stop == nil {
+		return s.stopped
+	}
+	select {
+	case <-s.options.Stop:
+		s.stopped = true
+	default:
+	}
+	return s.stopped
 }
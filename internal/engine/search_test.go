@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+func legalMoves(t *testing.T, fen string) (*chess.Board, []chess.Move) {
+	t.Helper()
+	board, err := chess.ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list chess.MoveList
+	board.GenerateLegalMovesInto(&list)
+	return board, list.ToSlice()
+}
+
+func TestFindBestMove(t *testing.T) {
+	tests := []struct {
+		name, fen, want string
+	}{
+		{"back rank mate", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8"},
+		{"hanging queen", "4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", "d2d5"},
+		{"rook mate", "k7/8/1K6/8/8/8/8/7R w - - 0 1", "h1h8"},
+	}
+	for _, tt := range tests {
+		board, moves := legalMoves(t, tt.fen)
+		if move := FindBestMove(board, moves, SearchOptions{}); move.String() != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, move.String(), tt.want)
+		}
+		if board.ExportFEN() != tt.fen {
+			t.Errorf("%s: board changed to %s", tt.name, board.ExportFEN())
+		}
+	}
+}
+
+// the probe's score replaces searching the position
+func TestFindBestMoveProbe(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/3r4/3QK3 w - - 5 40"
+	board, moves := legalMoves(t, fen)
+	if move := FindBestMove(board, moves, SearchOptions{}); move.Target() != chess.StringToSquare("d2") {
+		t.Fatalf("without a probe the rook should be taken, got %s", move.String())
+	}
+
+	root := board.Hash
+	probes := 0
+	probe := func(b *chess.Board) (int, bool) {
+		probes++
+		if b.Hash == root {
+			t.Error("the root position was probed")
+		}
+		// pretend taking the rook reaches an ending the side to move wins
+		if b.Occupancy().Count() == 3 {
+			return TABLEBASE_WIN, true
+		}
+		return 0, false
+	}
+	move := FindBestMove(board, moves, SearchOptions{Probe: probe})
+	if move.Target() == chess.StringToSquare("d2") {
+		t.Errorf("the probe says taking the rook loses, got %s", move.String())
+	}
+	if probes == 0 {
+		t.Error("the probe was never called")
+	}
+
+	// checking first reaches the same won ending two plies later, taking the rook straight away is quicker
+	board, moves = legalMoves(t, "k7/8/8/8/8/8/1r6/1Q5K w - - 5 40")
+	probe = func(b *chess.Board) (int, bool) {
+		if b.Occupancy().Count() == 3 {
+			return -TABLEBASE_WIN, true
+		}
+		return 0, false
+	}
+	if move := FindBestMove(board, moves, SearchOptions{Probe: probe}); move.String() != "b1b2" {
+		t.Errorf("expected the quickest win b1b2, got %s", move.String())
+	}
+}
+
+func TestFindBestMoveStop(t *testing.T) {
+	board, moves := legalMoves(t, chess.START_FEN)
+	stop := make(chan struct{})
+	close(stop)
+	move := FindBestMove(board, moves, SearchOptions{Depth: 20, Stop: stop})
+	if move != moves[0] {
+		t.Errorf("a stopped search should keep the first move, got %s", move.String())
+	}
+	if board.ExportFEN() != chess.START_FEN {
+		t.Errorf("board changed to %s", board.ExportFEN())
+	}
+}
@@ -0,0 +1,156 @@
+package polyglot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+// ENTRY_SIZE is the size of a book entry in bytes
+const ENTRY_SIZE = 16
+
+// Entry is one move for one position in a book
+type Entry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+// Book is an opening book held in memory, sorted by key
+type Book struct {
+	entries []Entry
+}
+
+// BookMove is a legal move from the book with its weight
+type BookMove struct {
+	Move   chess.Move
+	Weight int
+}
+
+// Open reads a book from a .bin file
+func Open(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads a book in the Polyglot format.
+// Books are meant to be sorted already, but one that isn't is sorted so lookups still work.
+func Read(r io.Reader) (*Book, error) {
+	reader := bufio.NewReader(r)
+	var entries []Entry
+	var buf [ENTRY_SIZE]byte
+	for {
+		n, err := io.ReadFull(reader, buf[:])
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("%w: %d bytes left over", ErrBookSize, n)
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Key:    binary.BigEndian.Uint64(buf[0:8]),
+			Move:   binary.BigEndian.Uint16(buf[8:10]),
+			Weight: binary.BigEndian.Uint16(buf[10:12]),
+			Learn:  binary.BigEndian.Uint32(buf[12:16]),
+		})
+	}
+	byKey := func(a, b Entry) int {
+		if a.Key < b.Key {
+			return -1
+		}
+		if a.Key > b.Key {
+			return 1
+		}
+		return 0
+	}
+	if !slices.IsSortedFunc(entries, byKey) {
+		slices.SortStableFunc(entries, byKey)
+	}
+	return &Book{entries: entries}, nil
+}
+
+// Len returns the number of entries in the book
+func (b *Book) Len() int {
+	return len(b.entries)
+}
+
+// Entries returns the entries for a key, in the order they're in the book
+func (b *Book) Entries(key uint64) []Entry {
+	start := sort.Search(len(b.entries), func(i int) bool {
+		return b.entries[i].Key >= key
+	})
+	end := start
+	for end < len(b.entries) && b.entries[end].Key == key {
+		end++
+	}
+	return b.entries[start:end]
+}
+
+// Moves returns the book moves for the position.
+// Moves that aren't legal, from a bad book or a key collision, are left out.
+func (b *Book) Moves(board *chess.Board) []BookMove {
+	var moves []BookMove
+	for _, entry := range b.Entries(Key(board)) {
+		move, err := DecodeMove(board, entry.Move)
+		if err != nil {
+			continue
+		}
+		moves = append(moves, BookMove{Move: move, Weight: int(entry.Weight)})
+	}
+	return moves
+}
+
+// BestMove returns the book move with the highest weight, the first one in the book if there's a tie
+func (b *Book) BestMove(board *chess.Board) (chess.Move, error) {
+	moves := b.Moves(board)
+	if len(moves) == 0 {
+		return 0, ErrNoBookHit
+	}
+	best := moves[0]
+	for _, move := range moves[1:] {
+		if move.Weight > best.Weight {
+			best = move
+		}
+	}
+	return best.Move, nil
+}
+
+// WeightedMove picks a book move at random, each as likely as its share of the total weight.
+// Moves with weight 0 are only played when every move has weight 0.
+func (b *Book) WeightedMove(board *chess.Board, rng *rand.Rand) (chess.Move, error) {
+	moves := b.Moves(board)
+	if len(moves) == 0 {
+		return 0, ErrNoBookHit
+	}
+	total := 0
+	for _, move := range moves {
+		total += move.Weight
+	}
+	if total == 0 {
+		return moves[rng.Intn(len(moves))].Move, nil
+	}
+	pick := rng.Intn(total)
+	for _, move := range moves {
+		if pick < move.Weight {
+			return move.Move, nil
+		}
+		pick -= move.Weight
+	}
+	// not reached, the weights add up to total
+	return moves[len(moves)-1].Move, nil
+}
@@ -0,0 +1,242 @@
+package polyglot
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+/*
+	Building a book counts how every move played in a collection of games turned out.
+	Each position on a game's main line, up to MaxPly, gets the move played from it
+	and the result from the point of view of the side that played it.
+	The weight written to the book is 2 points for a win and 1 for a draw, so moves that only lost aren't played.
+	When a position's weights don't fit in 16 bits they're all scaled down together.
+*/
+
+// MoveStats is how the games went after a move, from the point of view of the side that played it
+type MoveStats struct {
+	Move                chess.Move
+	Wins, Draws, Losses int
+	// the move as it's written to the book
+	encoded uint16
+}
+
+// Games returns how many games the move was played in
+func (m *MoveStats) Games() int {
+	return m.Wins + m.Draws + m.Losses
+}
+
+// Score returns the share of the points the move got, between 0 and 1
+func (m *MoveStats) Score() float64 {
+	return (float64(m.Wins) + float64(m.Draws)/2) / float64(m.Games())
+}
+
+// Weight returns the move's book weight before any scaling
+func (m *MoveStats) Weight() int {
+	return 2*m.Wins + m.Draws
+}
+
+// the moves played from one position
+type positionStats struct {
+	// to write the moves as SAN in the dump, a FEN is much smaller than a Board
+	fen      string
+	chess960 bool
+	// in the order they were first played
+	moves []*MoveStats
+}
+
+// Builder collects move statistics from games and turns them into a book
+type Builder struct {
+	// how many plies of each game to count, every one if 0
+	MaxPly int
+	// moves by a player rated below this are skipped, a missing rating counts as 0
+	MinRating int
+	// only games with these results are counted, 1-0, 0-1 and 1/2-1/2 if empty. Unfinished games (*) count as draws.
+	Results []string
+	// moves played in fewer games are left out of the book
+	MinGames int
+
+	// games counted and games skipped by the filters
+	Games, Skipped int
+
+	positions map[uint64]*positionStats
+}
+
+func NewBuilder() *Builder {
+	return &Builder{positions: make(map[uint64]*positionStats)}
+}
+
+// AddGame counts the main line of a game, if it passes the filters
+func (b *Builder) AddGame(game *chess.PGNGame) {
+	results := b.Results
+	if len(results) == 0 {
+		results = []string{"1-0", "0-1", "1/2-1/2"}
+	}
+	if !slices.Contains(results, game.Result) {
+		b.Skipped++
+		return
+	}
+	whiteRating, _ := strconv.Atoi(game.Tag("WhiteElo"))
+	blackRating, _ := strconv.Atoi(game.Tag("BlackElo"))
+	if whiteRating < b.MinRating && blackRating < b.MinRating {
+		b.Skipped++
+		return
+	}
+	b.Games++
+
+	board := *game.Start
+	for ply, move := range game.MainLine() {
+		if b.MaxPly > 0 && ply >= b.MaxPly {
+			break
+		}
+		rating := whiteRating
+		if !board.WhiteToMove {
+			rating = blackRating
+		}
+		if rating >= b.MinRating {
+			b.record(&board, move, game.Result)
+		}
+		board.MakeMove(move)
+	}
+}
+
+// adds a move and the game's result to the position's statistics
+func (b *Builder) record(board *chess.Board, move chess.Move, result string) {
+	key := Key(board)
+	position := b.positions[key]
+	if position == nil {
+		position = &positionStats{fen: board.ExportFEN(), chess960: board.Chess960}
+		b.positions[key] = position
+	}
+	var stats *MoveStats
+	for _, s := range position.moves {
+		if s.Move == move {
+			stats = s
+		}
+	}
+	if stats == nil {
+		stats = &MoveStats{Move: move, encoded: EncodeMove(board, move)}
+		position.moves = append(position.moves, stats)
+	}
+
+	won, lost := "1-0", "0-1"
+	if !board.WhiteToMove {
+		won, lost = lost, won
+	}
+	switch result {
+	case won:
+		stats.Wins++
+	case lost:
+		stats.Losses++
+	default:
+		stats.Draws++
+	}
+}
+
+// Positions returns how many positions have at least one move
+func (b *Builder) Positions() int {
+	return len(b.positions)
+}
+
+// the moves of a position that make it into the book, most played first
+func (b *Builder) bookMoves(position *positionStats) []*MoveStats {
+	var moves []*MoveStats
+	for _, stats := range position.moves {
+		if stats.Games() >= b.MinGames && stats.Weight() > 0 {
+			moves = append(moves, stats)
+		}
+	}
+	slices.SortStableFunc(moves, func(x, y *MoveStats) int {
+		return cmp.Compare(y.Games(), x.Games())
+	})
+	return moves
+}
+
+// the positions' keys in the order they're written to the book
+func (b *Builder) sortedKeys() []uint64 {
+	keys := make([]uint64, 0, len(b.positions))
+	for key := range b.positions {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Entries returns the book, sorted by key and then by weight with the highest first
+func (b *Builder) Entries() []Entry {
+	var entries []Entry
+	for _, key := range b.sortedKeys() {
+		position := b.positions[key]
+		moves := b.bookMoves(position)
+		// scale every weight by the same amount so the largest fits
+		largest := 0
+		for _, stats := range moves {
+			largest = max(largest, stats.Weight())
+		}
+		start := len(entries)
+		for _, stats := range moves {
+			weight := stats.Weight()
+			if largest > 0xffff {
+				weight = max(1, weight*0xffff/largest)
+			}
+			entries = append(entries, Entry{
+				Key:    key,
+				Move:   stats.encoded,
+				Weight: uint16(weight),
+			})
+		}
+		slices.SortStableFunc(entries[start:], func(x, y Entry) int {
+			return cmp.Compare(y.Weight, x.Weight)
+		})
+	}
+	return entries
+}
+
+// Dump writes the book as text, each position's FEN and key followed by its moves and their statistics
+func (b *Builder) Dump(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	for _, key := range b.sortedKeys() {
+		position := b.positions[key]
+		moves := b.bookMoves(position)
+		if len(moves) == 0 {
+			continue
+		}
+		// the FEN came from ExportFEN, but a board that didn't load would turn every move into nonsense
+		board := chess.NewBoard()
+		if err := board.LoadFEN(position.fen); err != nil {
+			return err
+		}
+		board.Chess960 = position.chess960
+		fmt.Fprintf(out, "%s  key %016x\n", position.fen, key)
+		for _, stats := range moves {
+			fmt.Fprintf(out, "  %-7s games %-6d +%d =%d -%d  score %.1f%%  weight %d\n",
+				board.MoveToSAN(stats.Move), stats.Games(), stats.Wins, stats.Draws, stats.Losses,
+				100*stats.Score(), stats.Weight())
+		}
+		fmt.Fprintln(out)
+	}
+	return out.Flush()
+}
+
+// Write writes entries as a Polyglot book, they have to be sorted by key already
+func Write(w io.Writer, entries []Entry) error {
+	out := bufio.NewWriter(w)
+	var buf [ENTRY_SIZE]byte
+	for _, entry := range entries {
+		binary.BigEndian.PutUint64(buf[0:8], entry.Key)
+		binary.BigEndian.PutUint16(buf[8:10], entry.Move)
+		binary.BigEndian.PutUint16(buf[10:12], entry.Weight)
+		binary.BigEndian.PutUint32(buf[12:16], entry.Learn)
+		if _, err := out.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,188 @@
+package polyglot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+const builderGames = `[WhiteElo "2500"]
+[BlackElo "2400"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 1-0
+
+[WhiteElo "2500"]
+[BlackElo "1500"]
+[Result "0-1"]
+
+1. e4 c5 2. Nf3 0-1
+
+[WhiteElo "1600"]
+[BlackElo "1700"]
+[Result "1/2-1/2"]
+
+1. d4 d5 1/2-1/2
+
+[Result "*"]
+
+1. c4 *
+`
+
+func buildBook(t *testing.T, builder *Builder) {
+	t.Helper()
+	games, err := chess.ReadPGN(strings.NewReader(builderGames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, game := range games {
+		builder.AddGame(game)
+	}
+}
+
+// finds the statistics for a UCI move from a position
+func moveStats(t *testing.T, builder *Builder, board *chess.Board, uci string) *MoveStats {
+	t.Helper()
+	position := builder.positions[Key(board)]
+	if position == nil {
+		return nil
+	}
+	for _, stats := range position.moves {
+		if stats.Move.String() == uci {
+			return stats
+		}
+	}
+	return nil
+}
+
+func TestBuilderStats(t *testing.T) {
+	builder := NewBuilder()
+	buildBook(t, builder)
+	if builder.Games != 3 || builder.Skipped != 1 {
+		t.Errorf("counted %d games and skipped %d, want 3 and 1", builder.Games, builder.Skipped)
+	}
+
+	start := playMoves(t)
+	tests := []struct {
+		board               *chess.Board
+		move                string
+		wins, draws, losses int
+	}{
+		{start, "e2e4", 1, 0, 1},
+		{start, "d2d4", 0, 1, 0},
+		// black's results are from black's side
+		{playMoves(t, "e2e4"), "c7c5", 1, 0, 0},
+		{playMoves(t, "e2e4"), "e7e5", 0, 0, 1},
+		{playMoves(t, "e2e4", "e7e5"), "g1f3", 1, 0, 0},
+	}
+	for _, test := range tests {
+		stats := moveStats(t, builder, test.board, test.move)
+		if stats == nil {
+			t.Errorf("%s: no statistics", test.move)
+			continue
+		}
+		if stats.Wins != test.wins || stats.Draws != test.draws || stats.Losses != test.losses {
+			t.Errorf("%s: got +%d =%d -%d, want +%d =%d -%d", test.move,
+				stats.Wins, stats.Draws, stats.Losses, test.wins, test.draws, test.losses)
+		}
+	}
+	if stats := moveStats(t, builder, start, "c2c4"); stats != nil {
+		t.Errorf("unfinished game was counted")
+	}
+}
+
+func TestBuilderFilters(t *testing.T) {
+	builder := NewBuilder()
+	builder.MaxPly = 2
+	buildBook(t, builder)
+	if stats := moveStats(t, builder, playMoves(t, "e2e4", "e7e5"), "g1f3"); stats != nil {
+		t.Errorf("MaxPly 2: the third ply was counted")
+	}
+	if stats := moveStats(t, builder, playMoves(t, "e2e4"), "e7e5"); stats == nil {
+		t.Errorf("MaxPly 2: the second ply wasn't counted")
+	}
+
+	builder = NewBuilder()
+	builder.MinRating = 2000
+	buildBook(t, builder)
+	if builder.Games != 2 {
+		t.Errorf("MinRating 2000: counted %d games, want 2", builder.Games)
+	}
+	// only white was rated 2000 in the second game
+	if stats := moveStats(t, builder, playMoves(t, "e2e4"), "c7c5"); stats != nil {
+		t.Errorf("MinRating 2000: counted a move by a 1500 player")
+	}
+	if stats := moveStats(t, builder, playMoves(t), "e2e4"); stats == nil || stats.Games() != 2 {
+		t.Errorf("MinRating 2000: e4 should be counted in both games")
+	}
+
+	builder = NewBuilder()
+	builder.Results = []string{"1-0", "*"}
+	buildBook(t, builder)
+	if builder.Games != 2 {
+		t.Errorf("Results 1-0 and *: counted %d games, want 2", builder.Games)
+	}
+	if stats := moveStats(t, builder, playMoves(t), "c2c4"); stats == nil || stats.Draws != 1 {
+		t.Errorf("Results 1-0 and *: the unfinished game should count as a draw")
+	}
+
+	builder = NewBuilder()
+	builder.MinGames = 2
+	buildBook(t, builder)
+	entries := builder.Entries()
+	if len(entries) != 1 || entries[0].Key != Key(playMoves(t)) {
+		t.Fatalf("MinGames 2: got %d entries, want only e4", len(entries))
+	}
+}
+
+func TestBuilderWrite(t *testing.T) {
+	builder := NewBuilder()
+	buildBook(t, builder)
+	entries := builder.Entries()
+
+	var buf bytes.Buffer
+	if err := Write(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != len(entries)*ENTRY_SIZE {
+		t.Fatalf("wrote %d bytes for %d entries", buf.Len(), len(entries))
+	}
+	book, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// e4 won once and lost once, d4 was drawn, so e4 has the higher weight
+	start := playMoves(t)
+	moves := book.Moves(start)
+	if len(moves) != 2 || moves[0].Move.String() != "e2e4" || moves[0].Weight != 2 || moves[1].Weight != 1 {
+		t.Errorf("start position moves: %v", moves)
+	}
+	// e5 only lost, so it has no weight and is left out
+	for _, move := range book.Moves(playMoves(t, "e2e4")) {
+		if move.Move.String() == "e7e5" {
+			t.Errorf("a move that only lost is in the book")
+		}
+	}
+	best, err := book.BestMove(playMoves(t, "e2e4", "e7e5"))
+	if err != nil || best.String() != "g1f3" {
+		t.Errorf("best move after 1. e4 e5 is %s (%v), want g1f3", best.String(), err)
+	}
+}
+
+func TestBuilderDump(t *testing.T) {
+	builder := NewBuilder()
+	buildBook(t, builder)
+	var buf bytes.Buffer
+	if err := builder.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dump := buf.String()
+	for _, want := range []string{chess.START_FEN, "Nf3", "e4      games 2      +1 =0 -1  score 50.0%  weight 2"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump is missing %q:\n%s", want, dump)
+		}
+	}
+}
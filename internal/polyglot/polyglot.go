@@ -0,0 +1,130 @@
+// Package polyglot reads and builds opening books in the Polyglot .bin format.
+package polyglot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+/*
+	Polyglot books are the common format for opening books, most GUIs and engines can read them.
+	http://hgm.nubati.net/book_format.html
+	A book is a list of 16 byte entries sorted by key, all numbers big endian:
+		key    uint64  the position, a Zobrist hash made with the Random64 table
+		move   uint16  the move, see below
+		weight uint16  how often to play the move compared to the other moves for the position
+		learn  uint32  unused here
+	The key is a Zobrist hash like Board.Hash, but with the fixed random numbers every book shares, so it's worked out separately.
+	The en passant file only counts when a pawn of the side to move is next to the pawn that just moved two squares.
+
+	Moves pack the squares and promotion into 16 bits:
+		bits 0-2 to file, 3-5 to rank, 6-8 from file, 9-11 from rank, 12-14 promotion (1 knight up to 4 queen)
+	Castling is written as the king taking its own rook, e1h1 for white castling short, even in standard chess.
+*/
+
+var (
+	ErrBookSize  = errors.New("polyglot book size isn't a multiple of 16 bytes")
+	ErrBookMove  = errors.New("polyglot move isn't legal in the position")
+	ErrNoBookHit = errors.New("position isn't in the book")
+)
+
+// the offsets into Random64 after the pieces
+const (
+	randomCastle    = 768
+	randomEnPassant = 772
+	randomTurn      = 780
+)
+
+// Key returns the Polyglot key of the position
+func Key(board *chess.Board) uint64 {
+	var key uint64
+	for square, piece := range board.Mailbox {
+		if piece.IsNone() {
+			continue
+		}
+		// black pawn 0, white pawn 1, black knight 2 and so on
+		kind := 2 * int(piece.Type()-chess.PAWN)
+		if piece.Color() == chess.WHITE {
+			kind++
+		}
+		key ^= Random64[64*kind+square]
+	}
+
+	for i, right := range "KQkq" {
+		if strings.ContainsRune(board.WhiteCastleRights+board.BlackCastleRights, right) {
+			key ^= Random64[randomCastle+i]
+		}
+	}
+
+	if board.EnPassantSquare >= 0 && enPassantCapturable(board) {
+		key ^= Random64[randomEnPassant+board.EnPassantSquare%8]
+	}
+
+	if board.WhiteToMove {
+		key ^= Random64[randomTurn]
+	}
+	return key
+}
+
+// whether a pawn of the side to move stands next to the pawn that just moved two squares
+func enPassantCapturable(board *chess.Board) bool {
+	file := board.EnPassantSquare % 8
+	// the pawn that moved is one rank past the en passant square
+	pawnRank, ours := 4, chess.Piece(chess.PAWN|chess.WHITE)
+	if !board.WhiteToMove {
+		pawnRank, ours = 3, chess.Piece(chess.PAWN|chess.BLACK)
+	}
+	if file > 0 && board.Mailbox[pawnRank*8+file-1] == ours {
+		return true
+	}
+	return file < 7 && board.Mailbox[pawnRank*8+file+1] == ours
+}
+
+// EncodeMove returns the Polyglot encoding of a legal move in the position
+func EncodeMove(board *chess.Board, move chess.Move) uint16 {
+	source, target := move.Source(), move.Target()
+	if move.Flag() == chess.CASTLE_FLAG {
+		// the king takes its own rook, side 0 is short and 1 is long like Board.CastlingRooks
+		color, side := 0, 0
+		if !board.WhiteToMove {
+			color = 1
+		}
+		if target%8 < source%8 {
+			side = 1
+		}
+		target = board.CastlingRooks[color][side]
+	}
+	encoded := uint16(target%8) | uint16(target/8)<<3 | uint16(source%8)<<6 | uint16(source/8)<<9
+	if promotion := move.PromotionPiece(); promotion != chess.NONE {
+		// knight 1, bishop 2, rook 3, queen 4
+		encoded |= uint16(promotion-chess.PAWN) << 12
+	}
+	return encoded
+}
+
+// DecodeMove finds the legal move a Polyglot move stands for in the position
+func DecodeMove(board *chess.Board, encoded uint16) (chess.Move, error) {
+	var list chess.MoveList
+	board.GenerateLegalMovesInto(&list)
+	for _, move := range list.Slice() {
+		if EncodeMove(board, move) == encoded {
+			return move, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrBookMove, moveString(encoded))
+}
+
+// the move in UCI notation the way the book has it, castling included
+func moveString(encoded uint16) string {
+	square := func(file, rank uint16) string {
+		return string(rune('a'+file)) + string(rune('1'+rank))
+	}
+	s := square(encoded>>6&7, encoded>>9&7) + square(encoded&7, encoded>>3&7)
+	if promotion := encoded >> 12 & 7; promotion > 0 && promotion <= 4 {
+		s += string("nbrq"[promotion-1])
+	}
+	return s
+}
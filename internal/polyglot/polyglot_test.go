@@ -0,0 +1,157 @@
+package polyglot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+// plays UCI moves from the start position
+func playMoves(t *testing.T, moves ...string) *chess.Board {
+	t.Helper()
+	board, err := chess.ParseFEN(chess.START_FEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, uci := range moves {
+		move, err := board.ParseUCIMove(uci)
+		if err != nil {
+			t.Fatalf("%s: %v", uci, err)
+		}
+		board.MakeMove(move)
+	}
+	return board
+}
+
+// the keys from the Polyglot book format description
+func TestKey(t *testing.T) {
+	testCases := []struct {
+		moves []string
+		key   uint64
+	}{
+		{nil, 0x463b96181691fc9c},
+		{[]string{"e2e4"}, 0x823c9b50fd114196},
+		{[]string{"e2e4", "d7d5"}, 0x0756b94461c50fb0},
+		{[]string{"e2e4", "d7d5", "e4e5"}, 0x662fafb965db29d4},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5"}, 0x22a48b5a8e47ff78},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, 0x652a607ca3f242c1},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, 0x00fdd303c946bdd9},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, 0x3c8123ea7b067637},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, 0x5c3f9b829b279560},
+	}
+	for _, tc := range testCases {
+		if key := Key(playMoves(t, tc.moves...)); key != tc.key {
+			t.Errorf("%v: expected %016x, got %016x", tc.moves, tc.key, key)
+		}
+	}
+}
+
+func TestMoveEncoding(t *testing.T) {
+	board, err := chess.ParseFEN("r3k2r/1P6/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		uci  string
+		book string
+	}{
+		// castling is the king taking its rook
+		{"e1g1", "e1h1"},
+		{"e1c1", "e1a1"},
+		{"b7b8q", "b7b8q"},
+		{"b7a8n", "b7a8n"},
+		{"a1a8", "a1a8"},
+	}
+	for _, tc := range testCases {
+		move, err := board.ParseUCIMove(tc.uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := EncodeMove(board, move)
+		if moveString(encoded) != tc.book {
+			t.Errorf("%s: expected %s, got %s", tc.uci, tc.book, moveString(encoded))
+		}
+		decoded, err := DecodeMove(board, encoded)
+		if err != nil || decoded != move {
+			t.Errorf("%s: decoded to %s, %v", tc.uci, decoded.String(), err)
+		}
+	}
+	// e1h1 as a rook move isn't legal here
+	if _, err := DecodeMove(board, 7<<6|7<<3); !errors.Is(err, ErrBookMove) {
+		t.Errorf("Expected ErrBookMove, got %v", err)
+	}
+}
+
+// writes entries the way they're stored in a .bin file
+func bookBytes(entries ...Entry) []byte {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		binary.Write(&buf, binary.BigEndian, entry)
+	}
+	return buf.Bytes()
+}
+
+func encode(t *testing.T, board *chess.Board, uci string) uint16 {
+	t.Helper()
+	move, err := board.ParseUCIMove(uci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return EncodeMove(board, move)
+}
+
+func TestBook(t *testing.T) {
+	start := playMoves(t)
+	key := Key(start)
+	e4 := playMoves(t, "e2e4")
+	// out of order on purpose, with an illegal move and another position mixed in
+	data := bookBytes(
+		Entry{Key: key, Move: encode(t, start, "d2d4"), Weight: 30},
+		Entry{Key: Key(e4), Move: encode(t, e4, "c7c5"), Weight: 5},
+		Entry{Key: key, Move: encode(t, start, "e2e4"), Weight: 60},
+		Entry{Key: 1, Move: 0, Weight: 1},
+		Entry{Key: key, Move: encode(t, start, "g1f3"), Weight: 10},
+		Entry{Key: key, Move: 7<<6 | 7<<3, Weight: 100},
+	)
+	book, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Len() != 6 || len(book.Entries(key)) != 4 || len(book.Entries(2)) != 0 {
+		t.Fatalf("Unexpected entries %d %d", book.Len(), len(book.Entries(key)))
+	}
+	if moves := book.Moves(start); len(moves) != 3 {
+		t.Errorf("Expected 3 legal book moves, got %v", moves)
+	}
+	if best, err := book.BestMove(start); err != nil || best.String() != "e2e4" {
+		t.Errorf("Expected e2e4, got %s %v", best.String(), err)
+	}
+	if best, err := book.BestMove(e4); err != nil || best.String() != "c7c5" {
+		t.Errorf("Expected c7c5, got %s %v", best.String(), err)
+	}
+	if _, err := book.BestMove(playMoves(t, "a2a3")); !errors.Is(err, ErrNoBookHit) {
+		t.Errorf("Expected ErrNoBookHit, got %v", err)
+	}
+
+	// picks follow the weights
+	rng := rand.New(rand.NewSource(1))
+	counts := map[string]int{}
+	for range 10000 {
+		move, err := book.WeightedMove(start, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[move.String()]++
+	}
+	if counts["e2e4"] < 5500 || counts["e2e4"] > 6500 || counts["d2d4"] < 2500 || counts["d2d4"] > 3500 || counts["g1f3"] < 700 || counts["g1f3"] > 1300 {
+		t.Errorf("Picks don't follow the weights: %v", counts)
+	}
+
+	if _, err := Read(bytes.NewReader(data[:20])); !errors.Is(err, ErrBookSize) {
+		t.Errorf("Expected ErrBookSize, got %v", err)
+	}
+}
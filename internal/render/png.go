@@ -0,0 +1,131 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// PNG writes the diagram as a PNG image
+func PNG(w io.Writer, board *chess.Board, opts Options) error {
+	img, err := Image(board, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// Image draws the diagram
+func Image(board *chess.Board, opts Options) (*image.RGBA, error) {
+	squareSize, err := opts.squareSize()
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, squareSize*8, squareSize*8))
+
+	for square := range 64 {
+		x, y := opts.squareOrigin(square, squareSize)
+		rect := image.Rect(x, y, x+squareSize, y+squareSize)
+		draw.Draw(img, rect, image.NewUniform(squareColor(square)), image.Point{}, draw.Src)
+	}
+	for _, square := range opts.highlighted() {
+		x, y := opts.squareOrigin(square, squareSize)
+		rect := image.Rect(x, y, x+squareSize, y+squareSize)
+		draw.Draw(img, rect, image.NewUniform(LastMoveColor), image.Point{}, draw.Over)
+	}
+	if opts.Coordinates {
+		if err := drawLabels(img, opts.labels(squareSize), squareSize); err != nil {
+			return nil, err
+		}
+	}
+
+	for square := range 64 {
+		piece := board.GetPieceAtIndex(square)
+		if piece.IsNone() {
+			continue
+		}
+		pieceImg, err := PieceImage(piece, squareSize)
+		if err != nil {
+			return nil, err
+		}
+		x, y := opts.squareOrigin(square, squareSize)
+		rect := image.Rect(x, y, x+squareSize, y+squareSize)
+		draw.Draw(img, rect, pieceImg, image.Point{}, draw.Over)
+	}
+
+	// arrows go over the pieces so they can't be hidden
+	bounds := img.Bounds()
+	for _, arrow := range opts.Arrows {
+		scanner := rasterx.NewScannerGV(bounds.Dx(), bounds.Dy(), img, bounds)
+		if arrow.From == arrow.To {
+			middle, outer, inner := opts.circle(arrow.From, squareSize)
+			stroker := rasterx.NewDasher(bounds.Dx(), bounds.Dy(), scanner)
+			width := fixed.Int26_6((outer - inner) * 64)
+			stroker.SetStroke(width, 0, nil, nil, rasterx.RoundGap, rasterx.Round, nil, 0)
+			stroker.SetColor(arrowColor(arrow))
+			rasterx.AddCircle(middle.x, middle.y, (outer+inner)/2, stroker)
+			stroker.Draw()
+			continue
+		}
+		filler := rasterx.NewFiller(bounds.Dx(), bounds.Dy(), scanner)
+		filler.SetColor(arrowColor(arrow))
+		outline := opts.arrowOutline(arrow, squareSize)
+		filler.Start(rasterx.ToFixedP(outline[0].x, outline[0].y))
+		for _, p := range outline[1:] {
+			filler.Line(rasterx.ToFixedP(p.x, p.y))
+		}
+		filler.Stop(true)
+		filler.Draw()
+	}
+	return img, nil
+}
+
+// PieceImage draws a piece to fill a square of the given size in pixels
+func PieceImage(piece chess.Piece, size int) (*image.RGBA, error) {
+	svg, err := pieceSVG(piece)
+	if err != nil {
+		return nil, err
+	}
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(svg))
+	if err != nil {
+		return nil, err
+	}
+	icon.SetTarget(0, 0, float64(size), float64(size))
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(size, size, scanner), 1.0)
+	return img, nil
+}
+
+func drawLabels(img *image.RGBA, labels []label, squareSize int) error {
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return err
+	}
+	face, err := opentype.NewFace(bold, &opentype.FaceOptions{Size: labelSize(squareSize), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+	for _, l := range labels {
+		drawer := font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(color.Color(labelColor(l))),
+			Face: face,
+			Dot:  fixed.P(int(l.x), int(l.y)),
+		}
+		drawer.DrawString(l.text)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Package render draws board diagrams as PNG or SVG without a display, for reports, docs and CI.
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/jgerontis/go-chess/assets"
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+/*
+	Both formats draw the same diagram:
+	- light and dark squares in the GUI's colors, white at the bottom unless Orientation is black
+	- the squares of the last move highlighted
+	- the pieces from assets/images, the same SVGs the GUI uses
+	- arrows from square to square, or a circle when an arrow starts and ends on the same square
+	- optionally the file letters along the bottom edge and the rank numbers along the left edge, inside the squares
+	All the geometry is worked out in pixels here, png.go and svg.go only draw it.
+*/
+
+// DEFAULT_SIZE is the width and height of a diagram in pixels when Options.Size isn't set
+const DEFAULT_SIZE = 400
+
+var (
+	ErrSize   = errors.New("diagram too small")
+	ErrSquare = errors.New("square off the board")
+)
+
+var (
+	LightSquare   = color.RGBA{240, 217, 181, 255}
+	DarkSquare    = color.RGBA{181, 136, 99, 255}
+	LastMoveColor = color.NRGBA{205, 210, 50, 130}
+	ArrowColor    = color.NRGBA{21, 120, 27, 170}
+)
+
+// Arrow points from one square to another, a circle if they're the same square
+type Arrow struct {
+	From, To int
+	// ArrowColor if nil
+	Color color.Color
+}
+
+// Options are everything a diagram can show besides the pieces
+type Options struct {
+	// width and height in pixels, rounded down to a multiple of 8. DEFAULT_SIZE if 0.
+	Size int
+	// the color at the bottom, chess.WHITE unless it's chess.BLACK
+	Orientation byte
+	// the move to highlight, nothing if 0
+	LastMove    chess.Move
+	Arrows      []Arrow
+	Coordinates bool
+}
+
+// the size of a square in pixels, after checking the options make sense
+func (o *Options) squareSize() (int, error) {
+	size := o.Size
+	if size == 0 {
+		size = DEFAULT_SIZE
+	}
+	if size < 8 {
+		return 0, fmt.Errorf("%w: %d pixels", ErrSize, size)
+	}
+	for _, arrow := range o.Arrows {
+		if arrow.From < 0 || arrow.From > 63 || arrow.To < 0 || arrow.To > 63 {
+			return 0, fmt.Errorf("%w: arrow from %d to %d", ErrSquare, arrow.From, arrow.To)
+		}
+	}
+	return size / 8, nil
+}
+
+// the top left corner of a square in the diagram
+func (o *Options) squareOrigin(square, squareSize int) (int, int) {
+	file, rank := square%8, square/8
+	if o.Orientation == chess.BLACK {
+		return (7 - file) * squareSize, rank * squareSize
+	}
+	return file * squareSize, (7 - rank) * squareSize
+}
+
+// the squares of the last move, none if there isn't one
+func (o *Options) highlighted() []int {
+	if o.LastMove == 0 {
+		return nil
+	}
+	return []int{o.LastMove.Source(), o.LastMove.Target()}
+}
+
+func squareColor(square int) color.RGBA {
+	if (square/8+square%8)%2 == 0 {
+		return DarkSquare
+	}
+	return LightSquare
+}
+
+func arrowColor(arrow Arrow) color.Color {
+	if arrow.Color == nil {
+		return ArrowColor
+	}
+	return arrow.Color
+}
+
+type point struct {
+	x, y float64
+}
+
+// the outline of an arrow from the middle of one square to the tip at the middle of the other
+func (o *Options) arrowOutline(arrow Arrow, squareSize int) []point {
+	s := float64(squareSize)
+	fromX, fromY := o.squareOrigin(arrow.From, squareSize)
+	toX, toY := o.squareOrigin(arrow.To, squareSize)
+	start := point{float64(fromX) + s/2, float64(fromY) + s/2}
+	tip := point{float64(toX) + s/2, float64(toY) + s/2}
+
+	length := math.Hypot(tip.x-start.x, tip.y-start.y)
+	// unit vectors along the arrow and across it
+	along := point{(tip.x - start.x) / length, (tip.y - start.y) / length}
+	across := point{-along.y, along.x}
+	shaft, head, headLength := s/12, s/4, s/2.5
+	at := func(distance, offset float64) point {
+		return point{start.x + along.x*distance + across.x*offset, start.y + along.y*distance + across.y*offset}
+	}
+	neck := length - headLength
+	return []point{
+		at(0, -shaft), at(neck, -shaft), at(neck, -head), at(length, 0), at(neck, head), at(neck, shaft), at(0, shaft),
+	}
+}
+
+// the middle and the outer and inner radius of the ring drawn for an arrow to its own square
+func (o *Options) circle(square, squareSize int) (point, float64, float64) {
+	x, y := o.squareOrigin(square, squareSize)
+	s := float64(squareSize)
+	return point{float64(x) + s/2, float64(y) + s/2}, s * 0.47, s * 0.39
+}
+
+// a coordinate label and where its text starts, the baseline of the text is at y
+type label struct {
+	text string
+	x, y float64
+	// the label is on a dark square
+	dark bool
+}
+
+// the file letters along the bottom and rank numbers down the left side of the diagram
+func (o *Options) labels(squareSize int) []label {
+	s := float64(squareSize)
+	pad := s / 16
+	fontSize := labelSize(squareSize)
+	var labels []label
+	for i := range 8 {
+		// the square in the bottom row and the left column
+		bottom, left := i, i*8
+		if o.Orientation == chess.BLACK {
+			bottom, left = 63-i, 63-i*8
+		}
+		x, y := o.squareOrigin(bottom, squareSize)
+		labels = append(labels, label{
+			text: string(rune('a' + bottom%8)),
+			x:    float64(x) + s - pad - fontSize*0.55,
+			y:    float64(y) + s - pad,
+			dark: squareColor(bottom) == DarkSquare,
+		})
+		x, y = o.squareOrigin(left, squareSize)
+		labels = append(labels, label{
+			text: string(rune('1' + left/8)),
+			x:    float64(x) + pad,
+			y:    float64(y) + pad + fontSize,
+			dark: squareColor(left) == DarkSquare,
+		})
+	}
+	return labels
+}
+
+// the font size of the coordinates in pixels
+func labelSize(squareSize int) float64 {
+	return float64(squareSize) / 5
+}
+
+// the text color that shows up on a square, the other square color
+func labelColor(l label) color.RGBA {
+	if l.dark {
+		return LightSquare
+	}
+	return DarkSquare
+}
+
+// the piece's SVG from the assets, like images/wn.svg for the white knight
+func pieceSVG(piece chess.Piece) ([]byte, error) {
+	prefix := "b"
+	if piece.Color() == chess.WHITE {
+		prefix = "w"
+	}
+	return assets.Images.ReadFile("images/" + prefix + strings.ToLower(piece.FenChar()) + ".svg")
+}
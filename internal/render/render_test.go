@@ -0,0 +1,159 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+func startBoard(t *testing.T) *chess.Board {
+	t.Helper()
+	board, err := chess.ParseFEN(chess.START_FEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return board
+}
+
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+func TestPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PNG(&buf, startBoard(t), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Couldn't decode the PNG: %v", err)
+	}
+	if img.Bounds().Dx() != DEFAULT_SIZE || img.Bounds().Dy() != DEFAULT_SIZE {
+		t.Errorf("Expected %dx%d, got %v", DEFAULT_SIZE, DEFAULT_SIZE, img.Bounds())
+	}
+}
+
+func TestImage(t *testing.T) {
+	board := startBoard(t)
+	img, err := Image(board, Options{Size: 160})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a1 is dark and in the bottom left corner, e4 is empty and light
+	if !sameColor(img.At(1, 158), DarkSquare) {
+		t.Errorf("Expected a dark square at the bottom left, got %v", img.At(1, 158))
+	}
+	if !sameColor(img.At(90, 90), LightSquare) {
+		t.Errorf("Expected e4 to be an empty light square, got %v", img.At(90, 90))
+	}
+	// the white king is drawn on e1
+	if sameColor(img.At(90, 150), LightSquare) {
+		t.Error("Expected the king on e1")
+	}
+
+	// with black at the bottom a1 is the top right corner
+	img, err = Image(board, Options{Size: 160, Orientation: chess.BLACK})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameColor(img.At(158, 1), DarkSquare) || !sameColor(img.At(1, 1), LightSquare) {
+		t.Errorf("Expected a1 at the top right, got %v and %v", img.At(158, 1), img.At(1, 1))
+	}
+
+	// the squares of the last move are highlighted and arrows are drawn over the board
+	e2, e4 := chess.StringToSquare("e2"), chess.StringToSquare("e4")
+	opts := Options{
+		Size:     160,
+		LastMove: chess.NewMove(e2, e4, chess.PAWN_DOUBLE_FLAG),
+		Arrows:   []Arrow{{From: chess.StringToSquare("a3"), To: chess.StringToSquare("h3"), Color: color.RGBA{255, 0, 0, 255}}},
+	}
+	img, err = Image(board, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sameColor(img.At(81, 81), LightSquare) {
+		t.Error("Expected e4 to be highlighted")
+	}
+	if !sameColor(img.At(70, 110), color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected the arrow across the third rank, got %v", img.At(70, 110))
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	board := startBoard(t)
+	if _, err := Image(board, Options{Size: 7}); !errors.Is(err, ErrSize) {
+		t.Errorf("Expected ErrSize, got %v", err)
+	}
+	if err := SVG(io.Discard, board, Options{Arrows: []Arrow{{From: 0, To: 64}}}); !errors.Is(err, ErrSquare) {
+		t.Errorf("Expected ErrSquare, got %v", err)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{
+		Coordinates: true,
+		LastMove:    chess.NewMove(chess.StringToSquare("g1"), chess.StringToSquare("f3"), chess.NO_FLAG),
+		Arrows:      []Arrow{{From: 12, To: 28}, {From: 36, To: 36}},
+	}
+	if err := SVG(&buf, startBoard(t), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// it has to be well formed XML, count the elements while checking
+	counts := map[string]int{}
+	ids := map[string]bool{}
+	decoder := xml.NewDecoder(strings.NewReader(buf.String()))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid SVG: %v\n%s", err, buf.String())
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "id" {
+					ids[attr.Value] = true
+				}
+				if attr.Name.Local == "class" {
+					counts[attr.Value]++
+				}
+			}
+		}
+	}
+	if counts["use"] != 32 || len(ids) != 12 {
+		t.Errorf("Expected 32 pieces from 12 definitions, got %d and %v", counts["use"], ids)
+	}
+	if counts["last-move"] != 2 || counts["text"] != 16 || counts["arrow"] != 2 {
+		t.Errorf("Unexpected elements %v", counts)
+	}
+	// the pieces have circles of their own, but no polygons
+	if counts["polygon"] != 1 {
+		t.Errorf("Expected one arrow, got %v", counts)
+	}
+}
+
+func TestPieceImage(t *testing.T) {
+	img, err := PieceImage(chess.Piece(chess.QUEEN|chess.BLACK), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Error("Expected the corner to be transparent")
+	}
+	if _, _, _, a := img.At(32, 40).RGBA(); a == 0 {
+		t.Error("Expected the queen in the middle")
+	}
+}
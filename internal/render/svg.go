@@ -0,0 +1,134 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+// the piece SVGs are drawn on a 45 by 45 grid
+const pieceViewBox = 45
+
+var svgComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+// SVG writes the diagram as a standalone SVG document.
+// Each piece that's on the board is defined once and placed with <use>.
+func SVG(w io.Writer, board *chess.Board, opts Options) error {
+	squareSize, err := opts.squareSize()
+	if err != nil {
+		return err
+	}
+	size := squareSize * 8
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", size, size, size, size)
+
+	// the pieces on the board, in the order they're first found
+	defs := map[string]bool{}
+	sb.WriteString("<defs>\n")
+	for square := range 64 {
+		piece := board.GetPieceAtIndex(square)
+		if piece.IsNone() || defs[pieceID(piece)] {
+			continue
+		}
+		defs[pieceID(piece)] = true
+		body, err := pieceBody(piece)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "<g id=\"%s\">%s</g>\n", pieceID(piece), body)
+	}
+	sb.WriteString("</defs>\n")
+
+	for square := range 64 {
+		x, y := opts.squareOrigin(square, squareSize)
+		fmt.Fprintf(&sb, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" %s/>\n", x, y, squareSize, squareSize, fill(squareColor(square)))
+	}
+	for _, square := range opts.highlighted() {
+		x, y := opts.squareOrigin(square, squareSize)
+		fmt.Fprintf(&sb, "<rect class=\"last-move\" x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" %s/>\n", x, y, squareSize, squareSize, fill(LastMoveColor))
+	}
+	if opts.Coordinates {
+		for _, l := range opts.labels(squareSize) {
+			fmt.Fprintf(&sb, "<text x=\"%s\" y=\"%s\" font-family=\"sans-serif\" font-weight=\"bold\" font-size=\"%s\" %s>%s</text>\n",
+				number(l.x), number(l.y), number(labelSize(squareSize)), fill(labelColor(l)), l.text)
+		}
+	}
+
+	scale := number(float64(squareSize) / pieceViewBox)
+	for square := range 64 {
+		piece := board.GetPieceAtIndex(square)
+		if piece.IsNone() {
+			continue
+		}
+		x, y := opts.squareOrigin(square, squareSize)
+		fmt.Fprintf(&sb, "<use xlink:href=\"#%s\" transform=\"translate(%d %d) scale(%s)\"/>\n", pieceID(piece), x, y, scale)
+	}
+
+	for _, arrow := range opts.Arrows {
+		if arrow.From == arrow.To {
+			middle, outer, inner := opts.circle(arrow.From, squareSize)
+			fmt.Fprintf(&sb, "<circle class=\"arrow\" cx=\"%s\" cy=\"%s\" r=\"%s\" fill=\"none\" stroke-width=\"%s\" %s/>\n",
+				number(middle.x), number(middle.y), number((outer+inner)/2), number(outer-inner), paint("stroke", arrowColor(arrow)))
+			continue
+		}
+		var points []string
+		for _, p := range opts.arrowOutline(arrow, squareSize) {
+			points = append(points, number(p.x)+","+number(p.y))
+		}
+		fmt.Fprintf(&sb, "<polygon class=\"arrow\" points=\"%s\" %s/>\n", strings.Join(points, " "), fill(arrowColor(arrow)))
+	}
+	sb.WriteString("</svg>\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// the id of a piece's definition, the same as its file name like wn for the white knight
+func pieceID(piece chess.Piece) string {
+	if piece.Color() == chess.WHITE {
+		return "w" + strings.ToLower(piece.FenChar())
+	}
+	return "b" + strings.ToLower(piece.FenChar())
+}
+
+// what's inside the piece's <svg> element, without the comments
+func pieceBody(piece chess.Piece) (string, error) {
+	svg, err := pieceSVG(piece)
+	if err != nil {
+		return "", err
+	}
+	start := bytes.Index(svg, []byte("<svg"))
+	end := bytes.LastIndex(svg, []byte("</svg>"))
+	if start == -1 || end == -1 {
+		return "", fmt.Errorf("no <svg> element in the %s image", pieceID(piece))
+	}
+	open := bytes.IndexByte(svg[start:], '>')
+	body := svgComment.ReplaceAllString(string(svg[start+open+1:end]), "")
+	return strings.Join(strings.Fields(body), " "), nil
+}
+
+func fill(c color.Color) string {
+	return paint("fill", c)
+}
+
+// a fill or stroke attribute for a color, with its opacity if it isn't opaque
+func paint(attribute string, c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	s := fmt.Sprintf("%s=\"#%02x%02x%02x\"", attribute, n.R, n.G, n.B)
+	if n.A != 255 {
+		s += fmt.Sprintf(" %s-opacity=\"%s\"", attribute, number(float64(n.A)/255))
+	}
+	return s
+}
+
+// a coordinate with at most two decimals
+func number(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
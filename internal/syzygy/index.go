@@ -0,0 +1,312 @@
+package syzygy
+
+import (
+	"slices"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+/*
+	A position is turned into an index into its table by placing the pieces group by group.
+	The files decide the groups and their order, see setGroups in table.go.
+	Symmetry keeps the tables small: without pawns the first piece is mirrored into the a1-d1-d4 triangle,
+	with pawns the leading pawn is mirrored onto files a-d and each of those files has its own table.
+	The lookup tables below are the same ones Stockfish builds in Tablebases::init.
+*/
+
+var (
+	// squares a2-h7 to 0-47, the higher the number the nearer the edge and the lower the rank
+	mapPawns [64]int
+	// squares below the a1-h8 diagonal to 0-27
+	mapB1H1H7 [64]int
+	// the a1-d1-d4 triangle to 0-9, with the diagonal squares last
+	mapA1D1D4 [64]int
+	// the 462 ways to place two kings, the first in the a1-d1-d4 triangle
+	mapKK [10][64]int
+	// binomial[k][n] is n choose k
+	binomial [7][64]uint64
+	// the index of the leading pawn group by how many pawns are in it and where the leading one is
+	leadPawnIdx [6][64]uint64
+	// how many ways to place a leading pawn group with its leading pawn on each of files a-d
+	leadPawnsSize [6][4]uint64
+)
+
+func init() {
+	code := 0
+	for square := range 64 {
+		if offDiagonal(square) < 0 {
+			mapB1H1H7[square] = code
+			code++
+		}
+	}
+
+	var diagonal []int
+	code = 0
+	for square := 0; square <= 27; square++ {
+		if square%8 > 3 {
+			continue
+		}
+		if offDiagonal(square) < 0 {
+			mapA1D1D4[square] = code
+			code++
+		} else if offDiagonal(square) == 0 {
+			diagonal = append(diagonal, square)
+		}
+	}
+	for _, square := range diagonal {
+		mapA1D1D4[square] = code
+		code++
+	}
+
+	type kings struct{ idx, square int }
+	var bothOnDiagonal []kings
+	code = 0
+	for idx := range 10 {
+		for s1 := 0; s1 <= 27; s1++ {
+			// b1 is 0 and so is every square outside the triangle
+			if mapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) || s1%8 > 3 {
+				continue
+			}
+			for s2 := range 64 {
+				switch {
+				case s1 == s2 || chess.KingMasks[s1].Occupied(s2):
+					// the kings can't touch
+				case offDiagonal(s1) == 0 && offDiagonal(s2) > 0:
+					// the first king is on the diagonal, the second can't be above it
+				case offDiagonal(s1) == 0 && offDiagonal(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, kings{idx, s2})
+				default:
+					mapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, k := range bothOnDiagonal {
+		mapKK[k.idx][k.square] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < len(binomial) && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	// 47 squares are left for the other pawns when the leading pawn is on a2, 2 fewer for each rank it moves up
+	available := 47
+	for count := 1; count < len(leadPawnIdx); count++ {
+		for file := range 4 {
+			var idx uint64
+			for rank := 1; rank <= 6; rank++ {
+				square := rank*8 + file
+				if count == 1 {
+					mapPawns[square] = available
+					available--
+					mapPawns[square^7] = available
+					available--
+				}
+				leadPawnIdx[count][square] = idx
+				idx += binomial[count-1][mapPawns[square]]
+			}
+			leadPawnsSize[count][file] = idx
+		}
+	}
+}
+
+// how far a square is above the a1-h8 diagonal, negative below it
+func offDiagonal(square int) int {
+	return square/8 - square%8
+}
+
+// the piece numbers the files use, 1-6 for white pawn to king and 9-14 for black
+func tablePiece(piece chess.Piece) byte {
+	if piece.Color() == chess.BLACK {
+		return piece.Type() | 8
+	}
+	return piece.Type()
+}
+
+// the lead pawn is the one with the highest mapPawns
+func comparePawns(a, b int) int {
+	return mapPawns[a] - mapPawns[b]
+}
+
+// the result of index, which of the file's tables to look in and where
+type position struct {
+	// the side to move in the table, 0 white and 1 black
+	side int
+	// the leading pawn's file, 0 without pawns
+	file int
+	idx  uint64
+}
+
+// works out where a position is in a table, errChangeSide if it's a DTZ table that only has the other side to move
+func (t *table) index(board *chess.Board) (position, error) {
+	var squares [MAX_PIECES]int
+	var pieces [MAX_PIECES]byte
+	size, leadPawnsCount := 0, 0
+	var leadPawns chess.Bitboard
+
+	// the files have the stronger side as white, and only white to move when both sides have the same pieces.
+	// Otherwise the colors swap and the board flips top to bottom.
+	symmetricBlackToMove := t.key == t.key2 && !board.WhiteToMove
+	blackStronger := boardKey(board) != t.key
+	flip := symmetricBlackToMove || blackStronger
+	var flipColor byte
+	flipSquares := 0
+	side := 1
+	if board.WhiteToMove {
+		side = 0
+	}
+	if flip {
+		flipColor, flipSquares = 8, 56
+		side ^= 1
+	}
+
+	file := 0
+	if t.hasPawns {
+		// the leading pawns come first in every file's piece order, so any of them has their color
+		leadColor := chess.WHITE_INDEX
+		if t.pairs[0][0].pieces[0]^flipColor == chess.PAWN|8 {
+			leadColor = chess.BLACK_INDEX
+		}
+		leadPawns = board.Pieces[leadColor][chess.PAWN]
+		for pawns := leadPawns; pawns != 0; {
+			squares[size] = pawns.PopLSB() ^ flipSquares
+			size++
+		}
+		leadPawnsCount = size
+		lead := 0
+		for i := 1; i < leadPawnsCount; i++ {
+			if comparePawns(squares[i], squares[lead]) > 0 {
+				lead = i
+			}
+		}
+		squares[0], squares[lead] = squares[lead], squares[0]
+		file = min(squares[0]%8, 7-squares[0]%8)
+	}
+
+	if t.dtz && !t.hasSide(side, file) {
+		return position{}, errChangeSide
+	}
+
+	for rest := board.Occupancy() &^ leadPawns; rest != 0; {
+		square := rest.PopLSB()
+		squares[size] = square ^ flipSquares
+		pieces[size] = tablePiece(board.Mailbox[square]) ^ flipColor
+		size++
+	}
+
+	d := t.pairsFor(side, file)
+	// put the pieces in the file's order, the one that compresses best
+	for i := leadPawnsCount; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// mirror the leading piece onto files a-d
+	if squares[0]%8 > 3 {
+		for i := range size {
+			squares[i] ^= 7
+		}
+	}
+
+	var idx uint64
+	if t.hasPawns {
+		idx = leadPawnIdx[leadPawnsCount][squares[0]]
+		slices.SortStableFunc(squares[1:leadPawnsCount], comparePawns)
+		for i := 1; i < leadPawnsCount; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		idx = t.leadingPieces(d, squares[:size])
+	}
+
+	idx *= d.groupIdx[0]
+	groupStart := d.groupLen[0]
+	// the other side's pawns, if any, come right after the leading pawns and are on ranks 2-7
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[groupStart : groupStart+d.groupLen[next]]
+		slices.Sort(group)
+		var n uint64
+		for i, square := range group {
+			// squares taken by earlier groups don't count
+			adjust := 0
+			for _, earlier := range squares[:groupStart] {
+				if square > earlier {
+					adjust++
+				}
+			}
+			if remainingPawns {
+				adjust += 8
+			}
+			n += binomial[i+1][square-adjust]
+		}
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		groupStart += d.groupLen[next]
+	}
+	return position{side: side, file: file, idx: idx}, nil
+}
+
+// the index of the leading group of a table without pawns, squares are changed by the mirroring
+func (t *table) leadingPieces(d *pairsData, squares []int) uint64 {
+	// mirror the leading piece onto ranks 1-4
+	if squares[0]/8 > 3 {
+		for i := range squares {
+			squares[i] ^= 56
+		}
+	}
+	// the first piece of the group off the a1-h8 diagonal has to be below it
+	for i := range d.groupLen[0] {
+		if offDiagonal(squares[i]) == 0 {
+			continue
+		}
+		if offDiagonal(squares[i]) > 0 {
+			for j := i; j < len(squares); j++ {
+				squares[j] = (squares[j]>>3 | squares[j]<<3) & 63
+			}
+		}
+		break
+	}
+
+	if !t.hasUniquePieces {
+		// just the two kings
+		return uint64(mapKK[mapA1D1D4[squares[0]]][squares[1]])
+	}
+
+	// three pieces, each one after the first skips the squares taken before it
+	adjust1, adjust2 := 0, 0
+	if squares[1] > squares[0] {
+		adjust1 = 1
+	}
+	if squares[2] > squares[0] {
+		adjust2++
+	}
+	if squares[2] > squares[1] {
+		adjust2++
+	}
+	rank0, rank1, rank2 := squares[0]/8, squares[1]/8, squares[2]/8
+	switch {
+	case offDiagonal(squares[0]) != 0:
+		return uint64((mapA1D1D4[squares[0]]*63+squares[1]-adjust1)*62 + squares[2] - adjust2)
+	case offDiagonal(squares[1]) != 0:
+		return uint64((6*63+rank0*28+mapB1H1H7[squares[1]])*62 + squares[2] - adjust2)
+	case offDiagonal(squares[2]) != 0:
+		return uint64(6*63*62 + 4*28*62 + rank0*7*28 + (rank1-adjust1)*28 + mapB1H1H7[squares[2]])
+	}
+	return uint64(6*63*62 + 4*28*62 + 4*7*28 + rank0*7*6 + (rank1-adjust1)*6 + rank2 - adjust2)
+}
@@ -0,0 +1,330 @@
+package syzygy
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+/*
+	The files don't store positions where the side to move can capture en passant, and they store
+	"don't care" values where a capture or pawn move is best, which compresses better.
+	So probing first tries every capture, and for DTZ every pawn move too, and only trusts the file when none of them is as good.
+	A DTZ file also only has one side to move, for the other side probing looks one move ahead.
+*/
+
+// RootMove is a legal move with the tablebase result after it, from the point of view of the side playing it
+type RootMove struct {
+	Move chess.Move
+	WDL  WDL
+	// plies from before the move to the next capture or pawn move on the way to the result,
+	// positive when winning and negative when losing, 0 for a draw.
+	// Cursed wins and blessed losses are 100 further away.
+	DTZ int
+	// higher is better, wins that beat the 50 move rule are MAX_RANK and losses that can't be dragged past it are -MAX_RANK
+	Rank int
+}
+
+// MAX_RANK is far beyond any distance to zeroing plus the halfmove clock, even in 7 piece tables,
+// so a cursed win always ranks above a draw and a blessed loss below it
+const MAX_RANK = 1 << 18
+
+// ProbeWDL returns the win/draw/loss result of a position for the side to move
+func (tb *Tablebase) ProbeWDL(board *chess.Board) (WDL, error) {
+	b, err := tb.probeable(board)
+	if err != nil {
+		return DRAW, err
+	}
+	wdl, _, err := tb.search(b, false)
+	return wdl, err
+}
+
+// ProbeDTZ returns the distance to zeroing of a position for the side to move, signed and counted like RootMove.DTZ.
+// A mated side gets -1.
+func (tb *Tablebase) ProbeDTZ(board *chess.Board) (int, error) {
+	b, err := tb.probeable(board)
+	if err != nil {
+		return 0, err
+	}
+	return tb.probeDTZ(b)
+}
+
+// RootMoves returns every legal move with its result, the best first.
+// Winning moves are sorted quickest first and losing moves longest first, so playing the first move always makes progress.
+// Repetitions aren't known to the board, so the ranks assume the position hasn't repeated since the last zeroing move.
+func (tb *Tablebase) RootMoves(board *chess.Board) ([]RootMove, error) {
+	b, err := tb.probeable(board)
+	if err != nil {
+		return nil, err
+	}
+	halfMoves := b.HalfMoves
+
+	var list chess.MoveList
+	b.GenerateLegalMovesInto(&list)
+	var moves []RootMove
+	for _, move := range list.Slice() {
+		zeroing := isZeroing(b, move)
+		state := b.MakeMove(move)
+		wdl, _, err := tb.search(b, false)
+		wdl = -wdl
+		dtz := dtzBeforeZeroing(wdl)
+		if err == nil && !zeroing {
+			// the position after the move is one ply further from zeroing
+			dtz, err = tb.probeDTZ(b)
+			dtz = -dtz
+			dtz += sign(dtz)
+		}
+		// a mating move is 1 ply from the end
+		if err == nil && dtz == 2 && b.Checkers() != 0 && !hasLegalMoves(b) {
+			dtz = 1
+		}
+		b.UnmakeMove(move, state)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, RootMove{Move: move, WDL: wdl, DTZ: dtz, Rank: rank(dtz, halfMoves)})
+	}
+	slices.SortStableFunc(moves, func(x, y RootMove) int {
+		if x.Rank != y.Rank {
+			return cmp.Compare(y.Rank, x.Rank)
+		}
+		return cmp.Compare(x.DTZ, y.DTZ)
+	})
+	return moves, nil
+}
+
+// RootMovesWDL returns every legal move with its result, the best first, from the WDL tables alone.
+// It's for when the DTZ tables are missing: every move in the best group keeps the result,
+// but nothing says which of them makes progress, so a search still has to choose. DTZ is left at 0.
+func (tb *Tablebase) RootMovesWDL(board *chess.Board) ([]RootMove, error) {
+	b, err := tb.probeable(board)
+	if err != nil {
+		return nil, err
+	}
+	var list chess.MoveList
+	b.GenerateLegalMovesInto(&list)
+	var moves []RootMove
+	for _, move := range list.Slice() {
+		state := b.MakeMove(move)
+		wdl, _, err := tb.search(b, false)
+		b.UnmakeMove(move, state)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, RootMove{Move: move, WDL: -wdl, Rank: wdlRank[-wdl+2]})
+	}
+	slices.SortStableFunc(moves, func(x, y RootMove) int {
+		return cmp.Compare(y.Rank, x.Rank)
+	})
+	return moves, nil
+}
+
+// the rank of each result by WDL+2 when there's no DTZ, cursed wins and blessed losses as if the 50 move rule was close
+var wdlRank = [5]int{-MAX_RANK, -MAX_RANK + 101, 0, MAX_RANK - 101, MAX_RANK}
+
+// how good a move is with the 50 move rule counted, like tbRank in Stockfish
+func rank(dtz, halfMoves int) int {
+	switch {
+	case dtz > 0 && dtz+halfMoves <= 99:
+		return MAX_RANK
+	case dtz > 0:
+		return MAX_RANK - (dtz + halfMoves)
+	case dtz < 0 && -dtz*2+halfMoves < 100:
+		return -MAX_RANK
+	case dtz < 0:
+		return -MAX_RANK + (-dtz + halfMoves)
+	}
+	return 0
+}
+
+// checks the position is one the tablebases have and returns a copy to make moves on
+func (tb *Tablebase) probeable(board *chess.Board) (*chess.Board, error) {
+	if board.WhiteCastleRights != "" || board.BlackCastleRights != "" {
+		return nil, ErrCastling
+	}
+	if pieces := board.Occupancy().Count(); pieces > tb.maxPieces {
+		return nil, fmt.Errorf("%w: %d pieces, the tables have up to %d", ErrTooManyPieces, pieces, tb.maxPieces)
+	}
+	b := *board
+	b.LegalMoves = nil
+	return &b, nil
+}
+
+// looks a position up in its table, DRAW for two bare kings
+func (tb *Tablebase) probeTable(board *chess.Board, dtz bool, wdl WDL) (int, error) {
+	key := boardKey(board)
+	if key == "KvK" {
+		return int(DRAW), nil
+	}
+	tables := tb.tables[key]
+	var t *table
+	if tables != nil {
+		t = tables.wdl
+		if dtz {
+			t = tables.dtz
+		}
+	}
+	if t == nil {
+		kind := "WDL"
+		if dtz {
+			kind = "DTZ"
+		}
+		return 0, fmt.Errorf("%w: %s %s", ErrMissingTable, kind, key)
+	}
+	if err := t.open(); err != nil {
+		return 0, err
+	}
+
+	pos, err := t.index(board)
+	if err != nil {
+		return 0, err
+	}
+	d := t.pairsFor(pos.side, pos.file)
+	value, err := d.decompress(t.file, pos.idx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", t.path, err)
+	}
+	if !dtz {
+		return value - 2, nil
+	}
+	return dtzPlies(d, value, wdl)
+}
+
+// the WDL of a position with the captures tried first, and for DTZ the pawn moves too.
+// Also whether the best move is one of those, in which case the DTZ file doesn't have the right value.
+func (tb *Tablebase) search(board *chess.Board, pawnMoves bool) (WDL, bool, error) {
+	var list chess.MoveList
+	board.GenerateLegalMovesInto(&list)
+	best, tried := LOSS, 0
+	for _, move := range list.Slice() {
+		if !isCapture(board, move) && (!pawnMoves || board.Mailbox[move.Source()].Type() != chess.PAWN) {
+			continue
+		}
+		tried++
+		state := board.MakeMove(move)
+		value, _, err := tb.search(board, false)
+		board.UnmakeMove(move, state)
+		if err != nil {
+			return DRAW, false, err
+		}
+		value = -value
+		if value > best {
+			best = value
+			if value >= WIN {
+				return value, true, nil
+			}
+		}
+	}
+
+	// when every legal move was tried the file isn't needed, it could even be wrong with en passant
+	allTried := tried > 0 && tried == list.Len()
+	value := best
+	if !allTried {
+		stored, err := tb.probeTable(board, false, DRAW)
+		if err != nil {
+			return DRAW, false, err
+		}
+		value = WDL(stored)
+	}
+	if best >= value {
+		return best, best > DRAW || allTried, nil
+	}
+	return value, false, nil
+}
+
+func (tb *Tablebase) probeDTZ(board *chess.Board) (int, error) {
+	wdl, zeroingBest, err := tb.search(board, true)
+	if err != nil || wdl == DRAW {
+		// DTZ files don't have draws
+		return 0, err
+	}
+	if zeroingBest {
+		return dtzBeforeZeroing(wdl), nil
+	}
+
+	dtz, err := tb.probeTable(board, true, wdl)
+	if err == nil {
+		if wdl == CURSED_WIN || wdl == BLESSED_LOSS {
+			dtz += 100
+		}
+		return dtz * sign(int(wdl)), nil
+	}
+	if err != errChangeSide {
+		return 0, err
+	}
+
+	// the file has the other side to move, so find the best move by the DTZ after it
+	best := 0xffff
+	var list chess.MoveList
+	board.GenerateLegalMovesInto(&list)
+	for _, move := range list.Slice() {
+		zeroing := isZeroing(board, move)
+		state := board.MakeMove(move)
+		var dtz int
+		if zeroing {
+			// the DTZ from before the move, with the result after it
+			var value WDL
+			value, _, err = tb.search(board, false)
+			dtz = -dtzBeforeZeroing(value)
+		} else {
+			dtz, err = tb.probeDTZ(board)
+			dtz = -dtz
+		}
+		if err == nil && dtz == 1 && board.Checkers() != 0 && !hasLegalMoves(board) {
+			best = 1
+		}
+		board.UnmakeMove(move, state)
+		if err != nil {
+			return 0, err
+		}
+		if !zeroing {
+			dtz += sign(dtz)
+		}
+		// only moves that keep the result count
+		if dtz < best && sign(dtz) == sign(int(wdl)) {
+			best = dtz
+		}
+	}
+	// no legal moves is mate
+	if best == 0xffff {
+		return -1, nil
+	}
+	return best, nil
+}
+
+// the DTZ of the move before a capture or pawn move with this result after it
+func dtzBeforeZeroing(wdl WDL) int {
+	switch wdl {
+	case WIN:
+		return 1
+	case CURSED_WIN:
+		return 101
+	case BLESSED_LOSS:
+		return -101
+	case LOSS:
+		return -1
+	}
+	return 0
+}
+
+func sign(x int) int {
+	return cmp.Compare(x, 0)
+}
+
+func isCapture(board *chess.Board, move chess.Move) bool {
+	return move.Flag() == chess.EN_PASSANT_FLAG ||
+		(move.Flag() != chess.CASTLE_FLAG && !board.Mailbox[move.Target()].IsNone())
+}
+
+// captures and pawn moves reset the 50 move rule
+func isZeroing(board *chess.Board, move chess.Move) bool {
+	return isCapture(board, move) || board.Mailbox[move.Source()].Type() == chess.PAWN
+}
+
+func hasLegalMoves(board *chess.Board) bool {
+	var list chess.MoveList
+	board.GenerateLegalMovesInto(&list)
+	return list.Len() > 0
+}
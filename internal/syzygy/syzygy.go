@@ -0,0 +1,200 @@
+// Package syzygy probes Syzygy endgame tablebases for win/draw/loss and distance to zeroing results.
+package syzygy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+)
+
+/*
+	Syzygy tablebases hold every position with up to 7 pieces, kings included, one pair of files per material.
+	https://www.chessprogramming.org/Syzygy_Bases
+	The .rtbw files hold the win/draw/loss result for both sides to move, the .rtbz files hold the distance to zeroing,
+	the number of plies to the next capture or pawn move on the way to the result, for one side to move.
+	Neither has positions with castling rights, and en passant captures are left to the probing code,
+	so probing looks at captures one ply deep before reading the file.
+
+	This is a port of the probing code from Stockfish, tbprobe.cpp by Ronald de Man and the Stockfish developers.
+	The files are read with ReadAt instead of being mapped into memory, the headers are read the first time a table is used.
+	A Tablebase can be probed from several goroutines at once.
+*/
+
+// MAX_PIECES is the most pieces, kings included, any Syzygy table has
+const MAX_PIECES = 7
+
+// WDL is a win/draw/loss result for the side to move
+type WDL int8
+
+const (
+	// a loss that the 50 move rule turns into a draw
+	BLESSED_LOSS WDL = -1
+	LOSS         WDL = -2
+	DRAW         WDL = 0
+	// a win that the 50 move rule turns into a draw
+	CURSED_WIN WDL = 1
+	WIN        WDL = 2
+)
+
+func (w WDL) String() string {
+	switch w {
+	case LOSS:
+		return "loss"
+	case BLESSED_LOSS:
+		return "blessed loss"
+	case DRAW:
+		return "draw"
+	case CURSED_WIN:
+		return "cursed win"
+	case WIN:
+		return "win"
+	}
+	return fmt.Sprintf("WDL(%d)", int8(w))
+}
+
+var (
+	ErrNoTables      = errors.New("no tablebase files found")
+	ErrMissingTable  = errors.New("tablebase file missing")
+	ErrCastling      = errors.New("tablebases don't have positions with castling rights")
+	ErrTooManyPieces = errors.New("too many pieces for the tablebases")
+	ErrCorrupt       = errors.New("tablebase file is corrupt")
+)
+
+// Tablebase is a set of Syzygy files found in one or more directories
+type Tablebase struct {
+	// by material key, both the key with the stronger side white and the one with it black
+	tables    map[string]*tables
+	maxPieces int
+	// how many materials have a .rtbw and how many a .rtbz
+	wdlCount, dtzCount int
+}
+
+// the files for one material, either can be nil
+type tables struct {
+	wdl, dtz *table
+}
+
+// Open finds the tablebase files in a list of directories, separated like PATH is.
+// Files are only opened when a position needs them.
+func Open(path string) (*Tablebase, error) {
+	tb := &Tablebase{tables: make(map[string]*tables)}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			if entry.IsDir() || (ext != ".rtbw" && ext != ".rtbz") {
+				continue
+			}
+			code := strings.TrimSuffix(name, ext)
+			if !validCode(code) {
+				continue
+			}
+			tb.add(code, filepath.Join(dir, name), ext == ".rtbz")
+		}
+	}
+	if tb.wdlCount == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoTables, path)
+	}
+	return tb, nil
+}
+
+// adds a file, the first one found wins when a directory repeats a material
+func (tb *Tablebase) add(code, path string, dtz bool) {
+	key, key2 := materialKeys(code)
+	t := tb.tables[key]
+	if t == nil {
+		t = &tables{}
+		tb.tables[key] = t
+		tb.tables[key2] = t
+	}
+	if dtz {
+		if t.dtz == nil {
+			t.dtz = newTable(code, path, true)
+			tb.dtzCount++
+		}
+		return
+	}
+	if t.wdl == nil {
+		t.wdl = newTable(code, path, false)
+		tb.wdlCount++
+		tb.maxPieces = max(tb.maxPieces, len(code)-1)
+	}
+}
+
+// MaxPieces returns the most pieces, kings included, of any WDL table found
+func (tb *Tablebase) MaxPieces() int {
+	return tb.maxPieces
+}
+
+// Count returns how many materials have WDL tables and how many have DTZ tables
+func (tb *Tablebase) Count() (int, int) {
+	return tb.wdlCount, tb.dtzCount
+}
+
+// Close closes the files that have been opened
+func (tb *Tablebase) Close() error {
+	var err error
+	for key, t := range tb.tables {
+		// both keys of a material share their tables, close them once
+		if key != t.key() {
+			continue
+		}
+		for _, table := range []*table{t.wdl, t.dtz} {
+			if table != nil {
+				err = errors.Join(err, table.close())
+			}
+		}
+	}
+	return err
+}
+
+// the key with the stronger side white
+func (t *tables) key() string {
+	if t.wdl != nil {
+		return t.wdl.key
+	}
+	return t.dtz.key
+}
+
+// whether a file name is a material like KRPvKR, with a king on each side and no more than MAX_PIECES pieces
+func validCode(code string) bool {
+	white, black, found := strings.Cut(code, "v")
+	if !found || len(code)-1 > MAX_PIECES {
+		return false
+	}
+	for _, side := range []string{white, black} {
+		if strings.Count(side, "K") != 1 || !strings.HasPrefix(side, "K") {
+			return false
+		}
+		for _, c := range side {
+			if !strings.ContainsRune(chess.MATERIAL_ORDER, c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// the material keys of a code, with its first side white and with it black
+func materialKeys(code string) (string, string) {
+	white, black, _ := strings.Cut(code, "v")
+	white, black = chess.SortMaterial(white), chess.SortMaterial(black)
+	return white + "v" + black, black + "v" + white
+}
+
+// the material key of a position, white's pieces first
+func boardKey(board *chess.Board) string {
+	white, black := board.MaterialKey()
+	return white + "v" + black
+}
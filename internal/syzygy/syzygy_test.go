@@ -0,0 +1,1196 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jgerontis/go-chess/internal/chess"
+	"github.com/jgerontis/go-chess/internal/endgame"
+)
+
+/*
+	There are no tablebase files in the repository, so the tests write small files in the Syzygy format themselves.
+	The values are compressed the way the generator does it, if less cleverly: Re-Pair replaces the most common pair
+	of symbols with a new symbol until no pair is common, then the symbols get a canonical Huffman code and are
+	packed into blocks. That way probing reads pairs of pairs and codes of several lengths, like it does in real files.
+*/
+
+// a file for the tests to write
+type testTable struct {
+	code string
+	dtz  bool
+	// the flags of every table in the file
+	flags byte
+	// DTZ only, used for every result when flags has flagMapped
+	dtzMap []int
+	// the value at each index, every value is 0 if nil
+	value func(side, file int, idx uint64) int
+}
+
+const (
+	testBlockSizeBits = 6
+	testSpanBits      = 6
+	testBlockSize     = 1 << testBlockSizeBits
+	testSpan          = 1 << testSpanBits
+	// pairs are only made while some pair of symbols comes up this often
+	testMinPairCount = 4
+	// blocks are kept short so probing has to walk past a few from the sparse index
+	testMaxBlockValues = 1 << 10
+)
+
+// the pieces in the order the tests encode them, in the file's numbering with the stronger side white
+func (tt *testTable) pieces(t *table) []byte {
+	white, black, _ := strings.Cut(t.key, "v")
+	number := func(c rune, black bool) byte {
+		piece := byte(strings.IndexRune(" PNBRQK", c))
+		if black {
+			piece |= 8
+		}
+		return piece
+	}
+	// pieces of a side that aren't kings or pawns, the ones there's only one of first
+	others := func(side string, black bool) (unique, rest []byte) {
+		for _, c := range "QRBN" {
+			n := strings.Count(side, string(c))
+			for range n {
+				if n == 1 {
+					unique = append(unique, number(c, black))
+				} else {
+					rest = append(rest, number(c, black))
+				}
+			}
+		}
+		return unique, rest
+	}
+	whiteUnique, whiteRest := others(white, false)
+	blackUnique, blackRest := others(black, true)
+
+	var pieces []byte
+	if t.hasPawns {
+		pawns := func(side string, black bool) []byte {
+			return []byte(strings.Repeat(string(number('P', black)), strings.Count(side, "P")))
+		}
+		// the leading pawns first, white's unless black has fewer, like newTable
+		whitePawns, blackPawns := strings.Count(white, "P"), strings.Count(black, "P")
+		if blackPawns == 0 || (whitePawns > 0 && blackPawns >= whitePawns) {
+			pieces = append(pawns(white, false), pawns(black, true)...)
+		} else {
+			pieces = append(pawns(black, true), pawns(white, false)...)
+		}
+		pieces = append(pieces, 6, 14)
+	} else {
+		pieces = []byte{6, 14}
+	}
+	pieces = append(pieces, whiteUnique...)
+	pieces = append(pieces, blackUnique...)
+	pieces = append(pieces, whiteRest...)
+	return append(pieces, blackRest...)
+}
+
+// writes a table file into dir
+func writeTestTable(t *testing.T, dir string, tt testTable) {
+	t.Helper()
+	ext := ".rtbw"
+	if tt.dtz {
+		ext = ".rtbz"
+	}
+	tab := newTable(tt.code, "", tt.dtz)
+	sides := 1
+	if !tt.dtz && tab.key != tab.key2 {
+		sides = 2
+	}
+	files := 1
+	if tab.hasPawns {
+		files = 4
+	}
+	pp := tab.hasPawns && tab.pawnCount[1] > 0
+	pieces := tt.pieces(tab)
+	order := [2]int{0, 0xf}
+	if pp {
+		order[1] = 1
+	}
+
+	var out []byte
+	u16 := func(v int) { out = binary.LittleEndian.AppendUint16(out, uint16(v)) }
+	u32 := func(v int) { out = binary.LittleEndian.AppendUint32(out, uint32(v)) }
+	align := func(n int) {
+		for len(out)%n != 0 {
+			out = append(out, 0)
+		}
+	}
+
+	magic := wdlMagic
+	if tt.dtz {
+		magic = dtzMagic
+	}
+	out = append(out, magic[:]...)
+	var flags byte
+	if tab.key != tab.key2 {
+		flags |= 1
+	}
+	if tab.hasPawns {
+		flags |= 2
+	}
+	out = append(out, flags)
+
+	var tables [4][2]*pairsData
+	for file := range files {
+		out = append(out, 0)
+		if pp {
+			out = append(out, 0x11)
+		}
+		for _, piece := range pieces {
+			out = append(out, piece|piece<<4)
+		}
+		for side := range sides {
+			d := &pairsData{}
+			copy(d.pieces[:], pieces)
+			tab.setGroups(d, order, file)
+			tables[file][side] = d
+		}
+	}
+	align(2)
+
+	var packed [4][2]*packedTable
+	for file := range files {
+		for side := range sides {
+			if tt.value == nil {
+				out = append(out, tt.flags|flagSingleValue, 0)
+				continue
+			}
+			d := tables[file][side]
+			values := make([]int, d.size())
+			for idx := range values {
+				values[idx] = tt.value(side, file, uint64(idx))
+			}
+			p := packTable(t, values)
+			packed[file][side] = p
+			out = append(out, tt.flags, testBlockSizeBits, testSpanBits, 0)
+			u32(len(p.blocks))
+			out = append(out, byte(p.maxSymLen), byte(p.minSymLen))
+			for _, sym := range p.lowestSym {
+				u16(sym)
+			}
+			u16(len(p.left))
+			for sym := range p.left {
+				left, right := p.left[sym], p.right[sym]
+				out = append(out, byte(left), byte(left>>8&0xf|right<<4), byte(right>>4))
+			}
+			if len(p.left)%2 == 1 {
+				out = append(out, 0)
+			}
+		}
+	}
+	if tt.dtz && tt.flags&flagMapped != 0 {
+		for range files {
+			for range 4 {
+				out = append(out, byte(len(tt.dtzMap)))
+				for _, v := range tt.dtzMap {
+					out = append(out, byte(v))
+				}
+			}
+		}
+		align(2)
+	}
+	if tt.value != nil {
+		for file := range files {
+			for side := range sides {
+				out = append(out, packed[file][side].sparseIndex...)
+			}
+		}
+		for file := range files {
+			for side := range sides {
+				for _, length := range packed[file][side].blockLength {
+					u16(length)
+				}
+			}
+		}
+	}
+	for file := range files {
+		for side := range sides {
+			align(64)
+			if tt.value == nil {
+				continue
+			}
+			for _, block := range packed[file][side].blocks {
+				out = append(out, block...)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, tt.code+ext), out, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a table's values compressed into blocks
+type packedTable struct {
+	// the pair each symbol stands for, 0xfff on the right for a value
+	left, right          []int
+	minSymLen, maxSymLen int
+	lowestSym            []int
+	blocks               [][]byte
+	blockLength          []int
+	sparseIndex          []byte
+}
+
+// compresses values with Re-Pair and a canonical Huffman code
+func packTable(t *testing.T, values []int) *packedTable {
+	t.Helper()
+	p := &packedTable{}
+	symbolOf := map[int]int{}
+	seq := make([]int, len(values))
+	for i, v := range values {
+		sym, ok := symbolOf[v]
+		if !ok {
+			sym = len(p.left)
+			symbolOf[v] = sym
+			p.left, p.right = append(p.left, v), append(p.right, 0xfff)
+		}
+		seq[i] = sym
+	}
+	// a Huffman code needs two symbols
+	if len(p.left) == 1 {
+		p.left, p.right = append(p.left, p.left[0]), append(p.right, 0xfff)
+	}
+	// how many values each symbol stands for
+	lengths := make([]int, len(p.left))
+	for i := range lengths {
+		lengths[i] = 1
+	}
+
+	for len(p.left) < 0xfff {
+		counts := map[[2]int]int{}
+		for i := 0; i+1 < len(seq); i++ {
+			counts[[2]int{seq[i], seq[i+1]}]++
+		}
+		var best [2]int
+		bestCount := testMinPairCount - 1
+		for pair, count := range counts {
+			if count > bestCount || (count == bestCount && (pair[0] < best[0] || pair[0] == best[0] && pair[1] < best[1])) {
+				best, bestCount = pair, count
+			}
+		}
+		if bestCount < testMinPairCount {
+			break
+		}
+		sym := len(p.left)
+		p.left, p.right = append(p.left, best[0]), append(p.right, best[1])
+		lengths = append(lengths, lengths[best[0]]+lengths[best[1]])
+		replaced := seq[:0]
+		for i := 0; i < len(seq); i++ {
+			if i+1 < len(seq) && seq[i] == best[0] && seq[i+1] == best[1] {
+				replaced = append(replaced, sym)
+				i++
+			} else {
+				replaced = append(replaced, seq[i])
+			}
+		}
+		seq = replaced
+	}
+
+	// Huffman code lengths, every symbol gets a code even if it's only used inside pairs
+	freq := make([]int, len(p.left))
+	for _, sym := range seq {
+		freq[sym]++
+	}
+	type node struct{ weight, parent int }
+	nodes := make([]node, len(freq))
+	for sym := range freq {
+		nodes[sym] = node{weight: freq[sym] + 1, parent: -1}
+	}
+	roots := make([]int, len(freq))
+	for i := range roots {
+		roots[i] = i
+	}
+	for len(roots) > 1 {
+		slices.SortStableFunc(roots, func(a, b int) int { return nodes[a].weight - nodes[b].weight })
+		merged := len(nodes)
+		nodes = append(nodes, node{weight: nodes[roots[0]].weight + nodes[roots[1]].weight, parent: -1})
+		nodes[roots[0]].parent, nodes[roots[1]].parent = merged, merged
+		roots = append([]int{merged}, roots[2:]...)
+	}
+	codeLen := make([]int, len(freq))
+	for sym := range codeLen {
+		for n := sym; nodes[n].parent != -1; n = nodes[n].parent {
+			codeLen[sym]++
+		}
+	}
+	p.minSymLen, p.maxSymLen = slices.Min(codeLen), slices.Max(codeLen)
+	if p.maxSymLen > 32 {
+		t.Fatalf("Huffman code %d bits long", p.maxSymLen)
+	}
+
+	// the canonical code numbers the symbols with the longest codes first
+	order := make([]int, len(freq))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return codeLen[b] - codeLen[a] })
+	number := make([]int, len(freq))
+	for n, sym := range order {
+		number[sym] = n
+	}
+	left, right := make([]int, len(freq)), make([]int, len(freq))
+	for sym := range freq {
+		left[number[sym]], right[number[sym]] = p.left[sym], p.right[sym]
+		if p.right[sym] != 0xfff {
+			left[number[sym]], right[number[sym]] = number[p.left[sym]], number[p.right[sym]]
+		}
+	}
+	p.left, p.right = left, right
+
+	count := make([]int, p.maxSymLen+1)
+	for _, length := range codeLen {
+		count[length]++
+	}
+	levels := p.maxSymLen - p.minSymLen + 1
+	p.lowestSym = make([]int, levels)
+	base := make([]int, levels)
+	for i := levels - 2; i >= 0; i-- {
+		n := count[p.minSymLen+i+1]
+		p.lowestSym[i] = p.lowestSym[i+1] + n
+		base[i] = (base[i+1] + n) / 2
+	}
+
+	// whole symbols go into each block, the sparse index points at the middle of every span
+	var blockStart []int
+	var buf []byte
+	bitsUsed, blockValues, total := 0, 0, 0
+	finish := func() {
+		p.blocks = append(p.blocks, buf)
+		p.blockLength = append(p.blockLength, blockValues-1)
+	}
+	for _, sym := range seq {
+		length, n := codeLen[sym], lengths[sym]
+		if buf == nil || bitsUsed+length > 8*testBlockSize || blockValues > 0 && blockValues+n > testMaxBlockValues {
+			if buf != nil {
+				finish()
+			}
+			buf, bitsUsed, blockValues = make([]byte, testBlockSize), 0, 0
+			blockStart = append(blockStart, total)
+		}
+		numbered := number[sym]
+		level := length - p.minSymLen
+		code := base[level] + numbered - p.lowestSym[level]
+		for bit := range length {
+			if code>>(length-1-bit)&1 == 1 {
+				buf[(bitsUsed+bit)/8] |= 1 << (7 - (bitsUsed+bit)%8)
+			}
+		}
+		bitsUsed += length
+		blockValues += n
+		total += n
+	}
+	finish()
+	if total != len(values) {
+		t.Fatalf("packed %d values, want %d", total, len(values))
+	}
+	for k := range (len(values) + testSpan - 1) / testSpan {
+		i := k*testSpan + testSpan/2
+		block, _ := slices.BinarySearch(blockStart, i+1)
+		block--
+		p.sparseIndex = binary.LittleEndian.AppendUint32(p.sparseIndex, uint32(block))
+		p.sparseIndex = binary.LittleEndian.AppendUint16(p.sparseIndex, uint16(i-blockStart[block]))
+	}
+	return p
+}
+
+// a board with the pieces on the squares and nothing else
+func placePieces(pieces map[int]chess.Piece, whiteToMove bool) *chess.Board {
+	board := chess.NewBoard()
+	board.EnPassantSquare = -1
+	board.WhiteToMove = whiteToMove
+	for square, piece := range pieces {
+		board.SetPieceAtIndex(piece, square)
+	}
+	board.UpdateAttacks()
+	return board
+}
+
+func legalMoves(board *chess.Board) []chess.Move {
+	var list chess.MoveList
+	board.GenerateLegalMovesInto(&list)
+	return list.ToSlice()
+}
+
+func mustParse(t *testing.T, fen string) *chess.Board {
+	t.Helper()
+	board, err := chess.ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("%s: %v", fen, err)
+	}
+	return board
+}
+
+// whether black, to move, can take an undefended queen
+func queenHangs(board *chess.Board) bool {
+	queen := board.Pieces[chess.WHITE_INDEX][chess.QUEEN].GetLSB()
+	king := board.Pieces[chess.BLACK_INDEX][chess.KING].GetLSB()
+	return chess.KingMasks[king].Occupied(queen) && !board.AttackedSquares(chess.WHITE).Occupied(queen)
+}
+
+// every legal KQvK position with white's pieces, one at a time
+func eachKQvK(fn func(board *chess.Board)) {
+	for wk := range 64 {
+		for wq := range 64 {
+			for bk := range 64 {
+				if wk == wq || wk == bk || wq == bk || chess.KingMasks[wk].Occupied(bk) {
+					continue
+				}
+				pieces := map[int]chess.Piece{
+					wk: chess.Piece(chess.WHITE | chess.KING),
+					wq: chess.Piece(chess.WHITE | chess.QUEEN),
+					bk: chess.Piece(chess.BLACK | chess.KING),
+				}
+				for _, whiteToMove := range []bool{true, false} {
+					board := placePieces(pieces, whiteToMove)
+					// the side not to move can't be in check
+					if whiteToMove && board.IsInCheck(chess.BLACK) {
+						continue
+					}
+					fn(board)
+				}
+			}
+		}
+	}
+}
+
+var kqvkFile []byte
+
+// writes KQvK.rtbw with the real results, except that black taking the queen is left to probing
+func writeKQvK(t *testing.T, dir string) {
+	t.Helper()
+	path := filepath.Join(dir, "KQvK.rtbw")
+	// working the values out takes a while, every test after the first gets a copy
+	if kqvkFile != nil {
+		if err := os.WriteFile(path, kqvkFile, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	tab := newTable("KQvK", "", false)
+	writeTestTable(t, dir, testTable{code: "KQvK", value: func(int, int, uint64) int { return 0 }})
+	tab.path = path
+	if err := tab.open(); err != nil {
+		t.Fatal(err)
+	}
+	defer tab.close()
+
+	values := [2]map[uint64]int{{}, {}}
+	eachKQvK(func(board *chess.Board) {
+		pos, err := tab.index(board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value := int(WIN)
+		if !board.WhiteToMove {
+			value = int(LOSS)
+			if !board.IsInCheck(chess.BLACK) && !hasLegalMoves(board) {
+				value = int(DRAW)
+			}
+		}
+		if old, ok := values[pos.side][pos.idx]; ok && old != value {
+			t.Fatalf("%s: index %d is %d and %d", board.ExportFEN(), pos.idx, old, value)
+		}
+		values[pos.side][pos.idx] = value
+	})
+	writeTestTable(t, dir, testTable{code: "KQvK", value: func(side, _ int, idx uint64) int {
+		return values[side][idx] + 2
+	}})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kqvkFile = data
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTable(t, dir, testTable{code: "KRvK"})
+	writeTestTable(t, dir, testTable{code: "KRvK", dtz: true})
+	writeTestTable(t, dir, testTable{code: "KQRvKR"})
+	for _, name := range []string{"KRvK.txt", "KvKR.rtbw.bak", "KKvK.rtbw", "QvK.rtbw"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0o644)
+	}
+	other := t.TempDir()
+	writeTestTable(t, other, testTable{code: "KBNvK"})
+
+	tb, err := Open(dir + string(filepath.ListSeparator) + other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+	if wdl, dtz := tb.Count(); wdl != 3 || dtz != 1 {
+		t.Errorf("found %d WDL and %d DTZ tables, want 3 and 1", wdl, dtz)
+	}
+	if tb.MaxPieces() != 5 {
+		t.Errorf("MaxPieces is %d, want 5", tb.MaxPieces())
+	}
+	// both colors find the same tables
+	if tb.tables["KRvK"] == nil || tb.tables["KRvK"] != tb.tables["KvKR"] {
+		t.Errorf("KRvK and KvKR should share tables")
+	}
+
+	if _, err := Open(t.TempDir()); !errors.Is(err, ErrNoTables) {
+		t.Errorf("empty directory: got %v, want ErrNoTables", err)
+	}
+	if _, err := Open(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("missing directory should fail")
+	}
+}
+
+func TestMaterialKeys(t *testing.T) {
+	key, key2 := materialKeys("KRPvKNB")
+	if key != "KRPvKBN" || key2 != "KBNvKRP" {
+		t.Errorf("got %s and %s", key, key2)
+	}
+	board := mustParse(t, "8/8/4k3/3bn3/8/2RP4/4K3/8 w - - 0 1")
+	if got := boardKey(board); got != "KRPvKBN" {
+		t.Errorf("board key %s, want KRPvKBN", got)
+	}
+}
+
+// where a square goes when the board is mirrored or turned, the first is the board as it is
+type symmetry func(square int) int
+
+var symmetries = []symmetry{
+	func(s int) int { return s },
+	func(s int) int { return s ^ 7 },
+	func(s int) int { return s ^ 56 },
+	func(s int) int { return s ^ 63 },
+	func(s int) int { return s>>3 | (s&7)<<3 },
+	func(s int) int { return (s>>3 | (s&7)<<3) ^ 7 },
+	func(s int) int { return (s>>3 | (s&7)<<3) ^ 56 },
+	func(s int) int { return (s>>3 | (s&7)<<3) ^ 63 },
+}
+
+// random positions for a material, pawns on ranks 2-7 and the kings apart
+func randomPosition(rng *rand.Rand, key string) (map[int]chess.Piece, bool) {
+	pieces := map[int]chess.Piece{}
+	white, black, _ := strings.Cut(key, "v")
+	kings := [2]int{}
+	for i, side := range []string{white, black} {
+		color := chess.WHITE
+		if i == 1 {
+			color = chess.BLACK
+		}
+		for _, c := range side {
+			piece := chess.Piece(color | byte(strings.IndexRune(" PNBRQK", c)))
+			for {
+				square := rng.Intn(64)
+				if _, taken := pieces[square]; taken {
+					continue
+				}
+				if c == 'P' && (square < 8 || square >= 56) {
+					continue
+				}
+				if c == 'K' && i == 1 && (square == kings[0] || chess.KingMasks[kings[0]].Occupied(square)) {
+					continue
+				}
+				if c == 'K' {
+					kings[i] = square
+				}
+				pieces[square] = piece
+				break
+			}
+		}
+	}
+	return pieces, rng.Intn(2) == 0
+}
+
+// the same position with the colors swapped and the board flipped
+func swapColors(pieces map[int]chess.Piece) map[int]chess.Piece {
+	swapped := map[int]chess.Piece{}
+	for square, piece := range pieces {
+		swapped[square^56] = chess.Piece(piece.Type() | (chess.WHITE + chess.BLACK - piece.Color()))
+	}
+	return swapped
+}
+
+// whether the pieces of a pawnless table's leading group are all on the a1-h8 diagonal or all on the a8-h1 one
+func leadingOnDiagonal(tab *table, pieces map[int]chess.Piece, whiteToMove bool) bool {
+	d := tab.pairsFor(0, 0)
+	flip := boardKey(placePieces(pieces, whiteToMove)) != tab.key || (tab.key == tab.key2 && !whiteToMove)
+	var squares []int
+	for _, want := range d.pieces[:d.groupLen[0]] {
+		for square, piece := range pieces {
+			number := tablePiece(piece)
+			if flip {
+				number ^= 8
+			}
+			if number == want && !slices.Contains(squares, square) {
+				squares = append(squares, square)
+				break
+			}
+		}
+	}
+	a1h8, a8h1 := true, true
+	for _, square := range squares {
+		a1h8 = a1h8 && square/8 == square%8
+		a8h1 = a8h1 && square/8+square%8 == 7
+	}
+	return a1h8 || a8h1
+}
+
+func TestIndexSymmetry(t *testing.T) {
+	dir := t.TempDir()
+	codes := []string{"KRvK", "KQvKR", "KRRvK", "KBNvK", "KRvKR", "KPvK", "KPvKP", "KPPvKP", "KRPvKR"}
+	for _, code := range codes {
+		writeTestTable(t, dir, testTable{code: code})
+	}
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	rng := rand.New(rand.NewSource(1))
+	for _, code := range codes {
+		tab := tb.tables[code].wdl
+		if err := tab.open(); err != nil {
+			t.Fatal(err)
+		}
+		for range 2000 {
+			pieces, whiteToMove := randomPosition(rng, tab.key)
+			pos, err := tab.index(placePieces(pieces, whiteToMove))
+			if err != nil {
+				t.Fatal(err)
+			}
+			board := placePieces(pieces, whiteToMove)
+			if size := tab.pairsFor(pos.side, pos.file).size(); pos.idx >= size {
+				t.Errorf("%s: index %d past the table size %d", board.ExportFEN(), pos.idx, size)
+			}
+
+			// pawns only allow the mirror between the a and h files.
+			// When the whole leading group is on a long diagonal the file doesn't fold the flip across it,
+			// the rest of the pieces can be on either side.
+			applies := symmetries
+			if tab.hasPawns {
+				applies = symmetries[:2]
+			} else if leadingOnDiagonal(tab, pieces, whiteToMove) {
+				applies = symmetries[:4]
+			}
+			for _, colors := range []bool{false, true} {
+				for _, sym := range applies {
+					moved := map[int]chess.Piece{}
+					for square, piece := range pieces {
+						moved[sym(square)] = piece
+					}
+					toMove := whiteToMove
+					if colors {
+						moved = swapColors(moved)
+						toMove = !toMove
+					}
+					other := placePieces(moved, toMove)
+					got, err := tab.index(other)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got != pos {
+						t.Errorf("%s is %+v but %s is %+v", board.ExportFEN(), pos, other.ExportFEN(), got)
+					}
+				}
+			}
+		}
+	}
+}
+
+// different positions, not counting symmetry, have different indexes
+func TestIndexUnique(t *testing.T) {
+	dir := t.TempDir()
+	for _, code := range []string{"KRvK", "KPvK"} {
+		writeTestTable(t, dir, testTable{code: code})
+	}
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	for _, code := range []string{"KRvK", "KPvK"} {
+		tab := tb.tables[code].wdl
+		if err := tab.open(); err != nil {
+			t.Fatal(err)
+		}
+		piece := chess.Piece(chess.WHITE | byte(strings.IndexRune(" PNBRQK", rune(code[1]))))
+		// the smallest of the squares each symmetry gives, one per position
+		canonical := func(wk, p, bk int) int {
+			best := -1
+			applies := symmetries
+			if tab.hasPawns {
+				applies = symmetries[:2]
+			}
+			for _, sym := range applies {
+				if c := sym(wk)<<12 | sym(p)<<6 | sym(bk); best < 0 || c < best {
+					best = c
+				}
+			}
+			return best
+		}
+		seen := map[position]int{}
+		for wk := range 64 {
+			for p := range 64 {
+				for bk := range 64 {
+					if wk == p || wk == bk || p == bk || chess.KingMasks[wk].Occupied(bk) {
+						continue
+					}
+					if piece.Type() == chess.PAWN && (p < 8 || p >= 56) {
+						continue
+					}
+					board := placePieces(map[int]chess.Piece{
+						wk: chess.Piece(chess.WHITE | chess.KING),
+						p:  piece,
+						bk: chess.Piece(chess.BLACK | chess.KING),
+					}, true)
+					pos, err := tab.index(board)
+					if err != nil {
+						t.Fatal(err)
+					}
+					c := canonical(wk, p, bk)
+					if old, ok := seen[pos]; ok && old != c {
+						t.Fatalf("%s: %+v is already another position", board.ExportFEN(), pos)
+					}
+					seen[pos] = c
+				}
+			}
+		}
+	}
+}
+
+func TestProbeWDL(t *testing.T) {
+	dir := t.TempDir()
+	writeKQvK(t, dir)
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	tests := []struct {
+		fen  string
+		want WDL
+	}{
+		{"8/8/8/8/8/8/8/K6k w - - 0 1", DRAW},
+		{"k7/8/8/8/8/8/8/1Q5K w - - 0 1", WIN},
+		{"k7/8/8/8/8/8/8/1Q5K b - - 0 1", LOSS},
+		// stalemate
+		{"k7/8/1Q6/8/8/8/8/7K b - - 0 1", DRAW},
+		{"7k/8/6Q1/8/8/8/8/K7 b - - 0 1", DRAW},
+		// the queen hangs
+		{"k7/1Q6/8/8/8/8/8/7K b - - 0 1", DRAW},
+		// mate
+		{"k7/1Q6/2K5/8/8/8/8/8 b - - 0 1", LOSS},
+		// black has the queen
+		{"K7/8/1q6/8/8/8/8/7k w - - 0 1", DRAW},
+		{"K7/1q6/8/8/8/8/8/7k w - - 0 1", DRAW},
+		{"K7/8/8/8/8/8/8/1q5k w - - 0 1", LOSS},
+		{"K7/8/8/8/8/8/8/1q5k b - - 0 1", WIN},
+	}
+	for _, test := range tests {
+		got, err := tb.ProbeWDL(mustParse(t, test.fen))
+		if err != nil {
+			t.Errorf("%s: %v", test.fen, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.fen, got, test.want)
+		}
+	}
+
+	// every position, with either color holding the queen
+	count := 0
+	eachKQvK(func(board *chess.Board) {
+		count++
+		if count%5 != 0 {
+			return
+		}
+		want := WIN
+		if !board.WhiteToMove {
+			want = LOSS
+			if queenHangs(board) || (!board.IsInCheck(chess.BLACK) && !hasLegalMoves(board)) {
+				want = DRAW
+			}
+		}
+		if got, err := tb.ProbeWDL(board); err != nil || got != want {
+			t.Fatalf("%s: got %s (%v), want %s", board.ExportFEN(), got, err, want)
+		}
+		pieces := map[int]chess.Piece{}
+		for square, piece := range board.Mailbox {
+			if !piece.IsNone() {
+				pieces[square] = piece
+			}
+		}
+		swapped := placePieces(swapColors(pieces), !board.WhiteToMove)
+		if got, err := tb.ProbeWDL(swapped); err != nil || got != want {
+			t.Fatalf("%s: got %s (%v), want %s", swapped.ExportFEN(), got, err, want)
+		}
+	})
+
+	errorTests := []struct {
+		fen  string
+		want error
+	}{
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", ErrCastling},
+		{"k7/8/8/8/8/8/8/1R5K w - - 0 1", ErrMissingTable},
+		{"k7/8/8/8/8/8/8/1QR4K w - - 0 1", ErrTooManyPieces},
+	}
+	for _, test := range errorTests {
+		if _, err := tb.ProbeWDL(mustParse(t, test.fen)); !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.fen, err, test.want)
+		}
+	}
+}
+
+// DTZ with white to move only, the value at each index made up from the index
+func dtzValue(side, _ int, idx uint64) int {
+	return int(idx*7919%50) + 1
+}
+
+func TestProbeDTZ(t *testing.T) {
+	tests := []struct {
+		name   string
+		flags  byte
+		dtzMap []int
+		// the DTZ a stored value stands for when winning
+		plies func(v int) int
+	}{
+		{"moves", 0, nil, func(v int) int { return 2*v + 1 }},
+		{"mapped plies", flagMapped | flagWinPlies, make([]int, 64), func(v int) int { return v + 3 + 1 }},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		writeKQvK(t, dir)
+		for i := range test.dtzMap {
+			test.dtzMap[i] = i + 3
+		}
+		writeTestTable(t, dir, testTable{code: "KQvK", dtz: true, flags: test.flags, dtzMap: test.dtzMap, value: dtzValue})
+		tb, err := Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tab := tb.tables["KQvK"].dtz
+		if err := tab.open(); err != nil {
+			t.Fatal(err)
+		}
+
+		// white to move is read straight from the file
+		stored := func(board *chess.Board) int {
+			pos, err := tab.index(board)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return test.plies(dtzValue(pos.side, pos.file, pos.idx))
+		}
+		board := mustParse(t, "k7/8/8/8/8/8/8/1Q5K w - - 0 1")
+		if got, err := tb.ProbeDTZ(board); err != nil || got != stored(board) {
+			t.Errorf("%s: %s: got %d (%v), want %d", test.name, board.ExportFEN(), got, err, stored(board))
+		}
+
+		// black to move is worked out from white's DTZ after each move
+		board = mustParse(t, "8/8/3k4/8/8/8/8/1Q5K b - - 0 1")
+		want := 0
+		for _, move := range legalMoves(board) {
+			after := *board
+			after.MakeMove(move)
+			want = min(want, -stored(&after)-1)
+		}
+		if got, err := tb.ProbeDTZ(board); err != nil || got != want {
+			t.Errorf("%s: %s: got %d (%v), want %d", test.name, board.ExportFEN(), got, err, want)
+		}
+
+		for _, test := range []struct {
+			fen  string
+			want int
+		}{
+			// mated
+			{"k7/1Q6/2K5/8/8/8/8/8 b - - 0 1", -1},
+			// stalemate and the queen hanging are draws
+			{"k7/8/1Q6/8/8/8/8/7K b - - 0 1", 0},
+			{"k7/1Q6/8/8/8/8/8/7K b - - 0 1", 0},
+		} {
+			if got, err := tb.ProbeDTZ(mustParse(t, test.fen)); err != nil || got != test.want {
+				t.Errorf("%s: got %d (%v), want %d", test.fen, got, err, test.want)
+			}
+		}
+		tb.Close()
+	}
+}
+
+func TestRootMoves(t *testing.T) {
+	dir := t.TempDir()
+	writeKQvK(t, dir)
+	writeTestTable(t, dir, testTable{code: "KQvK", dtz: true, value: dtzValue})
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	board := mustParse(t, "k7/8/1K6/8/8/8/7Q/8 w - - 0 1")
+	moves, err := tb.RootMoves(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != len(legalMoves(board)) {
+		t.Fatalf("got %d moves, want every legal move", len(moves))
+	}
+	// mate is the quickest win
+	if moves[0].Move.String() != "h2h8" || moves[0].DTZ != 1 || moves[0].WDL != WIN || moves[0].Rank != MAX_RANK {
+		t.Errorf("best move is %+v, want the mate h2h8", moves[0])
+	}
+	// giving the queen away, and stalemate by covering b8 or leaving b7 open
+	draws := []string{"h2b8", "h2c7", "h2d6", "h2e5", "h2f4", "h2g3", "b6a6"}
+	for i, move := range moves {
+		uci := move.Move.String()
+		switch {
+		case i == 0:
+		case slices.Contains(draws, uci):
+			if move.WDL != DRAW || move.DTZ != 0 || move.Rank != 0 {
+				t.Errorf("%s should draw: %+v", uci, move)
+			}
+		case move.WDL == WIN && move.DTZ <= 1:
+			t.Errorf("%s isn't mate but has DTZ %d", uci, move.DTZ)
+		}
+		if i > 0 && (moves[i-1].Rank < move.Rank || (moves[i-1].Rank == move.Rank && moves[i-1].DTZ > move.DTZ)) {
+			t.Errorf("moves out of order: %+v before %+v", moves[i-1], move)
+		}
+	}
+
+	// the 50 move rule is close enough to matter
+	board.HalfMoves = 98
+	moves, err = tb.RootMoves(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, move := range moves {
+		if move.DTZ > 1 && move.Rank != MAX_RANK-(move.DTZ+98) {
+			t.Errorf("%s with DTZ %d ranked %d", move.Move.String(), move.DTZ, move.Rank)
+		}
+	}
+
+	// 7 piece tables have cursed wins over 1000 plies from zeroing, they still rank between draws and real wins
+	for _, dtz := range []int{150, 1100, 1600} {
+		if win := rank(dtz, 99); win <= 0 || win >= MAX_RANK {
+			t.Errorf("cursed win with DTZ %d ranked %d", dtz, win)
+		}
+		if loss := rank(-dtz, 99); loss >= 0 || loss <= -MAX_RANK {
+			t.Errorf("blessed loss with DTZ %d ranked %d", -dtz, loss)
+		}
+	}
+}
+
+func TestRootMovesWDL(t *testing.T) {
+	dir := t.TempDir()
+	writeKQvK(t, dir)
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	board := mustParse(t, "k7/8/1K6/8/8/8/7Q/8 w - - 0 1")
+	// without the DTZ table only the WDL ranking is possible
+	if _, err := tb.RootMoves(board); !errors.Is(err, ErrMissingTable) {
+		t.Errorf("RootMoves got %v, want ErrMissingTable", err)
+	}
+	moves, err := tb.RootMovesWDL(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != len(legalMoves(board)) {
+		t.Fatalf("got %d moves, want every legal move", len(moves))
+	}
+	draws := []string{"h2b8", "h2c7", "h2d6", "h2e5", "h2f4", "h2g3", "b6a6"}
+	for i, move := range moves {
+		want, rank := WIN, MAX_RANK
+		if slices.Contains(draws, move.Move.String()) {
+			want, rank = DRAW, 0
+			if i < len(moves)-len(draws) {
+				t.Errorf("%s draws but comes before a win", move.Move.String())
+			}
+		}
+		if move.WDL != want || move.Rank != rank || move.DTZ != 0 {
+			t.Errorf("%s: got %+v, want %s", move.Move.String(), move, want)
+		}
+	}
+}
+
+func TestCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeKQvK(t, dir)
+	path := filepath.Join(dir, "KQvK.rtbw")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := mustParse(t, "k7/8/8/8/8/8/8/1Q5K b - - 0 1")
+	for name, corrupt := range map[string][]byte{
+		"magic":     append([]byte{0, 0, 0, 0}, data[4:]...),
+		"truncated": data[:len(data)/2],
+		"header":    data[:12],
+	} {
+		if err := os.WriteFile(path, corrupt, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		tb, err := Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tb.ProbeWDL(board); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: got %v, want ErrCorrupt", name, err)
+		}
+		tb.Close()
+	}
+}
+
+// real tables from https://tablebase.lichess.ovh/tables/standard/3-4-5/, if they've been copied into testdata
+const realTablesDir = "testdata"
+
+// known results, worked out by retrograde analysis, for real files to be checked against
+func TestRealTables(t *testing.T) {
+	tests := []struct {
+		code string
+		fen  string
+		wdl  WDL
+		// plies to mate when there are no zeroing moves on the way, real files round some of them to moves so
+		// they can be one out, 1 when a capture or pawn move wins at once and 0 to only check the sign
+		dtz int
+	}{
+		{"KQvK", "8/8/8/8/8/8/1Q6/K6k w - - 0 1", WIN, 11},
+		{"KQvK", "8/8/8/8/8/8/1Q6/K6k b - - 0 1", LOSS, -14},
+		{"KRvK", "8/8/8/3k4/8/8/8/KR6 w - - 0 1", WIN, 29},
+		{"KRvK", "8/8/8/3k4/8/8/8/KR6 b - - 0 1", LOSS, -30},
+		{"KRvK", "7k/8/6K1/8/8/8/8/R7 w - - 0 1", WIN, 1},
+		{"KPvK", "4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", WIN, 0},
+		{"KPvK", "4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", LOSS, 0},
+		{"KPvK", "8/8/8/8/8/4k3/4P3/4K3 w - - 0 1", DRAW, 0},
+		{"KPvK", "8/8/8/8/8/4k3/4P3/4K3 b - - 0 1", DRAW, 0},
+		{"KPvK", "7k/8/8/8/8/8/P7/K7 w - - 0 1", WIN, 0},
+		{"KRvKP", "8/8/8/8/8/1k6/7p/K2R4 w - - 0 1", WIN, 0},
+		{"KRvKP", "8/8/8/8/8/3k4/2R4p/K7 b - - 0 1", WIN, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.code+" "+test.fen, func(t *testing.T) {
+			if _, err := os.Stat(filepath.Join(realTablesDir, test.code+".rtbw")); err != nil {
+				t.Skipf("copy %s.rtbw and %s.rtbz into %s to check real tables", test.code, test.code, realTablesDir)
+			}
+			tb, err := Open(realTablesDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tb.Close()
+			board := chess.NewBoard()
+			if err := board.LoadFEN(test.fen); err != nil {
+				t.Fatal(err)
+			}
+			if wdl, err := tb.ProbeWDL(board); err != nil || wdl != test.wdl {
+				t.Fatalf("WDL %s (%v), want %s", wdl, err, test.wdl)
+			}
+			if _, err := os.Stat(filepath.Join(realTablesDir, test.code+".rtbz")); err != nil {
+				return
+			}
+			dtz, err := tb.ProbeDTZ(board)
+			if err != nil || sign(dtz) != sign(int(test.wdl)) {
+				t.Fatalf("DTZ %d (%v), want a %s", dtz, err, test.wdl)
+			}
+			if d := dtz - test.dtz; test.dtz != 0 && (d < -1 || d > 1) {
+				t.Errorf("DTZ %d, want %d", dtz, test.dtz)
+			}
+		})
+	}
+}
+
+// checks real files against the endgame package, which works the results out itself by retrograde analysis
+func TestRealTablesRetrograde(t *testing.T) {
+	tests := []struct {
+		code, material string
+		// no side ever has to capture or move a pawn on the way to mate, so DTZ is DTM to within rounding to moves
+		dtzIsDTM bool
+	}{
+		{"KQvK", "KQK", true},
+		{"KRvK", "KRK", true},
+		{"KPvK", "KPK", false},
+		{"KRvKP", "KRKP", false},
+	}
+	bb := endgame.NewBitbases()
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			if _, err := os.Stat(filepath.Join(realTablesDir, test.code+".rtbw")); err != nil {
+				t.Skipf("copy %s.rtbw and %s.rtbz into %s to check real tables", test.code, test.code, realTablesDir)
+			}
+			if len(test.material) == 4 && testing.Short() {
+				t.Skip("4 piece tables take a while to make")
+			}
+			_, err := os.Stat(filepath.Join(realTablesDir, test.code+".rtbz"))
+			hasDTZ := err == nil
+			tb, err := Open(realTablesDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tb.Close()
+			table, err := bb.Generate(test.material)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			white, black, _ := strings.Cut(test.code, "v")
+			var pieces []chess.Piece
+			for _, side := range []struct {
+				pieces string
+				color  byte
+			}{{white, chess.WHITE}, {black, chess.BLACK}} {
+				for _, c := range side.pieces {
+					pieces = append(pieces, chess.Piece(side.color|byte(strings.IndexRune(" PNBRQK", c))))
+				}
+			}
+			rng := rand.New(rand.NewSource(1))
+			for checked := 0; checked < 20000; {
+				squares := map[int]chess.Piece{}
+				for _, piece := range pieces {
+					square := rng.Intn(64)
+					if piece.Type() == chess.PAWN {
+						square = 8 + rng.Intn(48)
+					}
+					squares[square] = piece
+				}
+				if len(squares) < len(pieces) {
+					continue
+				}
+				board := placePieces(squares, rng.Intn(2) == 0)
+				want, err := table.Probe(board)
+				if errors.Is(err, endgame.ErrIllegalPosition) {
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				checked++
+				wdl, err := tb.ProbeWDL(board)
+				if err != nil || sign(int(wdl)) != int(want.WDL) {
+					t.Fatalf("%s: WDL %s (%v), want %s", board.ExportFEN(), wdl, err, want.WDL)
+				}
+				if !hasDTZ {
+					continue
+				}
+				dtz, err := tb.ProbeDTZ(board)
+				if err != nil || sign(dtz) != int(want.WDL) {
+					t.Fatalf("%s: DTZ %d (%v), want a %s", board.ExportFEN(), dtz, err, want.WDL)
+				}
+				// a mated side has DTZ -1 but DTM 0
+				dtm := want.DTM
+				if dtm == 0 && want.WDL == endgame.LOSS {
+					dtm = 1
+				}
+				if d := dtz*sign(dtz) - dtm; test.dtzIsDTM && (d < -1 || d > 1) {
+					t.Fatalf("%s: DTZ %d, but mate is %d plies away", board.ExportFEN(), dtz, want.DTM)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,593 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+/*
+	A table file is a 4 byte magic number, a header describing how each table in it is encoded and then the compressed tables.
+	WDL files have a table for each side to move, unless both sides have the same pieces, DTZ files have one.
+	Files with pawns have that for each file of the leading pawn, a to d.
+
+	The values are compressed with Re-Pair, pairs of symbols that often come next to each other are replaced by a new symbol,
+	and then the symbols are Huffman coded into blocks of the same size.
+	http://www.larsson.dogma.net/dcc99.pdf
+	A sparse index points into the blocks every span values, so finding a value reads one block and at most a few block lengths.
+*/
+
+var (
+	wdlMagic = [4]byte{0x71, 0xe8, 0x23, 0x5d}
+	dtzMagic = [4]byte{0xd7, 0x66, 0x0c, 0xa5}
+)
+
+// a DTZ table only has the other side to move, probing has to look one move ahead
+var errChangeSide = errors.New("table has the other side to move")
+
+// the flags of each table
+const (
+	flagSide        = 1
+	flagMapped      = 2
+	flagWinPlies    = 4
+	flagLossPlies   = 8
+	flagWide        = 16
+	flagSingleValue = 128
+)
+
+// one table of a file
+type pairsData struct {
+	flags                byte
+	minSymLen, maxSymLen int
+	blockSize            int
+	span                 uint64
+	numBlocks            int
+	// the first symbol of each code length, then the same left aligned in 64 bits
+	lowestSym []uint16
+	base64    []uint64
+	// the pair each symbol stands for, a symbol with a right of 0xfff stands for the value in left
+	left, right []uint16
+	// how many values each symbol stands for, less one
+	symLen []int
+	// 6 bytes every span values: the block, little endian uint32, and the offset into it, uint16
+	sparseIndex []byte
+	// how many values each block holds, less one
+	blockLength []uint16
+	// where the blocks start in the file
+	data int64
+
+	// the pieces in the order they're encoded
+	pieces [MAX_PIECES]byte
+	// how many pieces are in each group, ending with 0, and what each group's index is multiplied by.
+	// The last groupIdx is the size of the table.
+	groupLen [MAX_PIECES + 1]int
+	groupIdx [MAX_PIECES + 1]uint64
+	// DTZ only, the stored values to DTZ for each result, see wdlMap
+	dtzMap [4][]int
+}
+
+// a .rtbw or .rtbz file
+type table struct {
+	path            string
+	dtz             bool
+	key, key2       string
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	// the leading color's pawns and the other color's
+	pawnCount [2]int
+
+	once sync.Once
+	err  error
+	file *os.File
+	// [side to move][leading pawn file]
+	pairs [2][4]*pairsData
+	sides int
+}
+
+func newTable(code, path string, dtz bool) *table {
+	t := &table{path: path, dtz: dtz}
+	t.key, t.key2 = materialKeys(code)
+	white, black, _ := strings.Cut(t.key, "v")
+	t.pieceCount = len(white) + len(black)
+	whitePawns, blackPawns := strings.Count(white, "P"), strings.Count(black, "P")
+	t.hasPawns = whitePawns+blackPawns > 0
+	for _, side := range []string{white, black} {
+		for _, piece := range "QRBNP" {
+			if strings.Count(side, string(piece)) == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+	// with pawns on both sides the side with fewer pawns leads, it compresses better
+	if blackPawns == 0 || (whitePawns > 0 && blackPawns >= whitePawns) {
+		t.pawnCount = [2]int{whitePawns, blackPawns}
+	} else {
+		t.pawnCount = [2]int{blackPawns, whitePawns}
+	}
+	return t
+}
+
+// opens the file and reads its header the first time the table is used
+func (t *table) open() error {
+	t.once.Do(func() {
+		t.err = t.load()
+		if t.err != nil {
+			t.err = fmt.Errorf("%s: %w", t.path, t.err)
+		}
+	})
+	return t.err
+}
+
+func (t *table) close() error {
+	if t.file == nil {
+		return nil
+	}
+	return t.file.Close()
+}
+
+// the table for a side to move and leading pawn file
+func (t *table) pairsFor(side, file int) *pairsData {
+	return t.pairs[side%t.sides][file]
+}
+
+// whether a DTZ table has positions with this side to move
+func (t *table) hasSide(side, file int) bool {
+	return int(t.pairsFor(side, file).flags&flagSide) == side || (t.key == t.key2 && !t.hasPawns)
+}
+
+func (t *table) load() error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	t.file = file
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := t.read(file, info.Size()); err != nil {
+		file.Close()
+		t.file = nil
+		return err
+	}
+	return nil
+}
+
+// reads the header of a file, the same steps as do_init in Stockfish
+func (t *table) read(f io.ReaderAt, size int64) error {
+	r := &reader{f: f}
+	magic := wdlMagic
+	if t.dtz {
+		magic = dtzMagic
+	}
+	if [4]byte(r.bytes(4)) != magic {
+		return fmt.Errorf("%w: wrong magic number", ErrCorrupt)
+	}
+
+	const split, hasPawns = 1, 2
+	flags := r.u8()
+	if (flags&hasPawns != 0) != t.hasPawns || (flags&split != 0) != (t.key != t.key2) {
+		return fmt.Errorf("%w: the file doesn't match its name", ErrCorrupt)
+	}
+
+	t.sides = 1
+	if !t.dtz && t.key != t.key2 {
+		t.sides = 2
+	}
+	files := 1
+	if t.hasPawns {
+		files = 4
+	}
+	// pawns on both sides
+	pp := t.hasPawns && t.pawnCount[1] > 0
+
+	for file := range files {
+		for side := range t.sides {
+			t.pairs[side][file] = &pairsData{}
+		}
+		// the order the groups are encoded in, for each side in a nibble
+		order := [2][2]int{{int(r.peek(0) & 0xf), 0xf}, {int(r.peek(0) >> 4), 0xf}}
+		if pp {
+			order[0][1], order[1][1] = int(r.peek(1)&0xf), int(r.peek(1)>>4)
+		}
+		r.skip(1)
+		if pp {
+			r.skip(1)
+		}
+		for k := range t.pieceCount {
+			b := r.u8()
+			for side := range t.sides {
+				piece := b & 0xf
+				if side == 1 {
+					piece = b >> 4
+				}
+				t.pairs[side][file].pieces[k] = piece
+			}
+		}
+		for side := range t.sides {
+			t.setGroups(t.pairs[side][file], order[side], file)
+		}
+	}
+	r.align(2)
+
+	for file := range files {
+		for side := range t.sides {
+			t.pairs[side][file].setSizes(r)
+		}
+	}
+	if t.dtz {
+		t.setMaps(r, files)
+	}
+	for file := range files {
+		for side := range t.sides {
+			d := t.pairs[side][file]
+			d.sparseIndex = r.bytes(6 * sparseIndexSize(d))
+		}
+	}
+	for file := range files {
+		for side := range t.sides {
+			d := t.pairs[side][file]
+			lengths := r.bytes(2 * d.blockLengthSize())
+			d.blockLength = make([]uint16, len(lengths)/2)
+			for i := range d.blockLength {
+				d.blockLength[i] = binary.LittleEndian.Uint16(lengths[2*i:])
+			}
+		}
+	}
+	for file := range files {
+		for side := range t.sides {
+			d := t.pairs[side][file]
+			r.align(64)
+			d.data = r.off
+			r.off += int64(d.numBlocks) * int64(d.blockSize)
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, r.err)
+	}
+	if r.off > size {
+		return fmt.Errorf("%w: %d bytes short", ErrCorrupt, r.off-size)
+	}
+	return nil
+}
+
+// splits the pieces into the groups they're encoded in and works out what each group's index is multiplied by
+func (t *table) setGroups(d *pairsData, order [2]int, file int) {
+	// without pawns the first 3 pieces are placed together if there's a piece there's only one of, otherwise just the kings
+	firstLen := 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+	n := 0
+	d.groupLen[0] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	// the groups are numbered in the order of pieces, but encoded in the order the file gives
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch {
+		case k == order[0]:
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= leadPawnsSize[d.groupLen[0]][file]
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]:
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// the number of positions in the table
+func (d *pairsData) size() uint64 {
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	return d.groupIdx[n]
+}
+
+func sparseIndexSize(d *pairsData) int {
+	if d.flags&flagSingleValue != 0 {
+		return 0
+	}
+	return int((d.size() + d.span - 1) / d.span)
+}
+
+func (d *pairsData) blockLengthSize() int {
+	if d.flags&flagSingleValue != 0 {
+		return 0
+	}
+	return len(d.blockLength)
+}
+
+// reads the block sizes and the symbol tables
+func (d *pairsData) setSizes(r *reader) {
+	d.flags = r.u8()
+	if d.flags&flagSingleValue != 0 {
+		// the one value every position has
+		d.minSymLen = int(r.u8())
+		return
+	}
+	d.blockSize = 1 << r.u8()
+	d.span = 1 << r.u8()
+	padding := int(r.u8())
+	d.numBlocks = int(r.u32())
+	// the block lengths are padded so the sparse index can't point past them, they're read later
+	d.blockLength = make([]uint16, d.numBlocks+padding)
+	d.maxSymLen = int(r.u8())
+	d.minSymLen = int(r.u8())
+	if d.maxSymLen < d.minSymLen || d.maxSymLen > 32 {
+		r.fail("bad symbol lengths")
+		return
+	}
+
+	lengths := d.maxSymLen - d.minSymLen + 1
+	d.lowestSym = make([]uint16, lengths)
+	for i := range d.lowestSym {
+		d.lowestSym[i] = r.u16()
+	}
+	// the canonical Huffman code, base64[i] is the lowest code of length minSymLen+i
+	d.base64 = make([]uint64, lengths)
+	for i := lengths - 2; i >= 0; i-- {
+		d.base64[i] = uint64((int64(d.base64[i+1]) + int64(d.lowestSym[i]) - int64(d.lowestSym[i+1])) / 2)
+	}
+	// left aligned, so any code of length i padded to 64 bits is between base64[i-1] and base64[i]
+	for i := range d.base64 {
+		d.base64[i] <<= 64 - i - d.minSymLen
+	}
+
+	count := int(r.u16())
+	tree := r.bytes(3 * count)
+	if r.err != nil {
+		return
+	}
+	d.left, d.right = make([]uint16, count), make([]uint16, count)
+	for i := range count {
+		b := tree[3*i : 3*i+3]
+		d.left[i] = uint16(b[1]&0xf)<<8 | uint16(b[0])
+		d.right[i] = uint16(b[2])<<4 | uint16(b[1]>>4)
+	}
+	if count%2 == 1 {
+		r.skip(1)
+	}
+
+	d.symLen = make([]int, count)
+	visited := make([]bool, count)
+	for sym := range count {
+		if !visited[sym] && !d.setSymLen(sym, visited) {
+			r.fail("bad symbol tree")
+			return
+		}
+	}
+}
+
+// works out how many values a symbol stands for, false if the tree points outside itself
+func (d *pairsData) setSymLen(sym int, visited []bool) bool {
+	visited[sym] = true
+	right := int(d.right[sym])
+	if right == 0xfff {
+		d.symLen[sym] = 0
+		return true
+	}
+	left := int(d.left[sym])
+	if left >= len(d.symLen) || right >= len(d.symLen) {
+		return false
+	}
+	for _, s := range []int{left, right} {
+		if !visited[s] && !d.setSymLen(s, visited) {
+			return false
+		}
+	}
+	d.symLen[sym] = d.symLen[left] + d.symLen[right] + 1
+	return true
+}
+
+// reads the maps from stored values to DTZ, for each table and result
+func (t *table) setMaps(r *reader, files int) {
+	for file := range files {
+		d := t.pairs[0][file]
+		if d.flags&flagMapped == 0 {
+			continue
+		}
+		wide := d.flags&flagWide != 0
+		if wide {
+			r.align(2)
+		}
+		for i := range d.dtzMap {
+			if !wide {
+				d.dtzMap[i] = make([]int, r.u8())
+				for j := range d.dtzMap[i] {
+					d.dtzMap[i][j] = int(r.u8())
+				}
+				continue
+			}
+			d.dtzMap[i] = make([]int, r.u16())
+			for j := range d.dtzMap[i] {
+				d.dtzMap[i][j] = int(r.u16())
+			}
+		}
+	}
+	r.align(2)
+}
+
+// finds the value at an index
+func (d *pairsData) decompress(f io.ReaderAt, idx uint64) (int, error) {
+	if d.flags&flagSingleValue != 0 {
+		return d.minSymLen, nil
+	}
+	k := int(idx / d.span)
+	if 6*k+6 > len(d.sparseIndex) {
+		return 0, fmt.Errorf("%w: index %d out of range", ErrCorrupt, idx)
+	}
+	block := int(binary.LittleEndian.Uint32(d.sparseIndex[6*k:]))
+	offset := int(binary.LittleEndian.Uint16(d.sparseIndex[6*k+4:]))
+	offset += int(idx%d.span) - int(d.span/2)
+
+	for offset < 0 && block > 0 {
+		block--
+		offset += int(d.blockLength[block]) + 1
+	}
+	for block < len(d.blockLength) && offset > int(d.blockLength[block]) {
+		offset -= int(d.blockLength[block]) + 1
+		block++
+	}
+	if offset < 0 || block >= d.numBlocks {
+		return 0, fmt.Errorf("%w: block %d out of range", ErrCorrupt, block)
+	}
+
+	// reading whole 32 bit words can run a little past the end of the block
+	buf := make([]byte, d.blockSize+8)
+	if n, err := f.ReadAt(buf, d.data+int64(block)*int64(d.blockSize)); n < d.blockSize {
+		return 0, err
+	}
+	bits := binary.BigEndian.Uint64(buf)
+	next, bitsLeft := 8, 64
+
+	var sym int
+	for {
+		length := 0
+		for length+1 < len(d.base64) && bits < d.base64[length] {
+			length++
+		}
+		sym = int((bits-d.base64[length])>>(64-length-d.minSymLen)) + int(d.lowestSym[length])
+		if sym >= len(d.symLen) {
+			return 0, fmt.Errorf("%w: symbol %d out of range", ErrCorrupt, sym)
+		}
+		if offset < d.symLen[sym]+1 {
+			break
+		}
+		offset -= d.symLen[sym] + 1
+		length += d.minSymLen
+		bits <<= length
+		bitsLeft -= length
+		if bitsLeft <= 32 {
+			if next+4 > len(buf) {
+				return 0, fmt.Errorf("%w: block %d overrun", ErrCorrupt, block)
+			}
+			bitsLeft += 32
+			bits |= uint64(binary.BigEndian.Uint32(buf[next:])) << (64 - bitsLeft)
+			next += 4
+		}
+	}
+
+	// walk down the pairs to the value
+	for d.symLen[sym] != 0 {
+		left := int(d.left[sym])
+		if offset < d.symLen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symLen[left] + 1
+			sym = int(d.right[sym])
+		}
+	}
+	return int(d.left[sym]), nil
+}
+
+// which of the 4 DTZ maps each result uses, by WDL+2
+var wdlMap = [5]int{1, 3, 0, 2, 0}
+
+// turns a stored DTZ value into plies, the files keep some results in moves to save space
+func dtzPlies(d *pairsData, value int, wdl WDL) (int, error) {
+	if d.flags&flagMapped != 0 {
+		dtzMap := d.dtzMap[wdlMap[wdl+2]]
+		if value >= len(dtzMap) {
+			return 0, fmt.Errorf("%w: DTZ value %d out of range", ErrCorrupt, value)
+		}
+		value = dtzMap[value]
+	}
+	if (wdl == WIN && d.flags&flagWinPlies == 0) || (wdl == LOSS && d.flags&flagLossPlies == 0) ||
+		wdl == CURSED_WIN || wdl == BLESSED_LOSS {
+		value *= 2
+	}
+	return value + 1, nil
+}
+
+// reads a header one field at a time, keeping the first error
+type reader struct {
+	f   io.ReaderAt
+	off int64
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	buf := make([]byte, n)
+	if r.err == nil && n > 0 {
+		if _, err := r.f.ReadAt(buf, r.off); err != nil {
+			r.err = err
+		}
+	}
+	r.off += int64(n)
+	return buf
+}
+
+// the byte i bytes ahead, without moving past it
+func (r *reader) peek(i int) byte {
+	off := r.off
+	r.off += int64(i)
+	b := r.bytes(1)[0]
+	r.off = off
+	return b
+}
+
+func (r *reader) skip(n int) {
+	r.off += int64(n)
+}
+
+// moves to the next multiple of n bytes from the start of the file
+func (r *reader) align(n int64) {
+	r.off = (r.off + n - 1) / n * n
+}
+
+func (r *reader) fail(reason string) {
+	if r.err == nil {
+		r.err = errors.New(reason)
+	}
+}
+
+func (r *reader) u8() byte {
+	return r.bytes(1)[0]
+}
+
+func (r *reader) u16() uint16 {
+	return binary.LittleEndian.Uint16(r.bytes(2))
+}
+
+func (r *reader) u32() uint32 {
+	return binary.LittleEndian.Uint32(r.bytes(4))
+}
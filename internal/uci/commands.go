@@ -17,6 +17,14 @@ func (c *Client) IsReady() error {
 	return c.SendCommand("isready")
 }
 
+// SetOption changes an engine option
+func (c *Client) SetOption(name, value string) error {
+	if value == "" {
+		return c.SendCommand(fmt.Sprintf("setoption name %s", name))
+	}
+	return c.SendCommand(fmt.Sprintf("setoption name %s value %s", name, value))
+}
+
 // SetPosition sets the current position using FEN or startpos
 func (c *Client) SetPosition(fen string) error {
 	if fen == "" || fen == "startpos" {
@@ -0,0 +1,67 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Option types from the UCI specification
+const (
+	OptionCheck  = "check"
+	OptionSpin   = "spin"
+	OptionCombo  = "combo"
+	OptionButton = "button"
+	OptionString = "string"
+)
+
+// Option describes an engine option the GUI can change with "setoption"
+type Option struct {
+	Name    string
+	Type    string
+	Default string
+	Min     int      // spin only
+	Max     int      // spin only
+	Vars    []string // combo only
+}
+
+// String returns the "option" line sent in response to "uci"
+func (o Option) String() string {
+	line := fmt.Sprintf("option name %s type %s", o.Name, o.Type)
+	if o.Type != OptionButton {
+		line += fmt.Sprintf(" default %s", o.Default)
+	}
+	if o.Type == OptionSpin {
+		line += fmt.Sprintf(" min %d max %d", o.Min, o.Max)
+	}
+	for _, v := range o.Vars {
+		line += " var " + v
+	}
+	return line
+}
+
+// OptionHandler is implemented by engines that have options.
+// The server lists them after "uci" and passes "setoption" commands on.
+type OptionHandler interface {
+	// Options returns the options the engine supports
+	Options() []Option
+
+	// SetOption changes an option, value is empty for buttons
+	SetOption(name, value string) error
+}
+
+// parses the arguments of "setoption name <name> [value <value>]", both can contain spaces
+func parseSetOption(args []string) (name, value string) {
+	var nameParts, valueParts []string
+	target := &[]string{}
+	for _, arg := range args {
+		switch arg {
+		case "name":
+			target = &nameParts
+		case "value":
+			target = &valueParts
+		default:
+			*target = append(*target, arg)
+		}
+	}
+	return strings.Join(nameParts, " "), strings.Join(valueParts, " ")
+}
@@ -102,6 +102,8 @@ func (s *Server) handleCommand(line string) error {
 		return s.handleIsReady()
 	case "position":
 		return s.handlePosition(args)
+	case "setoption":
+		return s.handleSetOption(args)
 	case "go":
 		return s.handleGo(args)
 	case "stop":
@@ -120,10 +122,29 @@ func (s *Server) handleUCI() error {
 	info := s.engine.GetInfo()
 	fmt.Fprintf(s.writer, "id name %s\n", info.Name)
 	fmt.Fprintf(s.writer, "id author %s\n", info.Author)
+	if options, ok := s.engine.(OptionHandler); ok {
+		for _, option := range options.Options() {
+			fmt.Fprintf(s.writer, "%s\n", option.String())
+		}
+	}
 	fmt.Fprintf(s.writer, "uciok\n")
 	return nil
 }
 
+// handleSetOption passes the "setoption" command on to engines that have options
+func (s *Server) handleSetOption(args []string) error {
+	options, ok := s.engine.(OptionHandler)
+	if !ok {
+		return nil
+	}
+	name, value := parseSetOption(args)
+	if err := options.SetOption(name, value); err != nil {
+		// a bad option shouldn't end the session, tell the GUI and carry on
+		fmt.Fprintf(s.writer, "info string %v\n", err)
+	}
+	return nil
+}
+
 // handleIsReady responds to the "isready" command
 func (s *Server) handleIsReady() error {
 	if s.engine.IsReady() {